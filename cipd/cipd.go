@@ -35,7 +35,8 @@ import (
 )
 
 const (
-	cipdBackend            = "https://chrome-infra-packages.appspot.com"
+	// ServiceURL is the url of the cipd backend packages are fetched from.
+	ServiceURL             = "https://chrome-infra-packages.appspot.com"
 	exitCodeNoValidToken   = 1
 	cipdManifestInvalidErr = cmdline.ErrExitCode(25)
 )
@@ -97,7 +98,7 @@ func FetchBinary(jirix *jiri.X, binaryPath string) error {
 }
 
 func fetchBinaryImpl(jirix *jiri.X, binaryPath, platform, version, digest string) error {
-	cipdURL := fmt.Sprintf("%s/client?platform=%s&version=%s", cipdBackend, platform, version)
+	cipdURL := fmt.Sprintf("%s/client?platform=%s&version=%s", ServiceURL, platform, version)
 	data, err := fetchFile(jirix, cipdURL)
 	if err != nil {
 		return err
@@ -200,7 +201,7 @@ func fetchDigest(platform string) (digest, method string, err error) {
 }
 
 func selfUpdate(cipdPath, cipdVersion string) error {
-	args := []string{"selfupdate", "-version", cipdVersion, "-service-url", cipdBackend}
+	args := []string{"selfupdate", "-version", cipdVersion, "-service-url", ServiceURL}
 	command := exec.Command(cipdPath, args...)
 	return command.Run()
 }
@@ -236,7 +237,8 @@ func verifyDigest(data []byte, cipdDigest string) (bool, error) {
 	return false, nil
 }
 
-func getUserAgent() string {
+// UserAgent returns the User-Agent used by jiri for HTTP requests.
+func UserAgent() string {
 	ua := "jiri/" + version.GitCommit
 	if version.GitCommit == "" {
 		ua += "debug"
@@ -255,7 +257,7 @@ func fetchFile(jirix *jiri.X, url string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", getUserAgent())
+	req.Header.Set("User-Agent", UserAgent())
 	var contents []byte
 	if err := retry.Function(jirix, func() error {
 		resp, err := client.Do(req)
@@ -416,7 +418,7 @@ func Ensure(jirix *jiri.X, file, projectRoot string, timeout uint) error {
 	// Construct arguments and invoke cipd for ensure file
 	command := exec.CommandContext(ctx, jirix.CIPDPath(), args...)
 	// Add User-Agent info for cipd
-	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+getUserAgent())
+	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+UserAgent())
 	command.Stdin = os.Stdin
 	command.Stdout = os.Stdout
 	command.Stderr = os.Stderr
@@ -451,7 +453,7 @@ func EnsureFileVerify(jirix *jiri.X, file string) error {
 	command := exec.Command(jirix.CIPDPath(), args...)
 	var stdoutBuf, stderrBuf bytes.Buffer
 	// Add User-Agent info for cipd
-	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+getUserAgent())
+	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+UserAgent())
 	command.Stdin = os.Stdin
 	// Redirect outputs since cipd will print verbose information even
 	// if log-level is set to warning
@@ -487,7 +489,7 @@ func Resolve(jirix *jiri.X, file string) ([]PackageInstance, error) {
 	jirix.Logger.Debugf("Invoke cipd with %v", args)
 
 	command := exec.Command(jirix.CIPDPath(), args...)
-	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+getUserAgent())
+	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+UserAgent())
 	var stdoutBuf, stderrBuf bytes.Buffer
 	command.Stdin = os.Stdin
 	// Redirect outputs since cipd will print verbose information even
@@ -587,6 +589,89 @@ func parseVersions(file string) ([]PackageInstance, error) {
 	return output, nil
 }
 
+// InstanceTag describes a tag attached to a cipd package instance.
+type InstanceTag struct {
+	Tag          string `json:"tag"`
+	RegisteredTs int64  `json:"registered_ts"`
+}
+
+// InstanceDescription describes a cipd package instance as reported by
+// cipd describe.
+type InstanceDescription struct {
+	Pin struct {
+		PackageName string `json:"package"`
+		InstanceID  string `json:"instance_id"`
+	} `json:"pin"`
+	RegisteredTs int64         `json:"registered_ts"`
+	Tags         []InstanceTag `json:"tags"`
+}
+
+// runJSON invokes cipd with args and decodes the "result" field of its
+// json output into result.
+func runJSON(jirix *jiri.X, args []string, result any) error {
+	if err := Bootstrap(jirix); err != nil {
+		return err
+	}
+	jsonFile, err := os.CreateTemp("", "cipd*.json")
+	if err != nil {
+		return err
+	}
+	jsonFileName := jsonFile.Name()
+	jsonFile.Close()
+	defer os.Remove(jsonFileName)
+
+	args = append(args, "-json-output", jsonFileName, "-log-level", "warning")
+	jirix.Logger.Debugf("Invoke cipd with %v", args)
+	command := exec.Command(jirix.CIPDPath(), args...)
+	command.Env = append(os.Environ(), "CIPD_HTTP_USER_AGENT_PREFIX="+UserAgent())
+	var stdoutBuf, stderrBuf bytes.Buffer
+	command.Stdout = &stdoutBuf
+	command.Stderr = &stderrBuf
+	if err := command.Run(); err != nil {
+		return fmt.Errorf("cipd %s failed: %v: %s", args[0], err, strings.TrimSpace(stderrBuf.String()))
+	}
+	jsonData, err := os.ReadFile(jsonFileName)
+	if err != nil {
+		return err
+	}
+	output := struct {
+		Result any `json:"result"`
+	}{result}
+	if err := json.Unmarshal(jsonData, &output); err != nil {
+		return fmt.Errorf("failed to parse output of cipd %s: %v", args[0], err)
+	}
+	return nil
+}
+
+// Describe returns the description of the instance of package pkg that
+// version, which can be an instance id, a ref or a tag, points to.
+//
+// Describe is an expensive call for the cipd backend and should not be
+// used for every package on each update.
+func Describe(jirix *jiri.X, pkg, version string) (*InstanceDescription, error) {
+	var desc InstanceDescription
+	if err := runJSON(jirix, []string{"describe", pkg, "-version", version}, &desc); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// ListInstances returns the ids of the most recently registered instances
+// of package pkg, newest first.
+func ListInstances(jirix *jiri.X, pkg string, limit int) ([]string, error) {
+	var list struct {
+		Instances []InstanceDescription `json:"instances"`
+	}
+	if err := runJSON(jirix, []string{"instances", pkg, "-limit", strconv.Itoa(limit)}, &list); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(list.Instances))
+	for _, ins := range list.Instances {
+		ids = append(ids, ins.Pin.InstanceID)
+	}
+	return ids, nil
+}
+
 // CheckFloatingRefs determines if pkgs contains a floating ref which shouldn't
 // be used normally.
 //
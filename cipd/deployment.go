@@ -0,0 +1,158 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cipd
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout of the site metadata cipd keeps in the root it deploys packages to.
+const (
+	sitePackagesDir     = ".cipd/pkgs"
+	siteDescriptionFile = "description.json"
+	siteCurrentLink     = "_current"
+	siteCurrentFile     = "_current.txt"
+	sitePackageManifest = ".cipdpkg/manifest.json"
+	sitePackageMetaDir  = ".cipdpkg/"
+)
+
+// DeployedFile describes a file of a deployed package.
+type DeployedFile struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Symlink string `json:"symlink,omitempty"`
+	// Hash is the hex digest of the content of the file, which cipd
+	// records in the manifests of the instances it extracts.
+	Hash string `json:"hash,omitempty"`
+}
+
+// DeployedPackage describes a package instance that cipd deployed into a
+// root directory.
+type DeployedPackage struct {
+	PackageName string
+	Subdir      string
+	InstanceID  string
+	Files       []DeployedFile
+}
+
+// Deployed reads the cipd site metadata in root and returns the deployed
+// package instances.
+func Deployed(root string) ([]DeployedPackage, error) {
+	pkgsDir := filepath.Join(root, filepath.FromSlash(sitePackagesDir))
+	entries, err := os.ReadDir(pkgsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var deployed []DeployedPackage
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(pkgsDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, siteDescriptionFile))
+		if err != nil {
+			// Not a package directory.
+			continue
+		}
+		var desc struct {
+			Subdir      string `json:"subdir"`
+			PackageName string `json:"package_name"`
+		}
+		if err := json.Unmarshal(data, &desc); err != nil {
+			return nil, err
+		}
+		pkg := DeployedPackage{
+			PackageName: desc.PackageName,
+			Subdir:      desc.Subdir,
+		}
+		if target, err := os.Readlink(filepath.Join(dir, siteCurrentLink)); err == nil {
+			pkg.InstanceID = filepath.Base(target)
+		} else if data, err := os.ReadFile(filepath.Join(dir, siteCurrentFile)); err == nil {
+			pkg.InstanceID = strings.TrimSpace(string(data))
+		}
+		if pkg.InstanceID != "" {
+			data, err := os.ReadFile(filepath.Join(dir, pkg.InstanceID, filepath.FromSlash(sitePackageManifest)))
+			if err == nil {
+				var manifest struct {
+					Files []DeployedFile `json:"files"`
+				}
+				if err := json.Unmarshal(data, &manifest); err != nil {
+					return nil, err
+				}
+				pkg.Files = manifest.Files
+			}
+		}
+		deployed = append(deployed, pkg)
+	}
+	return deployed, nil
+}
+
+// Check compares the files of deployed package p with the files in root. It
+// returns the files which are missing and the files whose content or symlink
+// target differs. Files whose hash is not recorded are compared by size.
+func (p DeployedPackage) Check(root string) (missing, modified []string) {
+	for _, f := range p.Files {
+		if strings.HasPrefix(f.Name, sitePackageMetaDir) {
+			continue
+		}
+		path := filepath.Join(root, filepath.FromSlash(p.Subdir), filepath.FromSlash(f.Name))
+		if f.Symlink != "" {
+			target, err := os.Readlink(path)
+			if err != nil {
+				missing = append(missing, f.Name)
+			} else if target != f.Symlink {
+				modified = append(modified, f.Name)
+			}
+			continue
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			missing = append(missing, f.Name)
+		case !info.Mode().IsRegular() || info.Size() != f.Size:
+			modified = append(modified, f.Name)
+		case f.Hash != "":
+			if same, err := hashMatches(path, f.Hash); err != nil || !same {
+				modified = append(modified, f.Name)
+			}
+		}
+	}
+	return
+}
+
+// hashMatches returns true if the content of the file at path has the hex
+// digest want. The hash algorithm is picked from the length of the digest.
+func hashMatches(path, want string) (bool, error) {
+	var h hash.Hash
+	switch len(want) {
+	case 2 * sha256.Size:
+		h = sha256.New()
+	case 2 * sha1.Size:
+		h = sha1.New()
+	default:
+		// Unknown algorithm, the size already matched.
+		return true, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == strings.ToLower(want), nil
+}
@@ -0,0 +1,529 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/envvar"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type bisectCmd struct {
+	cmdBase
+
+	runHooks         bool
+	fetchPkgs        bool
+	hookTimeout      uint
+	fetchPkgsTimeout uint
+}
+
+func (c *bisectCmd) Name() string { return "bisect" }
+func (c *bisectCmd) Synopsis() string {
+	return "Find the project commit which introduced a regression"
+}
+func (c *bisectCmd) Usage() string {
+	return `Bisects the changes between two snapshots of the workspace to find the
+project commit which introduced a regression, like "git bisect" does for a
+single repository.
+
+Usage:
+  jiri bisect [flags] start <good-snapshot> <bad-snapshot>
+  jiri bisect [flags] good|bad|skip
+  jiri bisect [flags] run <command...>
+  jiri bisect [flags] reset
+
+<good-snapshot> and <bad-snapshot> are files or urls containing snapshots.
+
+"jiri bisect start" lists the commits of the projects updated between the
+snapshots, like "jiri diff -all-cls" does, and orders them by commit time.
+Each intermediate state of the workspace applies one more of these commits
+on top of the good snapshot. If the bad snapshot also adds, removes or moves
+projects or changes packages or hooks, these changes are applied first, as a
+single state. The state in the middle is then checked out.
+
+"jiri bisect good", "jiri bisect bad" and "jiri bisect skip" mark the state
+which is checked out and check out the next one to test. Once the first bad
+state is found, the culprit commit and its project are reported.
+
+"jiri bisect run" runs <command...> in the root of the workspace for every
+state to test until the culprit is found. The command is not run through a
+shell; use "sh -c <script>" for pipelines or redirections. The command exits
+with 0 if the state is good, with 125 if it cannot be tested and with any
+other code between 1 and 127 if it is bad. Other exit codes abort the
+bisection.
+
+"jiri bisect reset" ends the bisection and checks out the workspace as it was
+when it started.
+`
+}
+
+func (c *bisectCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.runHooks, "run-hooks", true, "Run hooks after checking out a state.")
+	f.BoolVar(&c.fetchPkgs, "fetch-packages", true, "Fetch packages after checking out a state.")
+	f.UintVar(&c.hookTimeout, "hook-timeout", project.DefaultHookTimeout, "Timeout in minutes for running the hooks operation.")
+	f.UintVar(&c.fetchPkgsTimeout, "fetch-packages-timeout", project.DefaultPackageTimeout, "Timeout in minutes for fetching prebuilt packages using cipd.")
+}
+
+func (c *bisectCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+// bisectProject is a project updated between the good and bad snapshots,
+// with its revision in the good snapshot.
+type bisectProject struct {
+	Name        string `json:"name"`
+	Remote      string `json:"remote"`
+	OldRevision string `json:"old_revision"`
+}
+
+// bisectStep is the change applied by a state of the bisection on top of
+// the previous state. Project is empty for the step which applies the
+// changes of the bad snapshot which are not commits of updated projects.
+type bisectStep struct {
+	Project string `json:"project,omitempty"`
+	Remote  string `json:"remote,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+func (s bisectStep) String() string {
+	if s.Project == "" {
+		return "new, deleted and moved projects, package and hook changes"
+	}
+	return fmt.Sprintf("%s %s %s", s.Project, shortRev(s.Commit), s.Subject)
+}
+
+// bisectState is the state of a bisection, stored in .jiri_root/bisect.
+// State 0 is the good snapshot and state i applies Steps[i-1] on top of
+// state i-1, the last state being the bad snapshot.
+type bisectState struct {
+	Projects []bisectProject `json:"projects"`
+	Steps    []bisectStep    `json:"steps"`
+	Good     int             `json:"good"`
+	Bad      int             `json:"bad"`
+	Skipped  []int           `json:"skipped,omitempty"`
+	Current  int             `json:"current"`
+}
+
+func bisectDir(jirix *jiri.X) string {
+	return filepath.Join(jirix.RootMetaDir(), "bisect")
+}
+
+func bisectStateFile(jirix *jiri.X) string {
+	return filepath.Join(bisectDir(jirix), "state.json")
+}
+
+func readBisectState(jirix *jiri.X) (*bisectState, error) {
+	data, err := os.ReadFile(bisectStateFile(jirix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no bisection in progress, run \"jiri bisect start\" first")
+		}
+		return nil, err
+	}
+	state := &bisectState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", bisectStateFile(jirix), err)
+	}
+	return state, nil
+}
+
+func (s *bisectState) write(jirix *jiri.X) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return project.SafeWriteFile(jirix, bisectStateFile(jirix), data)
+}
+
+func (s *bisectState) isSkipped(i int) bool {
+	for _, skipped := range s.Skipped {
+		if skipped == i {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the states between the good and bad states which have
+// not been skipped.
+func (s *bisectState) candidates() []int {
+	var ret []int
+	for i := s.Good + 1; i < s.Bad; i++ {
+		if !s.isSkipped(i) {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+// next returns the candidate state closest to the middle of the good and
+// bad states, or false if there is none left.
+func (s *bisectState) next() (int, bool) {
+	candidates := s.candidates()
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	mid := (s.Good + s.Bad) / 2
+	distance := func(i int) int {
+		if i < mid {
+			return mid - i
+		}
+		return i - mid
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	return candidates[0], true
+}
+
+func (c *bisectCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) == 0 {
+		return jirix.UsageErrorf("no action given")
+	}
+	switch action := args[0]; action {
+	case "start":
+		if len(args) != 3 {
+			return jirix.UsageErrorf("start expects a good and a bad snapshot")
+		}
+		return c.runStart(jirix, args[1], args[2])
+	case "good", "bad", "skip":
+		if len(args) != 1 {
+			return jirix.UsageErrorf("unexpected number of arguments")
+		}
+		state, err := readBisectState(jirix)
+		if err != nil {
+			return err
+		}
+		if err := state.mark(action); err != nil {
+			return err
+		}
+		return c.advance(jirix, state)
+	case "run":
+		if len(args) < 2 {
+			return jirix.UsageErrorf("run expects a command")
+		}
+		return c.runCommand(jirix, args[1:])
+	case "reset":
+		if len(args) != 1 {
+			return jirix.UsageErrorf("unexpected number of arguments")
+		}
+		return c.runReset(jirix)
+	default:
+		return jirix.UsageErrorf("unknown action %q", action)
+	}
+}
+
+// copySnapshot copies the snapshot file or url src to dst.
+func copySnapshot(jirix *jiri.X, src, dst string) error {
+	var data []byte
+	if _, err := os.Stat(src); err == nil {
+		if data, err = os.ReadFile(src); err != nil {
+			return err
+		}
+	} else {
+		resp, err := http.Get(src)
+		if err != nil {
+			return fmt.Errorf("failed to get snapshot %q: %v", src, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to get snapshot %q: %s", src, resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("failed to get snapshot %q: %v", src, err)
+		}
+	}
+	return project.SafeWriteFile(jirix, dst, data)
+}
+
+func (c *bisectCmd) runStart(jirix *jiri.X, good, bad string) error {
+	dir := bisectDir(jirix)
+	if _, err := os.Stat(bisectStateFile(jirix)); err == nil {
+		return fmt.Errorf("a bisection is already in progress, run \"jiri bisect reset\" first")
+	}
+	goodFile, badFile := filepath.Join(dir, "good.xml"), filepath.Join(dir, "bad.xml")
+	if err := copySnapshot(jirix, good, goodFile); err != nil {
+		return err
+	}
+	if err := copySnapshot(jirix, bad, badFile); err != nil {
+		return err
+	}
+
+	d, err := (&diffCmd{allCls: true, commitDates: true, firstParent: true}).getDiff(jirix, goodFile, badFile)
+	if err != nil {
+		return err
+	}
+	state, err := newBisectState(d)
+	if err != nil {
+		return err
+	}
+	if len(state.Steps) == 0 {
+		return fmt.Errorf("the snapshots do not differ")
+	}
+
+	// Record the current workspace to restore it on reset.
+	localManifestProjects, err := getDefaultLocalManifestProjects(jirix)
+	if err != nil {
+		return err
+	}
+	if err := project.CreateSnapshot(jirix, filepath.Join(dir, "original.xml"), nil, nil, false, localManifestProjects); err != nil {
+		return err
+	}
+	fmt.Fprintf(jirix.Stdout(), "Bisecting %d changes between the snapshots\n", len(state.Steps))
+	return c.advance(jirix, state)
+}
+
+// newBisectState returns the state of a bisection of the changes in d, with
+// the first-parent commits of the updated projects ordered by commit time.
+// The commits of each project keep their topological order.
+func newBisectState(d *Diff) (*bisectState, error) {
+	state := &bisectState{}
+	if len(d.NewProjects) != 0 || len(d.DeletedProjects) != 0 ||
+		len(d.NewPackages) != 0 || len(d.DeletedPackages) != 0 || len(d.UpdatedPackages) != 0 ||
+		len(d.NewHooks) != 0 || len(d.DeletedHooks) != 0 || len(d.UpdatedHooks) != 0 {
+		state.Steps = append(state.Steps, bisectStep{})
+	} else {
+		for _, p := range d.UpdatedProjects {
+			if p.OldRelativePath != "" {
+				state.Steps = append(state.Steps, bisectStep{})
+				break
+			}
+		}
+	}
+
+	type pending struct {
+		steps []bisectStep
+		times []time.Time
+	}
+	var queues []*pending
+	for _, p := range d.UpdatedProjects {
+		if p.OldRevision == "" {
+			continue
+		}
+		if p.Error != "" {
+			return nil, fmt.Errorf("failed to list the commits of project %s: %s", p.Name, p.Error)
+		}
+		state.Projects = append(state.Projects, bisectProject{Name: p.Name, Remote: p.Remote, OldRevision: p.OldRevision})
+		q := &pending{}
+		// Commits are listed oldest first.
+		for _, cl := range p.Cls {
+			t, err := time.Parse(time.RFC3339, cl.Date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %q of commit %s in project %s: %v", cl.Date, cl.Commit, p.Name, err)
+			}
+			q.steps = append(q.steps, bisectStep{
+				Project: p.Name,
+				Remote:  p.Remote,
+				Commit:  cl.Commit,
+				Author:  cl.Author,
+				Date:    cl.Date,
+				Subject: cl.Subject,
+			})
+			q.times = append(q.times, t)
+		}
+		if len(q.steps) != 0 {
+			queues = append(queues, q)
+		}
+	}
+
+	// Merge the commits of the projects by commit time, keeping the order
+	// of the commits within each project.
+	for len(queues) != 0 {
+		first := 0
+		for i, q := range queues[1:] {
+			if q.times[0].Before(queues[first].times[0]) {
+				first = i + 1
+			}
+		}
+		q := queues[first]
+		state.Steps = append(state.Steps, q.steps[0])
+		q.steps, q.times = q.steps[1:], q.times[1:]
+		if len(q.steps) == 0 {
+			queues = append(queues[:first], queues[first+1:]...)
+		}
+	}
+	state.Bad = len(state.Steps)
+	return state, nil
+}
+
+// mark marks the current state as good, bad or skipped.
+func (s *bisectState) mark(verdict string) error {
+	if s.Current <= s.Good || s.Current >= s.Bad {
+		return fmt.Errorf("the bisection is done, run \"jiri bisect reset\" to end it")
+	}
+	switch verdict {
+	case "good":
+		s.Good = s.Current
+	case "bad":
+		s.Bad = s.Current
+	case "skip":
+		s.Skipped = append(s.Skipped, s.Current)
+	}
+	return nil
+}
+
+// done returns whether the bisection has found the first bad state or
+// cannot narrow it down further.
+func (s *bisectState) done() bool {
+	_, ok := s.next()
+	return !ok
+}
+
+// advance checks out the next state to test, or reports the culprit if the
+// bisection is done.
+func (c *bisectCmd) advance(jirix *jiri.X, state *bisectState) error {
+	next, ok := state.next()
+	if !ok {
+		state.Current = state.Bad
+		if err := state.write(jirix); err != nil {
+			return err
+		}
+		state.report(jirix.Stdout())
+		return nil
+	}
+	state.Current = next
+	if err := state.write(jirix); err != nil {
+		return err
+	}
+	left := len(state.candidates()) - 1
+	fmt.Fprintf(jirix.Stdout(), "Bisecting: %d states left to test after this (roughly %d steps)\n", left, bits.Len(uint(left)))
+	fmt.Fprintf(jirix.Stdout(), "[%d/%d] %s\n", next, len(state.Steps), state.Steps[next-1])
+	return c.checkout(jirix, state, next)
+}
+
+// report prints the first bad change, or the changes it may be if skipped
+// states prevent narrowing it down to one.
+func (s *bisectState) report(w io.Writer) {
+	var suspects []int
+	for i := s.Good + 1; i < s.Bad; i++ {
+		suspects = append(suspects, i)
+	}
+	suspects = append(suspects, s.Bad)
+	if len(suspects) > 1 {
+		fmt.Fprintf(w, "There are only skipped states left to test.\nThe first bad change could be any of:\n")
+		for _, i := range suspects {
+			fmt.Fprintf(w, "  %s\n", s.Steps[i-1])
+		}
+		return
+	}
+	step := s.Steps[s.Bad-1]
+	if step.Project == "" {
+		fmt.Fprintf(w, "The first bad change is not a commit: it is one of the new, deleted and moved projects or package and hook changes of the bad snapshot\n")
+		return
+	}
+	fmt.Fprintf(w, "%s is the first bad commit\n", step.Commit)
+	fmt.Fprintf(w, "Project: %s (%s)\n", step.Project, step.Remote)
+	fmt.Fprintf(w, "Author:  %s\n", step.Author)
+	fmt.Fprintf(w, "Date:    %s\n", step.Date)
+	fmt.Fprintf(w, "\n    %s\n", step.Subject)
+}
+
+// checkout checks out state i of the bisection.
+func (c *bisectCmd) checkout(jirix *jiri.X, state *bisectState, i int) error {
+	dir := bisectDir(jirix)
+	snapshot := filepath.Join(dir, "bad.xml")
+	switch {
+	case i == 0:
+		snapshot = filepath.Join(dir, "good.xml")
+	case i < len(state.Steps):
+		m, err := project.ManifestFromFile(jirix, snapshot)
+		if err != nil {
+			return err
+		}
+		revisions := make(map[project.ProjectKey]string)
+		for _, p := range state.Projects {
+			revisions[project.MakeProjectKey(p.Name, p.Remote)] = p.OldRevision
+		}
+		for _, step := range state.Steps[:i] {
+			if step.Project != "" {
+				revisions[project.MakeProjectKey(step.Project, step.Remote)] = step.Commit
+			}
+		}
+		for j := range m.Projects {
+			if rev, ok := revisions[m.Projects[j].Key()]; ok {
+				m.Projects[j].Revision = rev
+			}
+		}
+		data, err := m.ToBytes()
+		if err != nil {
+			return err
+		}
+		snapshot = filepath.Join(dir, "candidate.xml")
+		if err := project.SafeWriteFile(jirix, snapshot, data); err != nil {
+			return err
+		}
+	}
+	return project.CheckoutSnapshot(jirix, snapshot, false, c.runHooks, c.fetchPkgs, c.hookTimeout, c.fetchPkgsTimeout, nil)
+}
+
+func (c *bisectCmd) runCommand(jirix *jiri.X, command []string) error {
+	state, err := readBisectState(jirix)
+	if err != nil {
+		return err
+	}
+	for !state.done() {
+		fmt.Fprintf(jirix.Stdout(), "running %s\n", strings.Join(command, " "))
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Env = envvar.MapToSlice(jirix.Env())
+		cmd.Dir = jirix.Root
+		cmd.Stdin = jirix.Stdin()
+		cmd.Stdout = jirix.Stdout()
+		cmd.Stderr = jirix.Stderr()
+		verdict := "good"
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return fmt.Errorf("failed to run %q: %v", strings.Join(command, " "), err)
+			}
+			switch code := exitErr.ExitCode(); {
+			case code == 125:
+				verdict = "skip"
+			case code > 0 && code < 128:
+				verdict = "bad"
+			default:
+				return fmt.Errorf("bisect run aborted: %q exited with %d", strings.Join(command, " "), code)
+			}
+		}
+		if err := state.mark(verdict); err != nil {
+			return err
+		}
+		if err := c.advance(jirix, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *bisectCmd) runReset(jirix *jiri.X) error {
+	dir := bisectDir(jirix)
+	if _, err := os.Stat(bisectStateFile(jirix)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no bisection in progress")
+		}
+		return err
+	}
+	original := filepath.Join(dir, "original.xml")
+	if err := project.CheckoutSnapshot(jirix, original, false, c.runHooks, c.fetchPkgs, c.hookTimeout, c.fetchPkgsTimeout, nil); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
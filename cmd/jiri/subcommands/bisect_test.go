@@ -0,0 +1,192 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/jiritest"
+	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+// commitFileAt commits file with the given date in the repository at dir
+// and returns the new revision.
+func commitFileAt(t *testing.T, fake *jiritest.FakeJiriRoot, dir, file, date string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitAt(t, dir, date, "add", file)
+	gitAt(t, dir, date, "commit", "-m", "add "+file)
+	rev, err := gitutil.New(fake.X, gitutil.RootDirOpt(dir)).CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rev
+}
+
+// gitAt runs git with args in the repository at dir, with the given author
+// and committer date.
+func gitAt(t *testing.T, dir, date string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=John Doe", "GIT_AUTHOR_EMAIL=john.doe@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=John Doe", "GIT_COMMITTER_EMAIL=john.doe@example.com", "GIT_COMMITTER_DATE="+date)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// setupBisect creates a good and a bad snapshot between which project 0
+// gets two commits and project 1 one commit adding a "bug" file, in between
+// the commits of project 0. It returns the revision of that commit.
+func setupBisect(t *testing.T) (*jiritest.FakeJiriRoot, []project.Project, string, string, string) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.xml"), filepath.Join(dir, "bad.xml")
+	if err := project.CreateSnapshot(fake.X, good, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	remote0, remote1 := fake.Projects[localProjects[0].Name], fake.Projects[localProjects[1].Name]
+	commitFileAt(t, fake, remote0, "a", "2026-01-01T00:00:01Z")
+	culprit := commitFileAt(t, fake, remote1, "bug", "2026-01-01T00:00:02Z")
+	commitFileAt(t, fake, remote0, "c", "2026-01-01T00:00:03Z")
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := project.CreateSnapshot(fake.X, bad, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	return fake, localProjects, good, bad, culprit
+}
+
+func checkFiles(t *testing.T, localProjects []project.Project, want map[string]bool) {
+	t.Helper()
+	for file, exists := range want {
+		_, err := os.Stat(filepath.Join(localProjects[0].Path, file))
+		if file == "bug" {
+			_, err = os.Stat(filepath.Join(localProjects[1].Path, file))
+		}
+		if (err == nil) != exists {
+			t.Errorf("file %q exists: %v, want %v", file, err == nil, exists)
+		}
+	}
+}
+
+func TestBisect(t *testing.T) {
+	fake, localProjects, good, bad, culprit := setupBisect(t)
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	cmd := &bisectCmd{}
+
+	if err := cmd.run(fake.X, []string{"start", good, bad}); err != nil {
+		t.Fatal(err)
+	}
+	// The first state to test applies the first commit of project 0.
+	checkFiles(t, localProjects, map[string]bool{"a": true, "bug": false, "c": false})
+
+	if err := cmd.run(fake.X, []string{"good"}); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, localProjects, map[string]bool{"a": true, "bug": true, "c": false})
+
+	if err := cmd.run(fake.X, []string{"bad"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := culprit + " is the first bad commit\nProject: " + localProjects[1].Name; !strings.Contains(stdout.String(), want) {
+		t.Errorf("output does not report the culprit %q:\n%s", want, stdout.String())
+	}
+	if err := cmd.run(fake.X, []string{"good"}); err == nil {
+		t.Errorf("expected marking a state after the bisection is done to fail")
+	}
+
+	if err := cmd.run(fake.X, []string{"reset"}); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, localProjects, map[string]bool{"a": true, "bug": true, "c": true})
+	if _, err := os.Stat(bisectDir(fake.X)); !os.IsNotExist(err) {
+		t.Errorf("expected bisect state to be removed, got %v", err)
+	}
+}
+
+func TestBisectFirstParent(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.xml"), filepath.Join(dir, "bad.xml")
+	if err := project.CreateSnapshot(fake.X, good, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	remote := fake.Projects[localProjects[0].Name]
+	gitAt(t, remote, "2026-01-01T00:00:00Z", "checkout", "-b", "side")
+	commitFileAt(t, fake, remote, "side", "2026-01-01T00:00:01Z")
+	gitAt(t, remote, "2026-01-01T00:00:00Z", "checkout", "-")
+	// b is a child of a, but has an older date.
+	a := commitFileAt(t, fake, remote, "a", "2026-01-01T00:00:03Z")
+	b := commitFileAt(t, fake, remote, "b", "2026-01-01T00:00:02Z")
+	gitAt(t, remote, "2026-01-01T00:00:04Z", "merge", "--no-ff", "-m", "merge side", "side")
+	merge, err := gitutil.New(fake.X, gitutil.RootDirOpt(remote)).CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := project.CreateSnapshot(fake.X, bad, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := (&diffCmd{allCls: true, commitDates: true, firstParent: true}).getDiff(fake.X, good, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := newBisectState(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range state.Steps {
+		got = append(got, step.Commit)
+	}
+	if want := []string{a, b, merge}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got steps %v, want %v", got, want)
+	}
+}
+
+func TestBisectRun(t *testing.T) {
+	fake, localProjects, good, bad, culprit := setupBisect(t)
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	cmd := &bisectCmd{}
+
+	if err := cmd.run(fake.X, []string{"start", good, bad}); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(fake.X.Root, filepath.Join(localProjects[1].Path, "bug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.run(fake.X, []string{"run", "test", "!", "-e", rel}); err != nil {
+		t.Fatal(err)
+	}
+	if want := culprit + " is the first bad commit"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("output does not report the culprit %q:\n%s", want, stdout.String())
+	}
+}
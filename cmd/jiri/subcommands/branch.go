@@ -6,12 +6,15 @@ package subcommands
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -30,27 +33,49 @@ type branchCmd struct {
 	deleteMerged          bool
 	forceDelete           bool
 	overrideProjectConfig bool
+	create                string
+	switchTo              string
+	projects              string
+	cwdProjects           bool
+	attributes            string
 }
 
 func (c *branchCmd) Name() string     { return "branch" }
-func (c *branchCmd) Synopsis() string { return "Show or delete branches" }
+func (c *branchCmd) Synopsis() string { return "Show, create, switch or delete branches" }
 func (c *branchCmd) Usage() string {
 	return `Show all the projects having branch <branch>. If -d or -D is passed, <branch>
 is deleted. if <branch> is not passed, show all projects which have branches other than "main"
 
 Usage:
   jiri branch [flags] <branch>
+  jiri branch -create <name> [-projects <regexp> | -cwd-projects | -attributes <attributes>]
+  jiri branch -switch <name>
 
 <branch> is the name of the branch.
+
+-create creates the branch <name> at JIRI_HEAD in the projects selected by
+-projects, -cwd-projects or -attributes, or in the project containing the
+current directory if none of these is given, and checks it out. The branch
+tracks the remote branch of the project in the manifest. The projects the
+branch is created in are recorded in the jiri root, so that
+"jiri upload -multipart" can upload the changes of the whole set.
+
+-switch checks out the branch <name> in all the projects which have it and
+checks out JIRI_HEAD in the other projects.
 `
 }
 
 func (c *branchCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.delete, "d", false, "Delete branch from project. Similar to running 'git branch -d <branch-name>'")
 	f.BoolVar(&c.forceDelete, "D", false, "Force delete branch from project. Similar to running 'git branch -D <branch-name>'")
-	f.BoolVar(&c.overrideProjectConfig, "override-pc", false, "Overrides project config's ignore and noupdate flag and deletes the branch.")
+	f.BoolVar(&c.overrideProjectConfig, "override-pc", false, "Overrides project config's ignore and noupdate flags, so that the branch is also deleted, created or switched to in those projects.")
 	f.BoolVar(&c.deleteMerged, "delete-merged", false, "Delete merged branches. Merged branches are the tracked branches merged with their tracking remote or un-tracked branches merged with the branch specified in manifest(default main). If <branch> is provided, it will only delete branch <branch> if merged.")
 	f.BoolVar(&c.deleteMergedCLs, "delete-merged-cl", false, "Implies -delete-merged. It also parses commit messages for ChangeID and checks with gerrit if those changes have been merged and deletes those branches. It will ignore a branch if it differs with remote by more than 10 commits.")
+	f.StringVar(&c.create, "create", "", "Create and check out this branch at JIRI_HEAD in the selected projects.")
+	f.StringVar(&c.switchTo, "switch", "", "Check out this branch in the projects which have it and JIRI_HEAD in the other projects.")
+	f.StringVar(&c.projects, "projects", "", "A regular expression matching the names of the projects to create the branch in.")
+	f.BoolVar(&c.cwdProjects, "cwd-projects", false, "Create the branch in the project containing the current directory and the projects under it.")
+	f.StringVar(&c.attributes, "attributes", "", "Create the branch in the projects with any of these attributes, separated by comma.")
 }
 
 func (c *branchCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -64,6 +89,30 @@ func (c *branchCmd) run(jirix *jiri.X, args []string) error {
 	} else if len(args) == 1 {
 		branch = args[0]
 	}
+	selectors := 0
+	for _, set := range []bool{c.projects != "", c.cwdProjects, c.attributes != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors > 1 {
+		return jirix.UsageErrorf("-projects, -cwd-projects and -attributes cannot be combined")
+	}
+	if selectors != 0 && c.create == "" {
+		return jirix.UsageErrorf("-projects, -cwd-projects and -attributes can only be used with -create")
+	}
+	if c.create != "" || c.switchTo != "" {
+		if c.create != "" && c.switchTo != "" {
+			return jirix.UsageErrorf("-create and -switch cannot be combined")
+		}
+		if len(args) != 0 {
+			return jirix.UsageErrorf("unexpected arguments")
+		}
+		if c.create != "" {
+			return c.createBranches(jirix, c.create)
+		}
+		return c.switchBranches(jirix, c.switchTo)
+	}
 	if c.delete || c.forceDelete {
 		if branch == "" {
 			return jirix.UsageErrorf("Please provide branch to delete")
@@ -466,6 +515,7 @@ func (c *branchCmd) deleteBranches(jirix *jiri.X, branchToDelete string) error {
 	jirix.TimerPush("Process")
 	errors := false
 	projectFound := false
+	var deleted []project.ProjectKey
 	var keys project.ProjectKeys
 	for key := range states {
 		keys = append(keys, key)
@@ -497,12 +547,16 @@ func (c *branchCmd) deleteBranches(jirix *jiri.X, branchToDelete string) error {
 						return err
 					}
 					fmt.Fprintf(jirix.Stdout(), "%s (was %s)\n", jirix.Color.Green("Deleted Branch %s", branchToDelete), jirix.Color.Yellow(shortHash))
+					deleted = append(deleted, key)
 				}
 				break
 			}
 		}
 	}
 	jirix.TimerPop()
+	if err := removeFromBranchSet(jirix, branchToDelete, deleted); err != nil {
+		return err
+	}
 
 	if !projectFound {
 		fmt.Fprintf(jirix.Stdout(), "Cannot find any project with branch %q\n", branchToDelete)
@@ -513,3 +567,305 @@ func (c *branchCmd) deleteBranches(jirix *jiri.X, branchToDelete string) error {
 	}
 	return nil
 }
+
+// branchSetsFile returns the file recording the projects the branches
+// created by "jiri branch -create" were created in.
+func branchSetsFile(jirix *jiri.X) string {
+	return filepath.Join(jirix.RootMetaDir(), "branches.json")
+}
+
+// readBranchSets returns the keys of the projects each branch created by
+// "jiri branch -create" was created in.
+func readBranchSets(jirix *jiri.X) (map[string][]string, error) {
+	sets := make(map[string][]string)
+	data, err := os.ReadFile(branchSetsFile(jirix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sets, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", branchSetsFile(jirix), err)
+	}
+	return sets, nil
+}
+
+func writeBranchSets(jirix *jiri.X, sets map[string][]string) error {
+	data, err := json.MarshalIndent(sets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return project.SafeWriteFile(jirix, branchSetsFile(jirix), data)
+}
+
+// branchSet returns the keys of the projects branch was created in by
+// "jiri branch -create", or nil if it was not.
+func branchSet(jirix *jiri.X, branch string) (map[project.ProjectKey]bool, error) {
+	sets, err := readBranchSets(jirix)
+	if err != nil || sets[branch] == nil {
+		return nil, err
+	}
+	keys := make(map[project.ProjectKey]bool)
+	for _, s := range sets[branch] {
+		if key, ok := project.ProjectKeyFromString(s); ok {
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+// addToBranchSet records that branch was created in the projects of keys.
+func addToBranchSet(jirix *jiri.X, branch string, keys []project.ProjectKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	sets, err := readBranchSets(jirix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !slices.Contains(sets[branch], key.String()) {
+			sets[branch] = append(sets[branch], key.String())
+		}
+	}
+	sort.Strings(sets[branch])
+	return writeBranchSets(jirix, sets)
+}
+
+// removeFromBranchSet records that branch was deleted from the projects of
+// keys.
+func removeFromBranchSet(jirix *jiri.X, branch string, keys []project.ProjectKey) error {
+	sets, err := readBranchSets(jirix)
+	if err != nil {
+		return err
+	}
+	if _, ok := sets[branch]; !ok || len(keys) == 0 {
+		return nil
+	}
+	sets[branch] = slices.DeleteFunc(sets[branch], func(s string) bool {
+		return slices.ContainsFunc(keys, func(key project.ProjectKey) bool { return key.String() == s })
+	})
+	if len(sets[branch]) == 0 {
+		delete(sets, branch)
+	}
+	return writeBranchSets(jirix, sets)
+}
+
+// jiriHeadRevision returns the revision of JIRI_HEAD in the repository of
+// scm, or the revision remote is pinned to if JIRI_HEAD does not exist.
+func jiriHeadRevision(scm *gitutil.Git, remote project.Project) (string, error) {
+	if r, err := scm.CurrentRevisionForRef("JIRI_HEAD"); err == nil {
+		return r, nil
+	}
+	headRev, err := project.GetHeadRevision(remote)
+	if err != nil {
+		return "", err
+	}
+	return scm.CurrentRevisionForRef(headRev)
+}
+
+// containsPath returns whether path is dir or under it.
+func containsPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// selectBranchProjects returns the keys of the projects in the manifest to
+// create a branch in.
+func (c *branchCmd) selectBranchProjects(jirix *jiri.X, localProjects, remoteProjects project.Projects) (project.ProjectKeys, error) {
+	var match func(local, remote project.Project) bool
+	switch {
+	case c.projects != "":
+		re, err := regexp.Compile(c.projects)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile regexp %v: %v", c.projects, err)
+		}
+		match = func(local, _ project.Project) bool { return re.MatchString(local.Name) }
+	case c.cwdProjects:
+		match = func(local, _ project.Project) bool {
+			return containsPath(local.Path, jirix.Cwd) || containsPath(jirix.Cwd, local.Path)
+		}
+	case c.attributes != "":
+		want := splitAttributes(c.attributes)
+		match = func(_, remote project.Project) bool { return hasAnyAttribute(remote.Attributes, want) }
+	default:
+		// Select the innermost project containing the current directory.
+		var current *project.Project
+		for _, local := range localProjects {
+			if containsPath(local.Path, jirix.Cwd) && (current == nil || len(local.Path) > len(current.Path)) {
+				local := local
+				current = &local
+			}
+		}
+		if current == nil {
+			return nil, jirix.UsageErrorf("%q is not in a project, use -projects, -cwd-projects or -attributes", jirix.Cwd)
+		}
+		match = func(local, _ project.Project) bool { return local.Key() == current.Key() }
+	}
+	var keys project.ProjectKeys
+	for key, local := range localProjects {
+		if remote, ok := remoteProjects[key]; ok && match(local, remote) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(keys)
+	return keys, nil
+}
+
+func (c *branchCmd) createBranches(jirix *jiri.X, branch string) error {
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	remoteProjects, _, _, err := project.LoadManifestFile(jirix, jirix.JiriManifestFile(), localProjects, nil)
+	if err != nil {
+		return err
+	}
+	keys, err := c.selectBranchProjects(jirix, localProjects, remoteProjects)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no project selected")
+	}
+
+	var created []project.ProjectKey
+	failures := 0
+	for _, key := range keys {
+		local, remote := localProjects[key], remoteProjects[key]
+		relativePath, err := filepath.Rel(jirix.Cwd, local.Path)
+		if err != nil {
+			relativePath = local.Path
+		}
+		if !c.overrideProjectConfig && (local.LocalConfig.Ignore || local.LocalConfig.NoUpdate) {
+			jirix.Logger.Warningf("Project %s(%s): branch %q won't be created due to its local-config. Use '-override-pc' flag\n\n", local.Name, relativePath, branch)
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", local.Name, relativePath)
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+		upstream := "origin/" + remote.RemoteBranch
+		if remote.RemoteBranch == "" {
+			upstream = "origin/main"
+		}
+		headRev, err := c.createBranch(scm, remote, branch, upstream)
+		if err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while creating branch: %s\n", err))
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s tracking %s at %s\n", jirix.Color.Green("Created branch %s", branch), upstream, jirix.Color.Yellow(shortRev(headRev)))
+		created = append(created, key)
+	}
+	if err := addToBranchSet(jirix, branch, created); err != nil {
+		return err
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to create branch %q in %d project(s)", branch, failures)
+	}
+	return nil
+}
+
+// createBranch creates branch at JIRI_HEAD tracking upstream and checks it
+// out. It returns the revision of JIRI_HEAD.
+func (c *branchCmd) createBranch(scm *gitutil.Git, remote project.Project, branch, upstream string) (string, error) {
+	if exists, err := scm.BranchExists("refs/heads/" + branch); err != nil {
+		return "", err
+	} else if exists {
+		return "", fmt.Errorf("branch %q already exists", branch)
+	}
+	headRev, err := jiriHeadRevision(scm, remote)
+	if err != nil {
+		return "", err
+	}
+	if err := scm.CreateBranchFromRef(branch, headRev); err != nil {
+		return "", err
+	}
+	if err := scm.SetUpstream(branch, upstream); err != nil {
+		scm.DeleteBranch(branch, gitutil.ForceOpt(true))
+		return "", err
+	}
+	if err := scm.Checkout(branch); err != nil {
+		scm.DeleteBranch(branch, gitutil.ForceOpt(true))
+		return "", err
+	}
+	return headRev, nil
+}
+
+func (c *branchCmd) switchBranches(jirix *jiri.X, branch string) error {
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	remoteProjects, _, _, err := project.LoadManifestFile(jirix, jirix.JiriManifestFile(), localProjects, nil)
+	if err != nil {
+		return err
+	}
+	states, err := project.GetProjectStates(jirix, localProjects, false)
+	if err != nil {
+		return err
+	}
+	hasBranch := func(state *project.ProjectState) bool {
+		for _, b := range state.Branches {
+			if b.Name == branch {
+				return true
+			}
+		}
+		return false
+	}
+	var keys project.ProjectKeys
+	found := false
+	for key, local := range localProjects {
+		if _, ok := remoteProjects[key]; !ok {
+			continue
+		}
+		if !c.overrideProjectConfig && (local.LocalConfig.Ignore || local.LocalConfig.NoUpdate) {
+			continue
+		}
+		keys = append(keys, key)
+		found = found || hasBranch(states[key])
+	}
+	if !found {
+		return fmt.Errorf("cannot find any project with branch %q", branch)
+	}
+	sort.Sort(keys)
+
+	failures := 0
+	for _, key := range keys {
+		state, remote := states[key], remoteProjects[key]
+		local := state.Project
+		relativePath, err := filepath.Rel(jirix.Cwd, local.Path)
+		if err != nil {
+			relativePath = local.Path
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+		var message string
+		if hasBranch(state) {
+			if state.CurrentBranch.Name == branch {
+				continue
+			}
+			err = scm.Checkout(branch)
+			message = fmt.Sprintf("Switched to branch %s", branch)
+		} else {
+			var headRev string
+			if headRev, err = jiriHeadRevision(scm, remote); err == nil {
+				if state.CurrentBranch.Name == "" && state.CurrentBranch.Revision == headRev {
+					continue
+				}
+				err = scm.Checkout(headRev, gitutil.DetachOpt(true))
+				message = fmt.Sprintf("Switched to JIRI_HEAD (%s)", shortRev(headRev))
+			}
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", local.Name, relativePath)
+		if err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while switching: %s\n", err))
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("%s", message))
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to switch %d project(s)", failures)
+	}
+	return nil
+}
@@ -730,3 +730,98 @@ func executeBranch(t *testing.T, fake *jiritest.FakeJiriRoot, c branchCmd, args
 	}
 	return strings.TrimSpace(strings.Join([]string{stdout, stderr}, " "))
 }
+
+func TestBranchCreateAndSwitch(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	fake.X.Cwd = fake.X.Root
+
+	branch := "feature"
+	cmd := branchCmd{create: branch, projects: "project-[01]$"}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, localProject := range localProjects[:2] {
+		git := gitutil.New(fake.X, gitutil.RootDirOpt(localProject.Path))
+		if current, err := git.CurrentBranchName(); err != nil {
+			t.Fatal(err)
+		} else if current != branch {
+			t.Errorf("project %s: expected branch %q to be checked out, got %q", localProject.Name, branch, current)
+		}
+		headRev, err := git.CurrentRevisionForRef("JIRI_HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rev, err := git.CurrentRevision(); err != nil {
+			t.Fatal(err)
+		} else if rev != headRev {
+			t.Errorf("project %s: expected branch at JIRI_HEAD %s, got %s", localProject.Name, headRev, rev)
+		}
+		if upstream, err := git.TrackingBranchFromSymbolicRef("refs/heads/" + branch); err != nil {
+			t.Fatal(err)
+		} else if upstream != "origin/main" {
+			t.Errorf("project %s: expected branch to track origin/main, got %q", localProject.Name, upstream)
+		}
+	}
+	set, err := branchSet(fake.X, branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[project.ProjectKey]bool{localProjects[0].Key(): true, localProjects[1].Key(): true}
+	if !cmp.Equal(set, want) {
+		t.Errorf("unexpected branch set: %s", cmp.Diff(want, set))
+	}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Errorf("expected creating an existing branch to fail")
+	}
+
+	// Without a selector the branch is created in the current project.
+	fake.X.Cwd = localProjects[2].Path
+	cmd = branchCmd{create: "other"}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if set, err := branchSet(fake.X, "other"); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 1 || !set[localProjects[2].Key()] {
+		t.Errorf("expected branch set of %q to be project %s, got %v", "other", localProjects[2].Name, set)
+	}
+
+	// Switching checks out JIRI_HEAD in the projects without the branch.
+	git0 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path))
+	if err := git0.Checkout("JIRI_HEAD", gitutil.DetachOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	cmd = branchCmd{switchTo: branch}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := git0.CurrentBranchName(); err != nil || current != branch {
+		t.Errorf("expected branch %q to be checked out in %s, got %q, %v", branch, localProjects[0].Name, current, err)
+	}
+	if git2 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[2].Path)); git2.IsOnBranch() {
+		t.Errorf("expected JIRI_HEAD to be checked out in %s", localProjects[2].Name)
+	}
+	cmd = branchCmd{switchTo: "missing"}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Errorf("expected switching to a missing branch to fail")
+	}
+
+	// Deleting the branch removes it from the branch set.
+	if err := git0.Checkout("JIRI_HEAD", gitutil.DetachOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	cmd = branchCmd{forceDelete: true}
+	if err := cmd.run(fake.X, []string{branch}); err != nil {
+		t.Fatal(err)
+	}
+	if set, err := branchSet(fake.X, branch); err != nil {
+		t.Fatal(err)
+	} else if len(set) != 1 || !set[localProjects[1].Key()] {
+		t.Errorf("expected only project %s in the branch set, got %v", localProjects[1].Name, set)
+	}
+}
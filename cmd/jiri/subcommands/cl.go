@@ -0,0 +1,821 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+// clQueryBatchSize is the maximum number of changes queried at once from a
+// Gerrit host.
+const clQueryBatchSize = 20
+
+type clCmd struct {
+	cmdBase
+
+	jsonOutput    bool
+	refreshMerged bool
+	change        int
+	topic         string
+	host          string
+	message       string
+}
+
+func (c *clCmd) Name() string { return "cl" }
+func (c *clCmd) Synopsis() string {
+	return "Show and manage the Gerrit changes of the local branches"
+}
+func (c *clCmd) Usage() string {
+	return `Shows the Gerrit changes of the local branches of all the projects, and
+reviews, abandons, restores, rebases or submits changes.
+
+Usage:
+  jiri cl [flags] status
+  jiri cl [flags] vote <label>=<value>...
+  jiri cl [flags] abandon
+  jiri cl [flags] restore
+  jiri cl [flags] rebase
+  jiri cl [flags] submit
+  jiri cl [flags] set-topic <topic>
+  jiri cl [flags] add-reviewer <reviewer>...
+
+"jiri cl status" lists the commits of every local branch which are not on its
+upstream branch, with the Gerrit change their Change-Id belongs to: its
+number, its status, its latest patchset and whether the local commit is that
+patchset, its review labels, its number of unresolved comments and whether it
+can be submitted. The changes are queried from the Gerrit host of each
+project.
+
+With -refresh-merged, the branches whose changes are all merged are offered
+for deletion.
+
+The other actions apply to the change given by -change, to the open changes
+of the topic given by -topic, or by default to the open changes of the commits
+of the current branch of the current project. A topic is looked up on the
+Gerrit hosts of all the projects, and its changes are applied in the order of
+their "MultiPart: <index>/<total>" lines if they have some. "restore" applies
+to the abandoned changes of a topic or branch instead.
+
+"jiri cl submit" checks that every change can be submitted before submitting
+any of them. Changes merged along with the previous ones, as hosts submitting
+whole topics do, are skipped.
+`
+}
+
+func (c *clCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.jsonOutput, "json", false, "Print the changes as JSON.")
+	f.BoolVar(&c.refreshMerged, "refresh-merged", false, "Offer to delete the branches whose changes are all merged.")
+	f.IntVar(&c.change, "change", 0, "The number of the change to apply the action to.")
+	f.StringVar(&c.topic, "topic", "", "The topic whose changes to apply the action to.")
+	f.StringVar(&c.host, "host", "", "Gerrit host to use. Defaults to the Gerrit host of the projects.")
+	f.StringVar(&c.message, "m", "", "The message to post with vote, abandon and restore.")
+}
+
+func (c *clCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+// CLStatus is a commit of a local branch and its Gerrit change, printed by
+// "jiri cl status".
+type CLStatus struct {
+	Project  string `json:"project"`
+	Path     string `json:"path"`
+	Branch   string `json:"branch"`
+	Commit   string `json:"commit"`
+	Subject  string `json:"subject"`
+	ChangeID string `json:"change_id,omitempty"`
+	Host     string `json:"host,omitempty"`
+	// The following fields are set if the change was found on the host.
+	Number int    `json:"number,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Status string `json:"status,omitempty"`
+	// Patchset is the number of the latest patchset of the change, and
+	// Uploaded whether the local commit is that patchset.
+	Patchset           int               `json:"patchset,omitempty"`
+	Uploaded           bool              `json:"uploaded"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	UnresolvedComments int               `json:"unresolved_comments"`
+	Submittable        bool              `json:"submittable"`
+	Error              string            `json:"error,omitempty"`
+
+	key           project.ProjectKey
+	gerritProject string
+	change        *gerrit.Change
+}
+
+// merged returns whether the change of the commit is merged.
+func (s CLStatus) merged() bool {
+	return s.Status == "MERGED"
+}
+
+func (c *clCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) == 0 {
+		return jirix.UsageErrorf("no action given")
+	}
+	switch args[0] {
+	case "status":
+		return c.runStatus(jirix, args[1:])
+	case "vote", "abandon", "restore", "rebase", "submit", "set-topic", "add-reviewer":
+		return c.runAction(jirix, args[0], args[1:])
+	default:
+		return jirix.UsageErrorf("unknown action %q", args[0])
+	}
+}
+
+func (c *clCmd) runStatus(jirix *jiri.X, args []string) error {
+	if len(args) != 0 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+	if c.jsonOutput && c.refreshMerged {
+		return jirix.UsageErrorf("-json and -refresh-merged cannot be combined")
+	}
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	remoteProjects, _, _, err := project.LoadManifestFile(jirix, jirix.JiriManifestFile(), localProjects, nil)
+	if err != nil {
+		return err
+	}
+	statuses, err := localBranchCLs(jirix, localProjects, remoteProjects)
+	if err != nil {
+		return err
+	}
+	failures := queryCLStatuses(jirix, statuses)
+
+	if c.jsonOutput {
+		e := json.NewEncoder(jirix.Stdout())
+		e.SetIndent("", " ")
+		if err := e.Encode(statuses); err != nil {
+			return err
+		}
+	} else {
+		printCLStatuses(jirix, statuses)
+	}
+	if c.refreshMerged {
+		if err := deleteMergedCLBranches(jirix, localProjects, remoteProjects, statuses); err != nil {
+			return err
+		}
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to query the changes of %d host(s)", failures)
+	}
+	return nil
+}
+
+// localBranchCLs returns the commits of the local branches of the projects
+// with a Gerrit host which are not on their upstream branch, sorted by
+// project path and branch, the most recent commit of each branch first.
+func localBranchCLs(jirix *jiri.X, localProjects, remoteProjects project.Projects) ([]CLStatus, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := []CLStatus{}
+	errs := make(map[project.ProjectKey]error)
+	limit := make(chan struct{}, jirix.Jobs)
+	for key, local := range localProjects {
+		remote, ok := remoteProjects[key]
+		if !ok || remote.GerritHost == "" {
+			continue
+		}
+		if strings.HasPrefix(local.Remote, "sso://") {
+			jirix.Logger.Debugf("Skipping project %s(%s) as it uses sso protocol\n\n", local.Name, local.Path)
+			continue
+		}
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(key project.ProjectKey, local, remote project.Project) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			projectStatuses, err := projectBranchCLs(jirix, local, remote)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[key] = err
+				return
+			}
+			statuses = append(statuses, projectStatuses...)
+		}(key, local, remote)
+	}
+	wg.Wait()
+	for key, err := range errs {
+		jirix.Logger.Errorf("Project %s(%s): %s\n", localProjects[key].Name, localProjects[key].Path, err)
+	}
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("failed to get the local branches of %d project(s)", len(errs))
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Path != statuses[j].Path {
+			return statuses[i].Path < statuses[j].Path
+		}
+		return statuses[i].Branch < statuses[j].Branch
+	})
+	return statuses, nil
+}
+
+// projectBranchCLs returns the commits of the local branches of a project
+// which are not on their upstream branch.
+func projectBranchCLs(jirix *jiri.X, local, remote project.Project) ([]CLStatus, error) {
+	relativePath, err := filepath.Rel(jirix.Root, local.Path)
+	if err != nil {
+		relativePath = local.Path
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+	branches, err := scm.GetAllBranchesInfo()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []CLStatus
+	for _, b := range branches {
+		trackingBranch := ""
+		if b.Tracking == nil {
+			rb := remote.RemoteBranch
+			if rb == "" {
+				rb = "main"
+			}
+			trackingBranch = fmt.Sprintf("remotes/origin/%s", rb)
+		} else {
+			trackingBranch = b.Tracking.Name
+		}
+		extraCommits, err := scm.ExtraCommits(b.Name, trackingBranch)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the extra commits of branch %q: %s", b.Name, err)
+		}
+		for _, commit := range extraCommits {
+			msg, err := scm.CommitMsg(commit)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get the message of commit %q: %s", commit, err)
+			}
+			status := CLStatus{
+				Project: local.Name,
+				Path:    relativePath,
+				Branch:  b.Name,
+				Commit:  commit,
+				Subject: strings.SplitN(msg, "\n", 2)[0],
+				Host:    remote.GerritHost,
+
+				key:           local.Key(),
+				gerritProject: gerritProjectName(remote.Remote),
+			}
+			if changeID := changeIDRE.FindStringSubmatch(msg); len(changeID) == 2 {
+				status.ChangeID = changeID[1]
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// queryCLStatuses fills statuses with the changes their Change-Ids belong
+// to, querying each Gerrit host in batches. It returns the number of hosts
+// which could not be queried.
+func queryCLStatuses(jirix *jiri.X, statuses []CLStatus) int {
+	byHost := make(map[string][]int)
+	var hosts []string
+	for i, s := range statuses {
+		if s.ChangeID == "" {
+			continue
+		}
+		if _, ok := byHost[s.Host]; !ok {
+			hosts = append(hosts, s.Host)
+		}
+		byHost[s.Host] = append(byHost[s.Host], i)
+	}
+	failures := 0
+	for _, host := range hosts {
+		hostURL, err := url.Parse(host)
+		if err == nil {
+			err = queryHostCLStatuses(gerrit.New(jirix, hostURL), statuses, byHost[host])
+		}
+		if err != nil {
+			failures++
+			jirix.Logger.Errorf("Cannot query the changes of Gerrit host %s: %s\n", host, err)
+			for _, i := range byHost[host] {
+				statuses[i].Error = err.Error()
+			}
+		}
+	}
+	return failures
+}
+
+// queryHostCLStatuses fills the statuses of indices with their changes on
+// the host of g.
+func queryHostCLStatuses(g *gerrit.Gerrit, statuses []CLStatus, indices []int) error {
+	changes := make(map[string][]gerrit.Change)
+	seen := make(map[string]bool)
+	var ids []string
+	for _, i := range indices {
+		if id := statuses[i].ChangeID; !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for start := 0; start < len(ids); start += clQueryBatchSize {
+		end := start + clQueryBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var terms []string
+		for _, id := range ids[start:end] {
+			terms = append(terms, "change:"+id)
+		}
+		cls, err := g.Query(strings.Join(terms, " OR "), gerrit.SubmittableOption)
+		if err != nil {
+			return err
+		}
+		for _, cl := range cls {
+			changes[cl.Change_id] = append(changes[cl.Change_id], cl)
+		}
+	}
+	for _, i := range indices {
+		s := &statuses[i]
+		cls := changes[s.ChangeID]
+		if len(cls) == 0 {
+			continue
+		}
+		// The same Change-Id may be used in several projects or branches,
+		// prefer the change of the local commit, then of the project.
+		cl := cls[0]
+		for _, candidate := range cls {
+			if candidate.Current_revision == s.Commit {
+				cl = candidate
+				break
+			}
+			if candidate.Project == s.gerritProject {
+				cl = candidate
+			}
+		}
+		s.Number = cl.Number
+		s.URL = g.GetChangeURL(cl.Number)
+		s.Status = cl.Status
+		if cl.Submitted != "" && s.Status == "" {
+			s.Status = "MERGED"
+		}
+		s.Patchset = cl.Revisions[cl.Current_revision].Number
+		s.Uploaded = cl.Current_revision == s.Commit
+		s.Labels = clLabels(cl)
+		s.UnresolvedComments = cl.Unresolved_comment_count
+		s.Submittable = cl.Submittable
+		s.change = &cl
+	}
+	return nil
+}
+
+// clLabels summarizes the review labels of a change, as the strongest vote
+// of each label: "approved", "rejected", "recommended" or "disliked".
+func clLabels(cl gerrit.Change) map[string]string {
+	labels := make(map[string]string)
+	for name, info := range cl.Labels {
+		for _, vote := range []string{"rejected", "approved", "disliked", "recommended"} {
+			if _, ok := info[vote]; ok {
+				labels[name] = vote
+				break
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func printCLStatuses(jirix *jiri.X, statuses []CLStatus) {
+	if len(statuses) == 0 {
+		fmt.Fprintf(jirix.Stdout(), "No local branch has commits to review\n")
+		return
+	}
+	w := tabwriter.NewWriter(jirix.Stdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "PROJECT\tBRANCH\tCOMMIT\tCHANGE\tSTATUS\tPATCHSET\tLABELS\tCOMMENTS\tSUBMITTABLE\tSUBJECT\n")
+	for _, s := range statuses {
+		change, status, patchset, labels, submittable := "-", "-", "-", "-", "-"
+		switch {
+		case s.Error != "":
+			status = "error"
+		case s.ChangeID == "":
+			status = "no Change-Id"
+		case s.Number == 0:
+			status = "not uploaded"
+		default:
+			change = fmt.Sprintf("%d", s.Number)
+			status = s.Status
+			patchset = fmt.Sprintf("%d", s.Patchset)
+			if !s.Uploaded {
+				patchset += " (local differs)"
+			}
+			if len(s.Labels) != 0 {
+				var l []string
+				for name, vote := range s.Labels {
+					l = append(l, name+"="+vote)
+				}
+				sort.Strings(l)
+				labels = strings.Join(l, ",")
+			}
+			submittable = fmt.Sprintf("%t", s.Submittable)
+		}
+		fmt.Fprintf(w, "%s(%s)\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.Project, s.Path, s.Branch, shortRev(s.Commit), change, status, patchset, labels, s.UnresolvedComments, submittable, s.Subject)
+	}
+	w.Flush()
+}
+
+// deleteMergedCLBranches offers to delete the local branches whose commits
+// all belong to merged changes.
+func deleteMergedCLBranches(jirix *jiri.X, localProjects, remoteProjects project.Projects, statuses []CLStatus) error {
+	type projectBranch struct {
+		key    project.ProjectKey
+		branch string
+	}
+	merged := make(map[projectBranch]bool)
+	var order []projectBranch
+	for _, s := range statuses {
+		pb := projectBranch{s.key, s.Branch}
+		if _, ok := merged[pb]; !ok {
+			order = append(order, pb)
+			merged[pb] = true
+		}
+		merged[pb] = merged[pb] && s.merged()
+	}
+	stdin := bufio.NewReader(jirix.Stdin())
+	failures := 0
+	for _, pb := range order {
+		if !merged[pb] {
+			continue
+		}
+		local := localProjects[pb.key]
+		relativePath, err := filepath.Rel(jirix.Cwd, local.Path)
+		if err != nil {
+			relativePath = local.Path
+		}
+		fmt.Fprintf(jirix.Stdout(), "The changes of branch %q of project %s(%s) are merged, delete it? [y/N] ", pb.branch, local.Name, relativePath)
+		answer, err := stdin.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			if err == io.EOF {
+				fmt.Fprintln(jirix.Stdout())
+				return nil
+			}
+			continue
+		}
+		if err := deleteMergedCLBranch(jirix, local, remoteProjects[pb.key], pb.branch); err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while deleting branch %q: %s\n", pb.branch, err))
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Deleted branch %q", pb.branch))
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to delete %d branch(es)", failures)
+	}
+	return nil
+}
+
+// deleteMergedCLBranch deletes branch from local, checking out JIRI_HEAD
+// first if it is the current branch.
+func deleteMergedCLBranch(jirix *jiri.X, local, remote project.Project, branch string) error {
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+	current, err := scm.CurrentBranchName()
+	if err != nil {
+		return err
+	}
+	if current == branch {
+		if uncommitted, err := scm.HasUncommittedChanges(); err != nil {
+			return err
+		} else if uncommitted {
+			return fmt.Errorf("it is checked out and has uncommitted changes")
+		}
+		rev, err := jiriHeadRevision(scm, remote)
+		if err != nil {
+			return err
+		}
+		if err := scm.Checkout(rev, gitutil.RecurseSubmodulesOpt(remote.GitSubmodules && jirix.EnableSubmodules), gitutil.DetachOpt(true)); err != nil {
+			return err
+		}
+	}
+	if err := scm.DeleteBranch(branch, gitutil.ForceOpt(true)); err != nil {
+		return err
+	}
+	return removeFromBranchSet(jirix, branch, []project.ProjectKey{local.Key()})
+}
+
+// clTarget is a change an action of "jiri cl" applies to.
+type clTarget struct {
+	g      *gerrit.Gerrit
+	change gerrit.Change
+}
+
+func (t clTarget) id() string {
+	return strconv.Itoa(t.change.Number)
+}
+
+func (c *clCmd) runAction(jirix *jiri.X, action string, args []string) error {
+	if c.change != 0 && c.topic != "" {
+		return jirix.UsageErrorf("-change and -topic cannot be combined")
+	}
+	// apply applies the action to a change and returns a description of
+	// what was done.
+	var apply func(t clTarget) (string, error)
+	switch action {
+	case "vote":
+		if len(args) == 0 {
+			return jirix.UsageErrorf("no vote given")
+		}
+		labels := make(map[string]string)
+		for _, arg := range args {
+			label, value, ok := strings.Cut(arg, "=")
+			if !ok || label == "" || value == "" {
+				return jirix.UsageErrorf("invalid vote %q, expected <label>=<value>", arg)
+			}
+			labels[label] = value
+		}
+		apply = func(t clTarget) (string, error) {
+			return "Voted " + strings.Join(args, " "), t.g.PostReview(t.change.Reference(), c.message, labels)
+		}
+	case "abandon":
+		apply = func(t clTarget) (string, error) {
+			return "Abandoned", t.g.Abandon(t.id(), c.message)
+		}
+	case "restore":
+		apply = func(t clTarget) (string, error) {
+			return "Restored", t.g.Restore(t.id(), c.message)
+		}
+	case "rebase":
+		apply = func(t clTarget) (string, error) {
+			return "Rebased", t.g.Rebase(t.id())
+		}
+	case "submit":
+		apply = func(t clTarget) (string, error) {
+			// Hosts submitting whole topics merge the other changes of the
+			// topic along with the first one.
+			change, err := t.g.GetChange(t.change.Number)
+			if err != nil {
+				return "", err
+			}
+			if change.Status == "MERGED" {
+				return "Already merged", nil
+			}
+			return "Submitted", t.g.Submit(t.id())
+		}
+	case "set-topic":
+		if len(args) != 1 {
+			return jirix.UsageErrorf("set-topic takes exactly one topic")
+		}
+		apply = func(t clTarget) (string, error) {
+			return fmt.Sprintf("Set topic to %q", args[0]), t.g.SetTopic(t.id(), gerrit.CLOpts{Topic: args[0]})
+		}
+	case "add-reviewer":
+		if len(args) == 0 {
+			return jirix.UsageErrorf("no reviewer given")
+		}
+		apply = func(t clTarget) (string, error) {
+			for _, reviewer := range args {
+				if err := t.g.AddReviewer(t.id(), reviewer); err != nil {
+					return "", err
+				}
+			}
+			return "Added " + strings.Join(args, ", "), nil
+		}
+	}
+	if action != "vote" && action != "set-topic" && action != "add-reviewer" && len(args) != 0 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+
+	targets, err := c.targetChanges(jirix, action)
+	if err != nil {
+		return err
+	}
+	if action == "submit" {
+		var blocked []string
+		for _, t := range targets {
+			if !t.change.Submittable {
+				blocked = append(blocked, fmt.Sprintf("%d(%s)", t.change.Number, t.change.Project))
+			}
+		}
+		if len(blocked) != 0 {
+			return fmt.Errorf("not submitting any change, the following changes cannot be submitted: %s", strings.Join(blocked, ", "))
+		}
+	}
+	failures := 0
+	for i, t := range targets {
+		fmt.Fprintf(jirix.Stdout(), "Change %s(%s): ", t.g.GetChangeURL(t.change.Number), t.change.Project)
+		msg, err := apply(t)
+		if err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error: %s\n", err))
+			if action == "submit" && i != len(targets)-1 {
+				return fmt.Errorf("failed to submit change %d, the remaining changes were not submitted", t.change.Number)
+			}
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("%s", msg))
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to %s %d change(s)", action, failures)
+	}
+	return nil
+}
+
+// targetChanges returns the changes the action applies to.
+func (c *clCmd) targetChanges(jirix *jiri.X, action string) ([]clTarget, error) {
+	var options []string
+	if action == "submit" {
+		options = append(options, gerrit.SubmittableOption)
+	}
+	switch {
+	case c.change != 0:
+		host := c.host
+		if host == "" {
+			p, err := currentProject(jirix)
+			if err != nil || p.GerritHost == "" {
+				return nil, fmt.Errorf("no Gerrit host; use the '-host' flag or run this from inside a project with a Gerrit host")
+			}
+			host = p.GerritHost
+		}
+		hostURL, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Gerrit host %q: %s", host, err)
+		}
+		g := gerrit.New(jirix, hostURL)
+		change, err := g.GetChange(c.change, options...)
+		if err != nil {
+			return nil, err
+		}
+		return []clTarget{{g, *change}}, nil
+	case c.topic != "":
+		return c.topicChanges(jirix, action, options)
+	default:
+		return c.currentBranchChanges(jirix, action)
+	}
+}
+
+// targetStatus returns the status of the changes the action applies to, and
+// how to describe it: abandoned changes are restored, and the open ones are
+// acted on otherwise.
+func targetStatus(action string) (status, kind string) {
+	if action == "restore" {
+		return "ABANDONED", "abandoned"
+	}
+	return "NEW", "open"
+}
+
+// topicChanges returns the changes of the topic on the Gerrit hosts of the
+// projects, ordered by part if they are multipart changes. The abandoned
+// changes are returned to restore them, and the open ones otherwise.
+func (c *clCmd) topicChanges(jirix *jiri.X, action string, options []string) ([]clTarget, error) {
+	var hosts []string
+	if c.host != "" {
+		hosts = append(hosts, c.host)
+	} else {
+		localProjects, err := project.LocalProjects(jirix, project.FastScan)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, p := range localProjects {
+			if p.GerritHost != "" && !seen[p.GerritHost] {
+				seen[p.GerritHost] = true
+				hosts = append(hosts, p.GerritHost)
+			}
+		}
+		sort.Strings(hosts)
+	}
+	var targets []clTarget
+	for _, host := range hosts {
+		hostURL, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Gerrit host %q: %s", host, err)
+		}
+		g := gerrit.New(jirix, hostURL)
+		changes, err := g.Query("topic:\""+c.topic+"\"", options...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot query the changes of topic %q from %s: %s", c.topic, host, err)
+		}
+		for _, change := range changes {
+			targets = append(targets, clTarget{g, change})
+		}
+	}
+
+	// Order the parts of a multipart topic, and check that none is missing.
+	set := gerrit.NewMultiPartCLSet()
+	parts := make(map[string]clTarget)
+	partKey := func(change gerrit.Change) string { return fmt.Sprintf("%s/%d", change.Project, change.Number) }
+	var others []clTarget
+	for _, t := range targets {
+		if t.change.MultiPart == nil {
+			others = append(others, t)
+			continue
+		}
+		if err := set.AddCL(t.change); err != nil {
+			return nil, fmt.Errorf("invalid multipart topic %q: %s", c.topic, err)
+		}
+		parts[partKey(t.change)] = t
+	}
+	if len(parts) != 0 {
+		cls := set.CLs()
+		if !set.Complete() {
+			return nil, fmt.Errorf("multipart topic %q is incomplete, found %d of its %d changes", c.topic, len(cls), cls[0].MultiPart.Total)
+		}
+		targets = targets[:0]
+		for _, cl := range cls {
+			targets = append(targets, parts[partKey(cl)])
+		}
+		targets = append(targets, others...)
+	}
+
+	status, kind := targetStatus(action)
+	var ret []clTarget
+	for _, t := range targets {
+		if t.change.Status == status {
+			ret = append(ret, t)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no %s changes found with topic %q", kind, c.topic)
+	}
+	return ret, nil
+}
+
+// currentBranchChanges returns the changes of the commits of the current
+// branch of the current project which are not on its upstream branch, the
+// oldest first. Like for topics, only the abandoned changes are returned to
+// restore them, and the open ones otherwise.
+func (c *clCmd) currentBranchChanges(jirix *jiri.X, action string) ([]clTarget, error) {
+	p, err := currentProject(jirix)
+	if err != nil {
+		return nil, fmt.Errorf("%s, please run with -change or -topic flag", err)
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+	if !scm.IsOnBranch() {
+		return nil, fmt.Errorf("project %s is not on any branch, please run with -change or -topic flag", p.Name)
+	}
+	branch, err := scm.CurrentBranchName()
+	if err != nil {
+		return nil, err
+	}
+	if c.host != "" {
+		p.GerritHost = c.host
+	}
+	if p.GerritHost == "" {
+		return nil, fmt.Errorf("no Gerrit host; use the '-host' flag, or add a 'gerrithost' attribute for project %q", p.Name)
+	}
+	hostURL, err := url.Parse(p.GerritHost)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Gerrit host %q: %s", p.GerritHost, err)
+	}
+	g := gerrit.New(jirix, hostURL)
+
+	projectStatuses, err := projectBranchCLs(jirix, p, p)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []CLStatus
+	for _, s := range projectStatuses {
+		if s.Branch == branch {
+			statuses = append(statuses, s)
+		}
+	}
+	if queryCLStatuses(jirix, statuses) != 0 {
+		return nil, fmt.Errorf("failed to query the changes of branch %q", branch)
+	}
+	status, kind := targetStatus(action)
+	var targets []clTarget
+	seen := make(map[int]bool)
+	for i := len(statuses) - 1; i >= 0; i-- {
+		s := statuses[i]
+		if s.change == nil {
+			jirix.Logger.Warningf("Commit %s of branch %q has no change on %s\n\n", shortRev(s.Commit), branch, p.GerritHost)
+			continue
+		}
+		if s.change.Status != status {
+			jirix.Logger.Debugf("Skipping change %d of branch %q, it is %s", s.Number, branch, strings.ToLower(s.change.Status))
+			continue
+		}
+		if !seen[s.Number] {
+			seen[s.Number] = true
+			targets = append(targets, clTarget{g, *s.change})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no %s change found for branch %q of project %s", kind, branch, p.Name)
+	}
+	return targets, nil
+}
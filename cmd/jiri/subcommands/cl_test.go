@@ -0,0 +1,305 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/jiritest"
+	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+// setupGerritUniverse creates the projects of setupUniverse with the given
+// Gerrit host and updates them.
+func setupGerritUniverse(t *testing.T, host string) ([]project.Project, *jiritest.FakeJiriRoot) {
+	t.Helper()
+	localProjects, fake := setupUniverse(t)
+	m, err := fake.ReadRemoteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range m.Projects {
+		m.Projects[i].GerritHost = host
+	}
+	if err := fake.WriteRemoteManifest(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	return localProjects, fake
+}
+
+func TestCLStatus(t *testing.T) {
+	mergedID, openID := generateChangeIds(1)[0], generateChangeIds(1)[0]
+	var mu sync.Mutex
+	var queries []string
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc("/changes/", func(rw http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		q := r.Form.Get("q")
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		var changes []string
+		for _, term := range strings.Split(q, " OR ") {
+			switch strings.TrimPrefix(term, "change:") {
+			case mergedID:
+				changes = append(changes, fmt.Sprintf(`{"change_id":%q,"_number":1,"status":"MERGED","current_revision":"r1","revisions":{"r1":{"_number":1}}}`, mergedID))
+			case openID:
+				changes = append(changes, fmt.Sprintf(`{"change_id":%q,"_number":2,"status":"NEW","current_revision":"r2","revisions":{"r2":{"_number":3}},"labels":{"Code-Review":{"approved":{}}},"unresolved_comment_count":2,"submittable":true}`, openID))
+			}
+		}
+		rw.Write([]byte(")]}'\n[" + strings.Join(changes, ",") + "]"))
+	})
+	serverMux.HandleFunc("/tools/hooks/commit-msg", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("#!/bin/sh"))
+	})
+	server := httptest.NewServer(serverMux)
+	defer server.Close()
+
+	localProjects, fake := setupGerritUniverse(t, server.URL)
+	git0 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path))
+	if err := git0.CreateBranchWithUpstream("merged", "origin/main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git0.Checkout("merged"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, localProjects[0].Path, "merged", "merged change\n\nChange-Id: "+mergedID)
+	git1 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[1].Path))
+	if err := git1.CreateAndCheckoutBranch("review"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, localProjects[1].Path, "open", "open change\n\nChange-Id: "+openID)
+	writeFile(t, fake.X, localProjects[1].Path, "local", "local change")
+
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	cmd := &clCmd{jsonOutput: true}
+	if err := cmd.run(fake.X, []string{"status"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 {
+		t.Errorf("expected the changes to be queried at once, got queries %q", queries)
+	}
+	var statuses []CLStatus
+	if err := json.Unmarshal(stdout.Bytes(), &statuses); err != nil {
+		t.Fatalf("cannot parse %q: %v", stdout.String(), err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 commits, got %+v", statuses)
+	}
+	if s := statuses[0]; s.Project != localProjects[0].Name || s.Branch != "merged" || s.Number != 1 || s.Status != "MERGED" {
+		t.Errorf("unexpected status of the merged change %+v", s)
+	}
+	if s := statuses[1]; s.Branch != "review" || s.Number != 0 || s.Subject != "local change" {
+		t.Errorf("unexpected status of the change not uploaded %+v", s)
+	}
+	s := statuses[2]
+	if s.Number != 2 || s.Patchset != 3 || s.Uploaded || !s.Submittable || s.UnresolvedComments != 2 || s.Labels["Code-Review"] != "approved" {
+		t.Errorf("unexpected status of the open change %+v", s)
+	}
+	if want := server.URL + "/c/2"; s.URL != want {
+		t.Errorf("got URL %q, want %q", s.URL, want)
+	}
+
+	// Only the branch whose changes are all merged is offered for deletion.
+	stdout.Reset()
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdin: strings.NewReader("y\n"), Stdout: &stdout, Env: fake.X.Context.Env()})
+	cmd = &clCmd{refreshMerged: true}
+	if err := cmd.run(fake.X, []string{"status"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(stdout.String(), "[y/N]"); got != 1 {
+		t.Errorf("expected 1 branch to be offered for deletion, got %d:\n%s", got, stdout.String())
+	}
+	if exists, err := git0.BranchExists("merged"); err != nil || exists {
+		t.Errorf("expected branch merged to be deleted, got %v, %v", exists, err)
+	}
+	if git0.IsOnBranch() {
+		t.Errorf("expected %s to be on JIRI_HEAD", localProjects[0].Name)
+	}
+	if exists, err := git1.BranchExists("review"); err != nil || !exists {
+		t.Errorf("expected branch review to be kept, got %v, %v", exists, err)
+	}
+}
+
+// fakeGerritChange is a change served by the fake Gerrit host of
+// TestCLActions.
+type fakeGerritChange struct {
+	number      int
+	changeID    string
+	project     string
+	status      string
+	submittable bool
+	message     string
+}
+
+// json returns the change as returned by a query, which only includes
+// whether the change is submittable if submittable is set.
+func (c *fakeGerritChange) json(submittable bool) string {
+	rev := fmt.Sprintf("r%d", c.number)
+	fields := ""
+	if submittable {
+		fields = fmt.Sprintf(`"submittable":%t,`, c.submittable)
+	}
+	return fmt.Sprintf(`{"change_id":%q,"_number":%d,"project":%q,"topic":"feature","status":%q,%s"current_revision":%q,"revisions":{%q:{"_number":1,"fetch":{"http":{"ref":"refs/changes/%02d/%d/1"}},"commit":{"message":%q}}}}`,
+		c.changeID, c.number, c.project, c.status, fields, rev, rev, c.number%100, c.number, c.message)
+}
+
+func TestCLActions(t *testing.T) {
+	changes := []*fakeGerritChange{
+		{number: 1, changeID: generateChangeIds(1)[0], project: "project-0", status: "NEW", submittable: true, message: "part 1\n\nMultiPart: 1/2\n"},
+		{number: 2, changeID: generateChangeIds(1)[0], project: "project-1", status: "NEW", message: "part 2\n\nMultiPart: 2/2\n"},
+	}
+	var mu sync.Mutex
+	var posts []string
+	// submittableQueries counts the queries asking whether changes are
+	// submittable.
+	submittableQueries := 0
+	// wholeTopic makes submitting a change merge all the changes.
+	wholeTopic := false
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc("/a/changes/", func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			posts = append(posts, r.URL.Path)
+			if wholeTopic && strings.HasSuffix(r.URL.Path, "/submit") {
+				for _, c := range changes {
+					c.status = "MERGED"
+				}
+			}
+			return
+		}
+		r.ParseForm()
+		submittable := false
+		for _, o := range r.Form["o"] {
+			if o == "SUBMITTABLE" {
+				submittable = true
+				submittableQueries++
+			}
+		}
+		var found []string
+		for _, term := range strings.Split(r.Form.Get("q"), " OR ") {
+			for _, c := range changes {
+				if term == `topic:"feature"` || term == "change:"+c.changeID || term == fmt.Sprintf("change:%d", c.number) {
+					found = append(found, c.json(submittable))
+				}
+			}
+		}
+		rw.Write([]byte(")]}'\n[" + strings.Join(found, ",") + "]"))
+	})
+	serverMux.HandleFunc("/tools/hooks/commit-msg", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("#!/bin/sh"))
+	})
+	server := httptest.NewServer(serverMux)
+	defer server.Close()
+
+	// The actions require credentials for the host.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".netrc"), []byte("machine "+strings.TrimPrefix(server.URL, "http://")+" login user password secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	localProjects, fake := setupGerritUniverse(t, server.URL)
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	runAction := func(cmd *clCmd, args ...string) error {
+		t.Helper()
+		posts = nil
+		submittableQueries = 0
+		return cmd.run(fake.X, args)
+	}
+
+	// Nothing is submitted unless every change of the topic is submittable.
+	if err := runAction(&clCmd{topic: "feature"}, "submit"); err == nil || !strings.Contains(err.Error(), "2(project-1)") {
+		t.Errorf("expected change 2 not to be submittable, got %v", err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("expected no change to be submitted, got %v", posts)
+	}
+	changes[1].submittable = true
+	if err := runAction(&clCmd{topic: "feature"}, "submit"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/a/changes/1/submit", "/a/changes/2/submit"}; strings.Join(posts, " ") != strings.Join(want, " ") {
+		t.Errorf("got requests %v, want %v", posts, want)
+	}
+
+	// Changes merged along with the previous ones are not submitted again.
+	for _, c := range changes {
+		c.status = "NEW"
+	}
+	wholeTopic = true
+	stdout.Reset()
+	if err := runAction(&clCmd{topic: "feature"}, "submit"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/a/changes/1/submit"; len(posts) != 1 || posts[0] != want {
+		t.Errorf("got requests %v, want %v", posts, want)
+	}
+	if !strings.Contains(stdout.String(), "Already merged") {
+		t.Errorf("expected change 2 to be reported as merged:\n%s", stdout.String())
+	}
+	wholeTopic = false
+
+	if err := runAction(&clCmd{change: 1, host: server.URL, message: "LGTM"}, "vote", "Code-Review=+2"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/a/changes/1/revisions/1/review"; len(posts) != 1 || posts[0] != want {
+		t.Errorf("got requests %v, want %v", posts, want)
+	}
+	if submittableQueries != 0 {
+		t.Errorf("expected voting not to query whether changes are submittable")
+	}
+	if err := runAction(&clCmd{change: 1, host: server.URL}, "vote", "Code-Review"); err == nil {
+		t.Errorf("expected an invalid vote to fail")
+	}
+
+	// By default the actions apply to the open changes of the current
+	// branch, skipping the ones already merged.
+	changes[0].status, changes[1].status = "MERGED", "NEW"
+	git := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[1].Path))
+	if err := git.CreateAndCheckoutBranch("feature"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, localProjects[1].Path, "part1", "part 1\n\nChange-Id: "+changes[0].changeID)
+	writeFile(t, fake.X, localProjects[1].Path, "part2", "part 2\n\nChange-Id: "+changes[1].changeID)
+	fake.X.Cwd = localProjects[1].Path
+	if err := runAction(&clCmd{}, "add-reviewer", "a@example.com", "b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/a/changes/2/reviewers", "/a/changes/2/reviewers"}; strings.Join(posts, " ") != strings.Join(want, " ") {
+		t.Errorf("got requests %v, want %v", posts, want)
+	}
+	if err := runAction(&clCmd{}, "submit"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/a/changes/2/submit"; len(posts) != 1 || posts[0] != want {
+		t.Errorf("got requests %v, want %v", posts, want)
+	}
+	if err := runAction(&clCmd{}, "restore"); err == nil || !strings.Contains(err.Error(), "no abandoned change") {
+		t.Errorf("expected no change to be restored, got %v", err)
+	}
+
+	// A multipart topic with missing parts is rejected.
+	changes = changes[1:]
+	if err := runAction(&clCmd{topic: "feature"}, "abandon"); err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("expected the topic to be incomplete, got %v", err)
+	}
+}
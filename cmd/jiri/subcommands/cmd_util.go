@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"sort"
 
 	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/project"
 )
 
@@ -50,3 +52,41 @@ func getDefaultLocalManifestProjects(jirix *jiri.X) ([]string, error) {
 	}
 	return localManifestProjects, nil
 }
+
+// topicBranchProjects returns the local projects which have branch checked
+// out, sorted by path. If branch is empty, the current branch of the project
+// containing the current working directory is used.
+func topicBranchProjects(jirix *jiri.X, branch string) (string, []project.Project, error) {
+	if branch == "" {
+		p, err := currentProject(jirix)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s, please run with -branch flag", err)
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+		if !scm.IsOnBranch() {
+			return "", nil, fmt.Errorf("project %s is not on any branch, please run with -branch flag", p.Name)
+		}
+		if branch, err = scm.CurrentBranchName(); err != nil {
+			return "", nil, err
+		}
+	}
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return "", nil, err
+	}
+	states, err := project.GetProjectStates(jirix, localProjects, false)
+	if err != nil {
+		return "", nil, err
+	}
+	var projects []project.Project
+	for _, state := range states {
+		if state.CurrentBranch.Name == branch {
+			projects = append(projects, state.Project)
+		}
+	}
+	if len(projects) == 0 {
+		return "", nil, fmt.Errorf("no project is on branch %q", branch)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Path < projects[j].Path })
+	return branch, projects, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+)
+
+type commitCmd struct {
+	cmdBase
+
+	all     bool
+	message string
+	branch  string
+}
+
+func (c *commitCmd) Name() string { return "commit" }
+func (c *commitCmd) Synopsis() string {
+	return "Commit the changes of all the projects on the topic branch"
+}
+func (c *commitCmd) Usage() string {
+	return `Commits the staged changes of every project which has the current topic
+branch checked out, with the same message.
+
+Usage:
+  jiri commit [flags] -m <message>
+
+The topic branch is the current branch of the project containing the current
+directory, or the branch given by -branch. Projects without changes to commit
+are skipped.
+`
+}
+
+func (c *commitCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.all, "a", false, "Commit all the changes to tracked files, not only the staged ones.")
+	f.StringVar(&c.message, "m", "", "The commit message.")
+	f.StringVar(&c.branch, "branch", "", "The topic branch. Defaults to the current branch of the current project.")
+}
+
+func (c *commitCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+func (c *commitCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) != 0 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+	if c.message == "" {
+		return jirix.UsageErrorf("a commit message is required, use -m")
+	}
+	branch, projects, err := topicBranchProjects(jirix, c.branch)
+	if err != nil {
+		return err
+	}
+	committed, failures := 0, 0
+	for _, p := range projects {
+		relativePath, err := filepath.Rel(jirix.Cwd, p.Path)
+		if err != nil {
+			relativePath = p.Path
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+		var files []string
+		if c.all {
+			files, err = scm.FilesWithUncommittedChanges()
+		} else {
+			files, err = scm.StagedFiles()
+		}
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", p.Name, relativePath)
+		if c.all {
+			err = scm.AddUpdatedFiles()
+		}
+		if err == nil {
+			err = scm.CommitWithMessage(c.message)
+		}
+		if err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while committing: %s\n", err))
+			continue
+		}
+		rev, err := scm.CurrentRevision()
+		if err != nil {
+			return err
+		}
+		committed++
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Committed %s on %s", shortRev(rev), branch))
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to commit in %d project(s)", failures)
+	}
+	if committed == 0 {
+		fmt.Fprintf(jirix.Stdout(), "Nothing to commit on %s\n", branch)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+// configureUser sets the user name and email in the local configuration of
+// the repository at dir, so that commits created by the commands under test
+// do not depend on the global git configuration.
+func configureUser(t *testing.T, jirix *jiri.X, dir string) {
+	t.Helper()
+	git := gitutil.New(jirix, gitutil.RootDirOpt(dir))
+	if err := git.Config("user.name", "John Doe"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Config("user.email", "john.doe@example.com"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	fake.X.Cwd = fake.X.Root
+	create := branchCmd{create: "topic", projects: "project-[01]$"}
+	if err := create.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range localProjects {
+		if err := os.WriteFile(filepath.Join(p.Path, "README"), []byte("change"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		configureUser(t, fake.X, p.Path)
+	}
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	fake.X.Cwd = localProjects[1].Path
+
+	// Nothing is staged.
+	cmd := &commitCmd{message: "Change the README"}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "Nothing to commit on topic") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	cmd.all = true
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range localProjects {
+		git := gitutil.New(fake.X, gitutil.RootDirOpt(p.Path))
+		msg, err := git.CommitMsg("HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirty, err := git.HasUncommittedChanges()
+		if err != nil {
+			t.Fatal(err)
+		}
+		onBranch := i < 2
+		if committed := strings.HasPrefix(msg, "Change the README"); committed != onBranch || dirty == onBranch {
+			t.Errorf("project %s: got message %q and uncommitted changes %v", p.Name, msg, dirty)
+		}
+	}
+	if err := (&commitCmd{}).run(fake.X, nil); err == nil {
+		t.Errorf("expected a missing message to fail")
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/log"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type containsCmd struct {
+	cmdBase
+
+	jsonOutput string
+	gerrit     bool
+}
+
+func (c *containsCmd) Name() string { return "contains" }
+func (c *containsCmd) Synopsis() string {
+	return "Report which snapshots contain a commit or change"
+}
+func (c *containsCmd) Usage() string {
+	return `Reports which snapshots contain a commit or a Gerrit change.
+
+Usage:
+  jiri contains [flags] <commit|change-id> [<snapshot>...]
+
+<commit|change-id> is a commit hash, possibly abbreviated to at least 7
+characters, or the Change-Id of a Gerrit change, such as "I0123456789abcdef0123456789abcdef01234567".
+
+<snapshot>... are files or urls containing snapshots. If none is given, the
+snapshots in the update history of the jiri root are used.
+
+The commit is looked up in the projects checked out under the jiri root. A
+Change-Id is looked up in the messages of the commits of all their refs, so
+that all the commits of a change cherry-picked to several branches are found.
+If nothing is found and -gerrit is set, the Gerrit hosts of the projects are
+queried instead, and merged changes are looked up at the commit they were
+merged as.
+
+A snapshot contains the commit if it is an ancestor of the revision of its
+project in the snapshot, as checked by "git merge-base --is-ancestor" in the
+local checkout or, if the project is not checked out, in the git cache.
+Missing revisions are fetched first.
+
+With -json-output, the results are also written as JSON:
+
+{
+	query: commit-or-change-id,
+	commits: [
+		{
+			project: name,
+			remote: remote,
+			commit: commit
+		},{...}...
+	],
+	snapshots: [
+		{
+			snapshot: file-or-url,
+			contains: true|false,
+			project: name, // of the commit found in the snapshot
+			commit: commit,
+			revision: revision, // of the project in the snapshot
+			error: error
+		},{...}...
+	]
+}
+`
+}
+
+func (c *containsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.jsonOutput, "json-output", "", "Path to write operation results to.")
+	f.BoolVar(&c.gerrit, "gerrit", true, "Query the Gerrit hosts of the projects if the commit or change is not found locally.")
+}
+
+func (c *containsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+var (
+	commitRE      = regexp.MustCompile("^[0-9a-f]{7,40}$")
+	changeIDArgRE = regexp.MustCompile("^I[0-9a-f]{40}$")
+	decimalRE     = regexp.MustCompile("^[0-9]+$")
+)
+
+// ContainsCommit is a commit matching the query of "jiri contains".
+type ContainsCommit struct {
+	Project string `json:"project"`
+	Remote  string `json:"remote"`
+	Commit  string `json:"commit"`
+
+	// dir is the local checkout of the project.
+	dir string
+}
+
+// ContainsSnapshot reports whether a snapshot contains one of the commits
+// matching the query of "jiri contains".
+type ContainsSnapshot struct {
+	Snapshot string `json:"snapshot"`
+	Contains bool   `json:"contains"`
+	Project  string `json:"project,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ContainsResult struct {
+	Query     string             `json:"query"`
+	Commits   []ContainsCommit   `json:"commits"`
+	Snapshots []ContainsSnapshot `json:"snapshots"`
+}
+
+func (c *containsCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) == 0 {
+		return jirix.UsageErrorf("no commit or change-id given")
+	}
+	query := args[0]
+	isChangeID := changeIDArgRE.MatchString(query)
+	if !isChangeID && !commitRE.MatchString(query) {
+		return jirix.UsageErrorf("%q is neither a commit nor a Change-Id", query)
+	}
+	if decimalRE.MatchString(query) {
+		return jirix.UsageErrorf("%q looks like a change number, use a longer commit hash or the Change-Id of the change", query)
+	}
+	snapshots := args[1:]
+	if len(snapshots) == 0 {
+		var err error
+		if snapshots, err = updateHistorySnapshots(jirix); err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return fmt.Errorf("no snapshots given and the update history is empty")
+		}
+	}
+
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	commits := findLocalCommits(jirix, localProjects, query, isChangeID)
+	if len(commits) == 0 && c.gerrit {
+		if commits, err = findGerritCommits(jirix, localProjects, query, isChangeID); err != nil {
+			return err
+		}
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("%s not found in any project", query)
+	}
+
+	result := ContainsResult{Query: query, Commits: commits}
+	for _, snapshot := range snapshots {
+		s := checkSnapshotContains(jirix, localProjects, snapshot, commits)
+		result.Snapshots = append(result.Snapshots, s)
+		switch {
+		case s.Error != "":
+			fmt.Fprintf(jirix.Stdout(), "%s: error: %s\n", snapshot, s.Error)
+		case s.Contains:
+			fmt.Fprintf(jirix.Stdout(), "%s: contains %s (%s at %s)\n", snapshot, shortRev(s.Commit), s.Project, shortRev(s.Revision))
+		case s.Revision != "":
+			fmt.Fprintf(jirix.Stdout(), "%s: does not contain %s (%s at %s)\n", snapshot, shortRev(s.Commit), s.Project, shortRev(s.Revision))
+		default:
+			fmt.Fprintf(jirix.Stdout(), "%s: does not contain %s (project not in snapshot)\n", snapshot, query)
+		}
+	}
+	if c.jsonOutput != "" {
+		return writeJSONOutput(c.jsonOutput, result)
+	}
+	return nil
+}
+
+// updateHistorySnapshots returns the snapshots in the update history, from
+// the oldest to the newest.
+func updateHistorySnapshots(jirix *jiri.X) ([]string, error) {
+	entries, err := os.ReadDir(jirix.UpdateHistoryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	skip := map[string]bool{
+		filepath.Base(jirix.UpdateHistoryLatestLink()):       true,
+		filepath.Base(jirix.UpdateHistorySecondLatestLink()): true,
+	}
+	var snapshots []string
+	for _, e := range entries {
+		if !e.IsDir() && !skip[e.Name()] {
+			snapshots = append(snapshots, filepath.Join(jirix.UpdateHistoryDir(), e.Name()))
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// findLocalCommits returns the commits matching the commit or Change-Id
+// query in the local checkouts of projects.
+func findLocalCommits(jirix *jiri.X, projects project.Projects, query string, isChangeID bool) []ContainsCommit {
+	var commits []ContainsCommit
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, jirix.Jobs)
+	for _, p := range projects {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(p project.Project) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+			var revs []string
+			if isChangeID {
+				var err error
+				if revs, err = scm.CommitsWithChangeID(query); err != nil {
+					jirix.Logger.Debugf("failed to search %s for %s: %s", p.Name, query, err)
+					return
+				}
+			} else {
+				var err error
+				if revs, err = scm.CommitsWithPrefix(query); err != nil {
+					jirix.Logger.Debugf("failed to search %s for %s: %s", p.Name, query, err)
+					return
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, rev := range revs {
+				commits = append(commits, ContainsCommit{Project: p.Name, Remote: p.Remote, Commit: rev, dir: p.Path})
+			}
+		}(p)
+	}
+	wg.Wait()
+	sortContainsCommits(commits)
+	return commits
+}
+
+// findGerritCommits returns the commits of the changes matching the commit
+// or Change-Id query on the Gerrit hosts of projects.
+func findGerritCommits(jirix *jiri.X, projects project.Projects, query string, isChangeID bool) ([]ContainsCommit, error) {
+	hosts := make(map[string][]project.Project)
+	for _, p := range projects {
+		if p.GerritHost != "" {
+			hosts[p.GerritHost] = append(hosts[p.GerritHost], p)
+		}
+	}
+	var commits []ContainsCommit
+	for host, hostProjects := range hosts {
+		hostURL, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gerrit host %q: %v", host, err)
+		}
+		g := gerrit.New(jirix, hostURL)
+		var cls gerrit.CLList
+		if isChangeID {
+			cls, err = g.Query(query)
+		} else {
+			cls, err = g.Query("commit:"+query, gerrit.AllRevisionsOption)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s for %s: %v", host, query, err)
+		}
+		for _, cl := range cls {
+			commit := cl.Current_revision
+			if !isChangeID && cl.Status != "MERGED" {
+				// The query matches any patchset of the change, possibly
+				// abbreviated.
+				if commit = gerritRevision(cl, query); commit == "" {
+					jirix.Logger.Debugf("no patchset of change %d matches %s", cl.Number, query)
+					continue
+				}
+			}
+			for _, p := range hostProjects {
+				if gerritProjectName(p.Remote) == cl.Project {
+					commits = append(commits, ContainsCommit{Project: p.Name, Remote: p.Remote, Commit: commit, dir: p.Path})
+				}
+			}
+		}
+	}
+	sortContainsCommits(commits)
+	return commits, nil
+}
+
+// gerritRevision returns the full commit hash of the patchset of cl
+// starting with prefix, or "" if there is none.
+func gerritRevision(cl gerrit.Change, prefix string) string {
+	for rev := range cl.Revisions {
+		if strings.HasPrefix(rev, prefix) {
+			return rev
+		}
+	}
+	return ""
+}
+
+// gerritProjectName returns the name of the Gerrit project of remote.
+func gerritProjectName(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+}
+
+func sortContainsCommits(commits []ContainsCommit) {
+	sort.Slice(commits, func(i, j int) bool {
+		if commits[i].Project != commits[j].Project {
+			return commits[i].Project < commits[j].Project
+		}
+		return commits[i].Commit < commits[j].Commit
+	})
+}
+
+// checkSnapshotContains checks whether one of commits is an ancestor of the
+// revision of its project in snapshot.
+func checkSnapshotContains(jirix *jiri.X, localProjects project.Projects, snapshot string, commits []ContainsCommit) ContainsSnapshot {
+	result := ContainsSnapshot{Snapshot: snapshot}
+	oldLogger := jirix.Logger
+	jirix.Logger = log.NewLogger(log.NoLogLevel, jirix.Color, false, 0, oldLogger.TimeLogThreshold(), nil, nil)
+	projects, _, _, err := project.LoadSnapshotFile(jirix, snapshot)
+	jirix.Logger = oldLogger
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, commit := range commits {
+		p, ok := projects[project.MakeProjectKey(commit.Project, commit.Remote)]
+		if !ok {
+			for _, sp := range projects {
+				if sp.Remote == commit.Remote {
+					p, ok = sp, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		result.Project, result.Commit, result.Revision = commit.Project, commit.Commit, p.Revision
+		contains, err := isAncestorInRepo(jirix, localProjects, p, commit)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if contains {
+			result.Contains = true
+			return result
+		}
+	}
+	return result
+}
+
+// isAncestorInRepo returns whether commit is an ancestor of the revision of
+// p, checked in the local checkout of p or in its git cache.
+func isAncestorInRepo(jirix *jiri.X, localProjects project.Projects, p project.Project, commit ContainsCommit) (bool, error) {
+	dir := commit.dir
+	if lp, ok := localProjects[p.Key()]; ok {
+		dir = lp.Path
+	} else if dir == "" && jirix.Cache != "" {
+		cacheDir, err := p.CacheDirPath(jirix)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Stat(cacheDir); err == nil {
+			dir = cacheDir
+		}
+	}
+	if dir == "" {
+		return false, fmt.Errorf("project %s is neither checked out nor cached", p.Name)
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(dir))
+	for _, rev := range []string{commit.Commit, p.Revision} {
+		if scm.IsRevAvailable(jirix, p.Remote, rev) {
+			continue
+		}
+		if err := scm.FetchRefspec(p.Remote, rev); err != nil {
+			return false, fmt.Errorf("failed to fetch %s of project %s: %v", rev, p.Name, err)
+		}
+	}
+	return scm.IsAncestor(commit.Commit, p.Revision)
+}
@@ -0,0 +1,267 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+func TestContains(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "before.xml"), filepath.Join(dir, "after.xml")
+	if err := project.CreateSnapshot(fake.X, before, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const changeID = "I0123456789abcdef0123456789abcdef01234567"
+	remote := fake.Projects[localProjects[1].Name]
+	cmd := exec.Command("git", "commit", "--allow-empty", "-m", "Fix the bug", "-m", "Change-Id: "+changeID)
+	cmd.Dir = remote
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=John Doe", "GIT_AUTHOR_EMAIL=john.doe@example.com",
+		"GIT_COMMITTER_NAME=John Doe", "GIT_COMMITTER_EMAIL=john.doe@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+	commit, err := gitutil.New(fake.X, gitutil.RootDirOpt(remote)).CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Update history snapshots are named after the second they are taken,
+	// date back the first one so that the second update does not replace it.
+	entries, err := os.ReadDir(fake.X.UpdateHistoryDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if _, err := time.Parse(time.RFC3339, e.Name()); err == nil {
+			if err := os.Rename(filepath.Join(fake.X.UpdateHistoryDir(), e.Name()), filepath.Join(fake.X.UpdateHistoryDir(), "2000-01-01T00:00:00Z")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := project.CreateSnapshot(fake.X, after, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	jsonOutput := filepath.Join(dir, "contains.json")
+	c := &containsCmd{jsonOutput: jsonOutput}
+	if err := c.run(fake.X, []string{commit[:12], before, after}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result ContainsResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Commits) != 1 || result.Commits[0].Commit != commit || result.Commits[0].Project != localProjects[1].Name {
+		t.Fatalf("unexpected commits %+v", result.Commits)
+	}
+	if len(result.Snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %+v", result.Snapshots)
+	}
+	if s := result.Snapshots[0]; s.Contains || s.Error != "" || s.Revision == "" {
+		t.Errorf("expected %s not to contain %s, got %+v", before, commit, s)
+	}
+	if s := result.Snapshots[1]; !s.Contains || s.Error != "" || s.Revision != commit {
+		t.Errorf("expected %s to contain %s, got %+v", after, commit, s)
+	}
+	if want := after + ": contains " + shortRev(commit); !strings.Contains(stdout.String(), want) {
+		t.Errorf("output does not contain %q:\n%s", want, stdout.String())
+	}
+
+	// Change-Ids are found in the commit messages and the update history
+	// is used when no snapshot is given.
+	stdout.Reset()
+	c = &containsCmd{}
+	if err := c.run(fake.X, []string{changeID}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected a line per update history snapshot, got:\n%s", stdout.String())
+	}
+	if !strings.Contains(lines[len(lines)-1], ": contains "+shortRev(commit)) {
+		t.Errorf("expected the latest update to contain %s, got:\n%s", commit, stdout.String())
+	}
+	if !strings.Contains(lines[0], ": does not contain") {
+		t.Errorf("expected the first update not to contain %s, got:\n%s", commit, stdout.String())
+	}
+
+	if err := c.run(fake.X, []string{"I1111111111111111111111111111111111111111", after}); err == nil {
+		t.Errorf("expected an unknown Change-Id to fail")
+	}
+
+	// Short hashes and change numbers are rejected, and refs are not
+	// mistaken for commits.
+	c = &containsCmd{}
+	for _, query := range []string{"beef", "1234567"} {
+		if err := c.run(fake.X, []string{query, after}); err == nil {
+			t.Errorf("expected query %q to be rejected", query)
+		}
+	}
+	if err := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path)).CreateBranch("deadbeef"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run(fake.X, []string{"deadbeef", after}); err == nil {
+		t.Errorf("expected branch deadbeef not to match a commit")
+	}
+}
+
+func TestContainsGerrit(t *testing.T) {
+	type change struct {
+		number    int
+		status    string
+		revisions []string
+	}
+	var changes []change
+	var gerritProject string
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc("/changes/", func(rw http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		prefix := strings.TrimPrefix(r.Form.Get("q"), "commit:")
+		allRevisions := false
+		for _, o := range r.Form["o"] {
+			allRevisions = allRevisions || o == "ALL_REVISIONS"
+		}
+		var matches []string
+		for _, c := range changes {
+			current := c.revisions[len(c.revisions)-1]
+			found := false
+			var revisions []string
+			for i, rev := range c.revisions {
+				found = found || strings.HasPrefix(rev, prefix)
+				if allRevisions || rev == current {
+					revisions = append(revisions, fmt.Sprintf(`%q:{"_number":%d}`, rev, i+1))
+				}
+			}
+			if found {
+				matches = append(matches, fmt.Sprintf(`{"project":%q,"_number":%d,"status":%q,"current_revision":%q,"revisions":{%s}}`,
+					gerritProject, c.number, c.status, current, strings.Join(revisions, ",")))
+			}
+		}
+		rw.Write([]byte(")]}'\n[" + strings.Join(matches, ",") + "]"))
+	})
+	server := httptest.NewServer(serverMux)
+	defer server.Close()
+
+	localProjects, fake := setupGerritUniverse(t, server.URL)
+	p := localProjects[1]
+	gerritProject = gerritProjectName(p.Remote)
+	remote := fake.Projects[p.Name]
+	scm := gitutil.New(fake.X, gitutil.RootDirOpt(remote))
+	// Uploads a patchset to ref of the remote, where jiri update does not
+	// fetch it from.
+	upload := func(ref, message string) string {
+		if err := scm.CreateAndCheckoutBranch("upload"); err != nil {
+			t.Fatal(err)
+		}
+		writeReadme(t, fake.X, remote, message)
+		rev, err := scm.CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := scm.Checkout("main"); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("git", "update-ref", ref, rev)
+		cmd.Dir = remote
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git update-ref failed: %v\n%s", err, out)
+		}
+		if err := scm.DeleteBranch("upload", gitutil.ForceOpt(true)); err != nil {
+			t.Fatal(err)
+		}
+		return rev
+	}
+	open := upload("refs/changes/01/1/1", "open change")
+	uploaded := upload("refs/changes/02/2/1", "merged change")
+	writeReadme(t, fake.X, remote, "merged change")
+	merged, err := scm.CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes = []change{
+		{number: 1, status: "NEW", revisions: []string{open, strings.Repeat("0", 40)}},
+		{number: 2, status: "MERGED", revisions: []string{uploaded, merged}},
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "snapshot.xml")
+	if err := project.CreateSnapshot(fake.X, snapshot, nil, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// abbrev returns the shortest abbreviation of rev which is not mistaken
+	// for a change number.
+	abbrev := func(rev string) string {
+		n := 7
+		for decimalRE.MatchString(rev[:n]) {
+			n++
+		}
+		return rev[:n]
+	}
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &bytes.Buffer{}, Env: fake.X.Context.Env()})
+	for _, test := range []struct {
+		query, commit string
+		contains      bool
+	}{
+		// Abbreviated patchsets which are not the current one are looked up
+		// in all the revisions of open changes.
+		{abbrev(open), open, false},
+		// Merged changes are checked at the commit they were merged as.
+		{abbrev(uploaded), merged, true},
+	} {
+		jsonOutput := filepath.Join(t.TempDir(), "contains.json")
+		c := &containsCmd{jsonOutput: jsonOutput, gerrit: true}
+		if err := c.run(fake.X, []string{test.query, snapshot}); err != nil {
+			t.Fatalf("query %s: %v", test.query, err)
+		}
+		data, err := os.ReadFile(jsonOutput)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result ContainsResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Commits) != 1 || result.Commits[0].Commit != test.commit || result.Commits[0].Project != p.Name {
+			t.Errorf("query %s: expected commit %s of %s, got %+v", test.query, test.commit, p.Name, result.Commits)
+			continue
+		}
+		if len(result.Snapshots) != 1 {
+			t.Fatalf("query %s: expected 1 snapshot, got %+v", test.query, result.Snapshots)
+		}
+		if s := result.Snapshots[0]; s.Error != "" || s.Contains != test.contains {
+			t.Errorf("query %s: expected contains to be %v, got %+v", test.query, test.contains, s)
+		}
+	}
+}
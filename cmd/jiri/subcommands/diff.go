@@ -9,14 +9,17 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net/url"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/google/subcommands"
 	"go.fuchsia.dev/jiri"
 	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/log"
 	"go.fuchsia.dev/jiri/project"
 )
@@ -25,7 +28,17 @@ type diffCmd struct {
 	cmdBase
 
 	cls          bool
+	allCls       bool
 	indentOutput bool
+	format       string
+
+	// commitDates makes -all-cls record the committer dates of commits
+	// instead of their author dates.
+	commitDates bool
+
+	// firstParent makes -all-cls list only the commits on the first-parent
+	// chain of the new revisions, in topological order, oldest first.
+	firstParent bool
 
 	// Need this to avoid infinite loop
 	maxCls uint
@@ -77,9 +90,65 @@ returned json:
 			has_more_cls: true,
 			error: error in retrieving CL
 		},{...}...
+	],
+	new_packages: [
+		{
+			name: name,
+			path: path,
+			version: version,
+			instances: [
+				{
+					name: instance-package-name,
+					instance_id: id
+				},{...}...
+			]
+		},{...}...
+	],
+	deleted_packages: [...],
+	updated_packages: [
+		{
+			name: name,
+			path: path,
+			version: version,
+			old_version: old-version, // if updated
+			instances: [
+				{
+					name: instance-package-name,
+					instance_id: id,
+					old_instance_id: old-id
+				},{...}...
+			]
+		},{...}...
+	],
+	new_hooks: [
+		{
+			name: name,
+			project: project,
+			action: action
+		},{...}...
+	],
+	deleted_hooks: [...],
+	updated_hooks: [
+		{
+			name: name,
+			project: project,
+			action: action,
+			old_action: old-action
+		},{...}...
 	]
 }
 
+Package and hook changes are omitted if there are none.
+
+With -all-cls, every commit between the old and new revisions of an updated
+project is listed, with its author and date, instead of at most -max-cls
+CLs. The commits are read from the local checkouts of the projects, which
+are fetched if needed, and are matched with their CLs if -cls is set.
+
+With -format=text or -format=markdown, the diff is printed in a human
+readable form, with the CLs grouped per project, e.g. for release notes or
+roll commit messages.
+
 Usage:
   jiri diff [flags] <snapshot-1> <snapshot-2>
 
@@ -91,6 +160,8 @@ func (c *diffCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.cls, "cls", true, "Return CLs for changed projects")
 	f.BoolVar(&c.indentOutput, "indent", true, "Indent json output")
 	f.UintVar(&c.maxCls, "max-cls", 5, "Max number of CLs returned per changed project")
+	f.BoolVar(&c.allCls, "all-cls", false, "List every commit of changed projects with its author and date, ignoring -max-cls")
+	f.StringVar(&c.format, "format", "json", "Output format, \"json\", \"text\" or \"markdown\"")
 }
 
 func (c *diffCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -101,10 +172,21 @@ func (c *diffCmd) run(jirix *jiri.X, args []string) error {
 	if len(args) != 2 {
 		return jirix.UsageErrorf("Please provide two snapshots to diff")
 	}
+	if c.format != "json" && c.format != "text" && c.format != "markdown" {
+		return jirix.UsageErrorf("unsupported format %q", c.format)
+	}
 	d, err := c.getDiff(jirix, args[0], args[1])
 	if err != nil {
 		return err
 	}
+	switch c.format {
+	case "text":
+		d.writeText(jirix.Stdout())
+		return nil
+	case "markdown":
+		d.writeMarkdown(jirix.Stdout())
+		return nil
+	}
 	e := json.NewEncoder(jirix.Stdout())
 	if c.indentOutput {
 		e.SetIndent("", " ")
@@ -123,16 +205,25 @@ func (c *diffCmd) getDiff(jirix *jiri.X, snapshot1, snapshot2 string) (*Diff, er
 		jirix.Logger = oldLogger
 	}()
 	jirix.Logger = log.NewLogger(log.NoLogLevel, jirix.Color, false, 0, oldLogger.TimeLogThreshold(), nil, nil)
-	projects1, _, _, err := project.LoadSnapshotFile(jirix, snapshot1)
+	projects1, hooks1, pkgs1, err := project.LoadSnapshotFile(jirix, snapshot1)
 	if err != nil {
 		return nil, err
 	}
-	projects2, _, _, err := project.LoadSnapshotFile(jirix, snapshot2)
+	projects2, hooks2, pkgs2, err := project.LoadSnapshotFile(jirix, snapshot2)
 	if err != nil {
 		return nil, err
 	}
 	project.MatchLocalWithRemote(projects1, projects2)
 	jirix.Logger = oldLogger
+	diff.diffPackages(pkgs1, pkgs2)
+	diff.diffHooks(hooks1, hooks2)
+
+	var localProjects project.Projects
+	if c.allCls {
+		if localProjects, err = project.LocalProjects(jirix, project.FastScan); err != nil {
+			return nil, err
+		}
+	}
 
 	// Get deleted projects
 	for key, p1 := range projects1 {
@@ -204,7 +295,9 @@ func (c *diffCmd) getDiff(jirix *jiri.X, snapshot1, snapshot2 string) (*Diff, er
 		}
 		if p1.Revision != p2.Revision {
 			diffP.OldRevision = p1.Revision
-			if !c.cls {
+			if c.allCls {
+				c.listAllCommits(jirix, localProjects, p2, p1.Revision, &diffP)
+			} else if !c.cls {
 				// do nothing, prevents nested if/else
 			} else if p2.GerritHost == "" {
 				diffP.Error = "no gerrit host"
@@ -276,11 +369,149 @@ func (c *diffCmd) getDiff(jirix *jiri.X, snapshot1, snapshot2 string) (*Diff, er
 	return diff.Sort(), nil
 }
 
+// listAllCommits records every commit between oldRevision and the revision
+// of p in diffP, using the local checkout of p. Failures are recorded in
+// diffP.Error.
+func (c *diffCmd) listAllCommits(jirix *jiri.X, localProjects project.Projects, p project.Project, oldRevision string, diffP *DiffProject) {
+	local, ok := localProjects[p.Key()]
+	if !ok {
+		for _, lp := range localProjects {
+			if lp.Remote == p.Remote {
+				local, ok = lp, true
+				break
+			}
+		}
+	}
+	if !ok {
+		diffP.Error = "project is not checked out locally"
+		return
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+	for _, rev := range []string{oldRevision, p.Revision} {
+		if scm.IsRevAvailable(jirix, p.Remote, rev) {
+			continue
+		}
+		if err := scm.FetchRefspec(p.Remote, rev); err != nil {
+			diffP.Error = fmt.Sprintf("failed to fetch %s: %s", rev, err)
+			return
+		}
+	}
+	date := "%aI"
+	if c.commitDates {
+		date = "%cI"
+	}
+	args := []string{oldRevision + ".." + p.Revision}
+	if c.firstParent {
+		args = append(args, "--first-parent", "--topo-order", "--reverse")
+	}
+	entries, err := scm.LogCommits("%H%n%an%n"+date+"%n%s", args...)
+	if err != nil {
+		diffP.Error = fmt.Sprintf("failed to list commits: %s", err)
+		return
+	}
+	var g *gerrit.Gerrit
+	if c.cls && p.GerritHost != "" {
+		if hostURL, err := url.Parse(p.GerritHost); err != nil {
+			diffP.Error = fmt.Sprintf("invalid gerrit host %q: %s", p.GerritHost, err)
+		} else {
+			g = gerrit.New(jirix, hostURL)
+		}
+	}
+	for _, e := range entries {
+		if len(e) < 4 {
+			continue
+		}
+		diffCl := DiffCl{Commit: e[0], Author: e[1], Date: e[2], Subject: e[3]}
+		if g != nil {
+			cls, err := g.ListChangesByCommit(diffCl.Commit)
+			if err != nil {
+				diffP.Error = fmt.Sprintf("not able to get CL for revision %s: %s", diffCl.Commit, err)
+				g = nil
+			} else if len(cls) != 0 {
+				diffCl.Number = cls[0].Number
+				diffCl.URL = fmt.Sprintf("%s/c/%d", strings.TrimSuffix(p.GerritHost, "/"), cls[0].Number)
+			}
+		}
+		diffP.Cls = append(diffP.Cls, diffCl)
+	}
+}
+
+// diffPackages records the packages which are new in pkgs2, deleted from
+// pkgs1 or whose versions or instances changed.
+func (d *Diff) diffPackages(pkgs1, pkgs2 project.Packages) {
+	instances := func(pkg project.Package) map[string]string {
+		ret := make(map[string]string)
+		for _, ins := range pkg.Instances {
+			ret[ins.Name] = ins.ID
+		}
+		return ret
+	}
+	newDiffPackage := func(pkg project.Package) DiffPackage {
+		diffPkg := DiffPackage{Name: pkg.Name, Path: pkg.Path, Version: pkg.Version}
+		for _, ins := range pkg.Instances {
+			diffPkg.Instances = append(diffPkg.Instances, DiffPackageInstance{Name: ins.Name, InstanceID: ins.ID})
+		}
+		return diffPkg
+	}
+	for key, pkg1 := range pkgs1 {
+		if _, ok := pkgs2[key]; !ok {
+			d.DeletedPackages = append(d.DeletedPackages, newDiffPackage(pkg1))
+		}
+	}
+	for key, pkg2 := range pkgs2 {
+		pkg1, ok := pkgs1[key]
+		if !ok {
+			d.NewPackages = append(d.NewPackages, newDiffPackage(pkg2))
+			continue
+		}
+		diffPkg := DiffPackage{Name: pkg2.Name, Path: pkg2.Path, Version: pkg2.Version}
+		if pkg1.Version != pkg2.Version {
+			diffPkg.OldVersion = pkg1.Version
+		}
+		ids1, ids2 := instances(pkg1), instances(pkg2)
+		names := make(map[string]bool)
+		for name := range ids1 {
+			names[name] = true
+		}
+		for name := range ids2 {
+			names[name] = true
+		}
+		for name := range names {
+			if ids1[name] != ids2[name] {
+				diffPkg.Instances = append(diffPkg.Instances, DiffPackageInstance{Name: name, InstanceID: ids2[name], OldInstanceID: ids1[name]})
+			}
+		}
+		if diffPkg.OldVersion != "" || len(diffPkg.Instances) != 0 {
+			sort.Slice(diffPkg.Instances, func(i, j int) bool { return diffPkg.Instances[i].Name < diffPkg.Instances[j].Name })
+			d.UpdatedPackages = append(d.UpdatedPackages, diffPkg)
+		}
+	}
+}
+
+// diffHooks records the hooks which are new in hooks2, deleted from hooks1
+// or whose actions changed.
+func (d *Diff) diffHooks(hooks1, hooks2 project.Hooks) {
+	for key, h1 := range hooks1 {
+		if _, ok := hooks2[key]; !ok {
+			d.DeletedHooks = append(d.DeletedHooks, DiffHook{Name: h1.Name, Project: h1.ProjectName, Action: h1.Action})
+		}
+	}
+	for key, h2 := range hooks2 {
+		if h1, ok := hooks1[key]; !ok {
+			d.NewHooks = append(d.NewHooks, DiffHook{Name: h2.Name, Project: h2.ProjectName, Action: h2.Action})
+		} else if h1.Action != h2.Action {
+			d.UpdatedHooks = append(d.UpdatedHooks, DiffHook{Name: h2.Name, Project: h2.ProjectName, Action: h2.Action, OldAction: h1.Action})
+		}
+	}
+}
+
 type DiffCl struct {
 	Commit  string `json:"commit"`
 	Number  int    `json:"number"`
 	Subject string `json:"subject"`
 	URL     string `json:"url"`
+	Author  string `json:"author,omitempty"`
+	Date    string `json:"date,omitempty"`
 }
 
 type DiffProject struct {
@@ -309,15 +540,253 @@ func (p DiffProjectsByName) Less(i, j int) bool {
 	return p[i].Name < p[j].Name
 }
 
+type DiffPackageInstance struct {
+	Name          string `json:"name"`
+	InstanceID    string `json:"instance_id,omitempty"`
+	OldInstanceID string `json:"old_instance_id,omitempty"`
+}
+
+type DiffPackage struct {
+	Name       string                `json:"name"`
+	Path       string                `json:"path"`
+	Version    string                `json:"version"`
+	OldVersion string                `json:"old_version,omitempty"`
+	Instances  []DiffPackageInstance `json:"instances,omitempty"`
+}
+
+type DiffHook struct {
+	Name      string `json:"name"`
+	Project   string `json:"project"`
+	Action    string `json:"action"`
+	OldAction string `json:"old_action,omitempty"`
+}
+
 type Diff struct {
 	NewProjects     []DiffProject `json:"new_projects"`
 	DeletedProjects []DiffProject `json:"deleted_projects"`
 	UpdatedProjects []DiffProject `json:"updated_projects"`
+	NewPackages     []DiffPackage `json:"new_packages,omitempty"`
+	DeletedPackages []DiffPackage `json:"deleted_packages,omitempty"`
+	UpdatedPackages []DiffPackage `json:"updated_packages,omitempty"`
+	NewHooks        []DiffHook    `json:"new_hooks,omitempty"`
+	DeletedHooks    []DiffHook    `json:"deleted_hooks,omitempty"`
+	UpdatedHooks    []DiffHook    `json:"updated_hooks,omitempty"`
 }
 
 func (d *Diff) Sort() *Diff {
 	sort.Sort(DiffProjectsByName(d.NewProjects))
 	sort.Sort(DiffProjectsByName(d.DeletedProjects))
 	sort.Sort(DiffProjectsByName(d.UpdatedProjects))
+	for _, pkgs := range [][]DiffPackage{d.NewPackages, d.DeletedPackages, d.UpdatedPackages} {
+		sort.Slice(pkgs, func(i, j int) bool {
+			if pkgs[i].Name != pkgs[j].Name {
+				return pkgs[i].Name < pkgs[j].Name
+			}
+			return pkgs[i].Path < pkgs[j].Path
+		})
+	}
+	for _, hooks := range [][]DiffHook{d.NewHooks, d.DeletedHooks, d.UpdatedHooks} {
+		sort.Slice(hooks, func(i, j int) bool {
+			if hooks[i].Project != hooks[j].Project {
+				return hooks[i].Project < hooks[j].Project
+			}
+			return hooks[i].Name < hooks[j].Name
+		})
+	}
 	return d
 }
+
+// describe returns a one line description of cl, with its url if it has
+// one and its commit otherwise.
+func (cl DiffCl) describe() string {
+	s := shortRev(cl.Commit)
+	if cl.URL != "" {
+		s = cl.URL
+	}
+	s += " " + cl.Subject
+	if cl.Author != "" {
+		s += fmt.Sprintf(" (%s, %s)", cl.Author, cl.Date)
+	}
+	return s
+}
+
+// revisionChange returns a description of how the revision or path of an
+// updated project changed.
+func (p DiffProject) revisionChange() string {
+	var changes []string
+	if p.OldRevision != "" {
+		changes = append(changes, fmt.Sprintf("%s -> %s", shortRev(p.OldRevision), shortRev(p.Revision)))
+	}
+	if p.OldRelativePath != "" {
+		changes = append(changes, fmt.Sprintf("moved from %s to %s", p.OldRelativePath, p.RelativePath))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// versionChange returns a description of how the version of an updated
+// package changed.
+func (p DiffPackage) versionChange() string {
+	if p.OldVersion != "" {
+		return fmt.Sprintf("%s -> %s", p.OldVersion, p.Version)
+	}
+	return p.Version
+}
+
+func (i DiffPackageInstance) idChange() string {
+	oldID, id := i.OldInstanceID, i.InstanceID
+	if oldID == "" {
+		oldID = "(none)"
+	}
+	if id == "" {
+		id = "(none)"
+	}
+	return fmt.Sprintf("%s -> %s", oldID, id)
+}
+
+// writeText writes d to w in a human readable text format.
+func (d *Diff) writeText(w io.Writer) {
+	section := func(title string, n int) bool {
+		if n != 0 {
+			fmt.Fprintf(w, "%s:\n", title)
+		}
+		return n != 0
+	}
+	if section("Updated projects", len(d.UpdatedProjects)) {
+		for _, p := range d.UpdatedProjects {
+			fmt.Fprintf(w, "  %s (%s): %s\n", p.Name, p.RelativePath, p.revisionChange())
+			for _, cl := range p.Cls {
+				fmt.Fprintf(w, "    %s\n", cl.describe())
+			}
+			if p.HasMoreCls {
+				fmt.Fprintf(w, "    ...\n")
+			}
+			if p.Error != "" {
+				fmt.Fprintf(w, "    error: %s\n", p.Error)
+			}
+		}
+	}
+	if section("New projects", len(d.NewProjects)) {
+		for _, p := range d.NewProjects {
+			fmt.Fprintf(w, "  %s (%s) at %s\n", p.Name, p.RelativePath, shortRev(p.Revision))
+		}
+	}
+	if section("Deleted projects", len(d.DeletedProjects)) {
+		for _, p := range d.DeletedProjects {
+			fmt.Fprintf(w, "  %s (%s) at %s\n", p.Name, p.RelativePath, shortRev(p.Revision))
+		}
+	}
+	if section("Updated packages", len(d.UpdatedPackages)) {
+		for _, p := range d.UpdatedPackages {
+			fmt.Fprintf(w, "  %s (%s): %s\n", p.Name, p.Path, p.versionChange())
+			for _, i := range p.Instances {
+				fmt.Fprintf(w, "    %s: %s\n", i.Name, i.idChange())
+			}
+		}
+	}
+	if section("New packages", len(d.NewPackages)) {
+		for _, p := range d.NewPackages {
+			fmt.Fprintf(w, "  %s (%s) at %s\n", p.Name, p.Path, p.Version)
+		}
+	}
+	if section("Deleted packages", len(d.DeletedPackages)) {
+		for _, p := range d.DeletedPackages {
+			fmt.Fprintf(w, "  %s (%s) at %s\n", p.Name, p.Path, p.Version)
+		}
+	}
+	if section("Updated hooks", len(d.UpdatedHooks)) {
+		for _, h := range d.UpdatedHooks {
+			fmt.Fprintf(w, "  %s (%s): %s -> %s\n", h.Name, h.Project, h.OldAction, h.Action)
+		}
+	}
+	if section("New hooks", len(d.NewHooks)) {
+		for _, h := range d.NewHooks {
+			fmt.Fprintf(w, "  %s (%s): %s\n", h.Name, h.Project, h.Action)
+		}
+	}
+	if section("Deleted hooks", len(d.DeletedHooks)) {
+		for _, h := range d.DeletedHooks {
+			fmt.Fprintf(w, "  %s (%s): %s\n", h.Name, h.Project, h.Action)
+		}
+	}
+}
+
+// writeMarkdown writes d to w in markdown, with a section per updated
+// project listing its CLs.
+func (d *Diff) writeMarkdown(w io.Writer) {
+	section := func(title string, n int) bool {
+		if n != 0 {
+			fmt.Fprintf(w, "## %s\n\n", title)
+		}
+		return n != 0
+	}
+	if section("Updated projects", len(d.UpdatedProjects)) {
+		for _, p := range d.UpdatedProjects {
+			fmt.Fprintf(w, "### %s\n\n`%s`: %s\n\n", p.Name, p.RelativePath, p.revisionChange())
+			for _, cl := range p.Cls {
+				title := "`" + shortRev(cl.Commit) + "`"
+				if cl.URL != "" {
+					title = fmt.Sprintf("[%d](%s)", cl.Number, cl.URL)
+				}
+				fmt.Fprintf(w, "* %s %s", title, cl.Subject)
+				if cl.Author != "" {
+					fmt.Fprintf(w, " (%s, %s)", cl.Author, cl.Date)
+				}
+				fmt.Fprintf(w, "\n")
+			}
+			if p.HasMoreCls {
+				fmt.Fprintf(w, "* ...\n")
+			}
+			if p.Error != "" {
+				fmt.Fprintf(w, "\n_Error: %s_\n", p.Error)
+			}
+			if len(p.Cls) != 0 || p.HasMoreCls || p.Error != "" {
+				fmt.Fprintf(w, "\n")
+			}
+		}
+	}
+	list := func(title string, projects []DiffProject) {
+		if section(title, len(projects)) {
+			for _, p := range projects {
+				fmt.Fprintf(w, "* **%s** `%s` at `%s`\n", p.Name, p.RelativePath, shortRev(p.Revision))
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	list("New projects", d.NewProjects)
+	list("Deleted projects", d.DeletedProjects)
+	if section("Updated packages", len(d.UpdatedPackages)) {
+		for _, p := range d.UpdatedPackages {
+			fmt.Fprintf(w, "* **%s** `%s`: %s\n", p.Name, p.Path, p.versionChange())
+			for _, i := range p.Instances {
+				fmt.Fprintf(w, "  * `%s`: %s\n", i.Name, i.idChange())
+			}
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	listPackages := func(title string, pkgs []DiffPackage) {
+		if section(title, len(pkgs)) {
+			for _, p := range pkgs {
+				fmt.Fprintf(w, "* **%s** `%s` at `%s`\n", p.Name, p.Path, p.Version)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	listPackages("New packages", d.NewPackages)
+	listPackages("Deleted packages", d.DeletedPackages)
+	if section("Updated hooks", len(d.UpdatedHooks)) {
+		for _, h := range d.UpdatedHooks {
+			fmt.Fprintf(w, "* **%s** (%s): `%s` -> `%s`\n", h.Name, h.Project, h.OldAction, h.Action)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	listHooks := func(title string, hooks []DiffHook) {
+		if section(title, len(hooks)) {
+			for _, h := range hooks {
+				fmt.Fprintf(w, "* **%s** (%s): `%s`\n", h.Name, h.Project, h.Action)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	listHooks("New hooks", d.NewHooks)
+	listHooks("Deleted hooks", d.DeletedHooks)
+}
@@ -5,15 +5,18 @@
 package subcommands
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/jiritest"
 	"go.fuchsia.dev/jiri/project"
 )
@@ -183,3 +186,185 @@ func TestDiffSnapshotsURL(t *testing.T) {
 		t.Fatalf("Wrong diff (-want +got):\n%s", d)
 	}
 }
+
+// writeSnapshot writes m to a file in a temporary directory and returns its
+// path.
+func writeSnapshot(t *testing.T, m *project.Manifest) string {
+	t.Helper()
+	b, err := m.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "snapshot")
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestDiffPackagesAndHooks(t *testing.T) {
+	t.Parallel()
+
+	fake := jiritest.NewFakeJiriRoot(t)
+	p := project.Project{Name: "project-0", Path: "path-0", Remote: "remote-url", Revision: "revision-0"}
+	m1 := &project.Manifest{
+		Version:  project.ManifestVersion,
+		Projects: []project.Project{p},
+		Hooks: []project.Hook{
+			{Name: "deleted", ProjectName: p.Name, Action: "deleted.sh"},
+			{Name: "updated", ProjectName: p.Name, Action: "old.sh"},
+		},
+		Packages: []project.Package{
+			{Name: "tools/deleted", Path: "prebuilt/deleted", Version: "version:1", Instances: []project.PackageInstance{{Name: "tools/deleted", ID: "deleted-id"}}},
+			{Name: "tools/same", Path: "prebuilt/same", Version: "version:1", Instances: []project.PackageInstance{{Name: "tools/same", ID: "same-id"}}},
+			{Name: "tools/updated", Path: "prebuilt/updated", Version: "version:1", Instances: []project.PackageInstance{{Name: "tools/updated", ID: "old-id"}}},
+			{Name: "tools/rebuilt", Path: "prebuilt/rebuilt", Version: "latest", Instances: []project.PackageInstance{{Name: "tools/rebuilt", ID: "old-id"}}},
+		},
+	}
+	m2 := &project.Manifest{
+		Version:  project.ManifestVersion,
+		Projects: []project.Project{p},
+		Hooks: []project.Hook{
+			{Name: "new", ProjectName: p.Name, Action: "new.sh"},
+			{Name: "updated", ProjectName: p.Name, Action: "new.sh"},
+		},
+		Packages: []project.Package{
+			{Name: "tools/new", Path: "prebuilt/new", Version: "version:1", Instances: []project.PackageInstance{{Name: "tools/new", ID: "new-id"}}},
+			{Name: "tools/same", Path: "prebuilt/same", Version: "version:1", Instances: []project.PackageInstance{{Name: "tools/same", ID: "same-id"}}},
+			{Name: "tools/updated", Path: "prebuilt/updated", Version: "version:2", Instances: []project.PackageInstance{{Name: "tools/updated", ID: "new-id"}}},
+			{Name: "tools/rebuilt", Path: "prebuilt/rebuilt", Version: "latest", Instances: []project.PackageInstance{{Name: "tools/rebuilt", ID: "new-id"}}},
+		},
+	}
+
+	d, err := (&diffCmd{}).getDiff(fake.X, writeSnapshot(t, m1), writeSnapshot(t, m2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Diff{
+		NewProjects:     []DiffProject{},
+		DeletedProjects: []DiffProject{},
+		UpdatedProjects: []DiffProject{},
+		NewPackages: []DiffPackage{
+			{Name: "tools/new", Path: "prebuilt/new", Version: "version:1", Instances: []DiffPackageInstance{{Name: "tools/new", InstanceID: "new-id"}}},
+		},
+		DeletedPackages: []DiffPackage{
+			{Name: "tools/deleted", Path: "prebuilt/deleted", Version: "version:1", Instances: []DiffPackageInstance{{Name: "tools/deleted", InstanceID: "deleted-id"}}},
+		},
+		UpdatedPackages: []DiffPackage{
+			{Name: "tools/rebuilt", Path: "prebuilt/rebuilt", Version: "latest", Instances: []DiffPackageInstance{{Name: "tools/rebuilt", InstanceID: "new-id", OldInstanceID: "old-id"}}},
+			{Name: "tools/updated", Path: "prebuilt/updated", Version: "version:2", OldVersion: "version:1", Instances: []DiffPackageInstance{{Name: "tools/updated", InstanceID: "new-id", OldInstanceID: "old-id"}}},
+		},
+		NewHooks:     []DiffHook{{Name: "new", Project: p.Name, Action: "new.sh"}},
+		DeletedHooks: []DiffHook{{Name: "deleted", Project: p.Name, Action: "deleted.sh"}},
+		UpdatedHooks: []DiffHook{{Name: "updated", Project: p.Name, Action: "new.sh", OldAction: "old.sh"}},
+	}
+	if diff := cmp.Diff(want, d); diff != "" {
+		t.Fatalf("Wrong diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffFormats(t *testing.T) {
+	t.Parallel()
+
+	d := &Diff{
+		UpdatedProjects: []DiffProject{{
+			Name:         "project-0",
+			RelativePath: "path-0",
+			Revision:     "2222222222222222",
+			OldRevision:  "1111111111111111",
+			Cls: []DiffCl{
+				{Commit: "3333333333333333", Number: 12, Subject: "Fix the build", URL: "https://review/c/12", Author: "John Doe", Date: "2026-01-02T03:04:05Z"},
+				{Commit: "4444444444444444", Subject: "Add a test"},
+			},
+		}},
+		NewProjects:     []DiffProject{{Name: "project-1", RelativePath: "path-1", Revision: "5555555555555555"}},
+		UpdatedPackages: []DiffPackage{{Name: "tools/foo", Path: "prebuilt/foo", Version: "version:2", OldVersion: "version:1"}},
+		UpdatedHooks:    []DiffHook{{Name: "setup", Project: "project-0", Action: "new.sh", OldAction: "old.sh"}},
+	}
+
+	var text bytes.Buffer
+	d.writeText(&text)
+	for _, want := range []string{
+		"Updated projects:\n  project-0 (path-0): " + shortRev("1111111111111111") + " -> " + shortRev("2222222222222222") + "\n",
+		"    https://review/c/12 Fix the build (John Doe, 2026-01-02T03:04:05Z)\n",
+		"    " + shortRev("4444444444444444") + " Add a test\n",
+		"New projects:\n  project-1 (path-1)",
+		"Updated packages:\n  tools/foo (prebuilt/foo): version:1 -> version:2\n",
+		"Updated hooks:\n  setup (project-0): old.sh -> new.sh\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output does not contain %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "Deleted projects") {
+		t.Errorf("text output contains empty section:\n%s", text.String())
+	}
+
+	var markdown bytes.Buffer
+	d.writeMarkdown(&markdown)
+	for _, want := range []string{
+		"## Updated projects\n\n### project-0\n",
+		"* [12](https://review/c/12) Fix the build (John Doe, 2026-01-02T03:04:05Z)\n",
+		"* `" + shortRev("4444444444444444") + "` Add a test\n",
+		"## New projects\n\n* **project-1** `path-1`",
+		"## Updated packages\n\n* **tools/foo** `prebuilt/foo`: version:1 -> version:2\n",
+		"## Updated hooks\n\n* **setup** (project-0): `old.sh` -> `new.sh`\n",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown output does not contain %q:\n%s", want, markdown.String())
+		}
+	}
+}
+
+func TestDiffAllCls(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	p := localProjects[0]
+	remote := fake.Projects[p.Name]
+	scm := gitutil.New(fake.X, gitutil.RootDirOpt(remote))
+	oldRev, err := scm.CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeReadme(t, fake.X, remote, "second readme")
+	writeReadme(t, fake.X, remote, "third readme")
+	newRev, err := scm.CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotProject := project.Project{Name: p.Name, Path: "path-0", Remote: p.Remote, Revision: oldRev}
+	m1 := &project.Manifest{Version: project.ManifestVersion, Projects: []project.Project{snapshotProject}}
+	snapshotProject.Revision = newRev
+	m2 := &project.Manifest{Version: project.ManifestVersion, Projects: []project.Project{snapshotProject}}
+
+	// The local checkout does not have the new commits yet, they are
+	// fetched from the remote.
+	d, err := (&diffCmd{allCls: true, maxCls: 1}).getDiff(fake.X, writeSnapshot(t, m1), writeSnapshot(t, m2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.UpdatedProjects) != 1 {
+		t.Fatalf("expected 1 updated project, got %+v", d.UpdatedProjects)
+	}
+	up := d.UpdatedProjects[0]
+	if up.Error != "" {
+		t.Fatalf("unexpected error: %s", up.Error)
+	}
+	if up.HasMoreCls {
+		t.Errorf("expected every commit to be listed")
+	}
+	if len(up.Cls) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", up.Cls)
+	}
+	if up.Cls[0].Commit != newRev {
+		t.Errorf("expected newest commit %s first, got %s", newRev, up.Cls[0].Commit)
+	}
+	for _, cl := range up.Cls {
+		if cl.Author != "John Doe" || cl.Date == "" || cl.Subject != "creating README" {
+			t.Errorf("unexpected commit %+v", cl)
+		}
+	}
+}
@@ -109,7 +109,8 @@ func (c *editCmd) run(jirix *jiri.X, args []string) error {
 		}
 	}
 
-	return c.updateManifest(jirix, manifestPath, projects, imports, packages)
+	_, err = c.updateManifest(jirix, manifestPath, projects, imports, packages)
+	return err
 }
 
 func (c *editCmd) writeManifest(jirix *jiri.X, manifestPath, manifestContent string, projects map[string]string) error {
@@ -132,24 +133,9 @@ func (c *editCmd) writeManifest(jirix *jiri.X, manifestPath, manifestContent str
 		}
 	}
 
-	isLockfileDir := func(jirix *jiri.X, s string) bool {
-		switch s {
-		case "", ".", jirix.Root, string(filepath.Separator):
-			return false
-		}
-		return true
-	}
-
 	if len(projects) != 0 && (c.editMode == lockfile || c.editMode == both) {
 		// Search lockfiles and update
-		dir := manifestPath
-		for ; isLockfileDir(jirix, dir); dir = path.Dir(dir) {
-			lockfile := path.Join(path.Dir(dir), jirix.LockfileName)
-
-			if _, err := os.Stat(lockfile); err != nil {
-				jirix.Logger.Debugf("lockfile could not be accessed at %q due to error %v", lockfile, err)
-				continue
-			}
+		for _, lockfile := range lockfilesForManifest(jirix, manifestPath) {
 			if err := updateLocks(jirix, tempDir, lockfile, backup, projects); err != nil {
 				rewind()
 				return err
@@ -164,6 +150,31 @@ func (c *editCmd) writeManifest(jirix *jiri.X, manifestPath, manifestContent str
 	return nil
 }
 
+// lockfilesForManifest returns the lockfiles in the directory of manifestPath
+// and in its parent directories up to JIRI_ROOT.
+func lockfilesForManifest(jirix *jiri.X, manifestPath string) []string {
+	isLockfileDir := func(jirix *jiri.X, s string) bool {
+		switch s {
+		case "", ".", jirix.Root, string(filepath.Separator):
+			return false
+		}
+		return true
+	}
+
+	var lockfiles []string
+	dir := manifestPath
+	for ; isLockfileDir(jirix, dir); dir = path.Dir(dir) {
+		lockfile := path.Join(path.Dir(dir), jirix.LockfileName)
+
+		if _, err := os.Stat(lockfile); err != nil {
+			jirix.Logger.Debugf("lockfile could not be accessed at %q due to error %v", lockfile, err)
+			continue
+		}
+		lockfiles = append(lockfiles, lockfile)
+	}
+	return lockfiles
+}
+
 func updateLocks(jirix *jiri.X, tempDir, lockfile string, backup, projects map[string]string) error {
 	jirix.Logger.Debugf("try updating lockfile %q", lockfile)
 	bin, err := os.ReadFile(lockfile)
@@ -171,6 +182,10 @@ func updateLocks(jirix *jiri.X, tempDir, lockfile string, backup, projects map[s
 		return err
 	}
 
+	version, err := project.LockFileVersion(bin)
+	if err != nil {
+		return err
+	}
 	projectLocks, packageLocks, err := project.UnmarshalLockEntries(bin)
 	if err != nil {
 		return err
@@ -196,7 +211,7 @@ func updateLocks(jirix *jiri.X, tempDir, lockfile string, backup, projects map[s
 			return err
 		}
 		backup[lockfile] = backupName
-		ebin, err := project.MarshalLockEntries(projectLocks, packageLocks)
+		ebin, err := project.MarshalLockFile(version, projectLocks, packageLocks)
 		if err != nil {
 			return err
 		}
@@ -274,7 +289,49 @@ func updateRevisionOrVersionAttr(manifestContent, tag, newAttrValue, name, attr
 	return strings.Replace(manifestContent, s, rs, 1), nil
 }
 
-func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, imports, packages map[string]string) error {
+// remoteBranchTip returns the revision branch of remote points to, "main"
+// being used if branch is empty.
+func remoteBranchTip(scm *gitutil.Git, remote, branch string) (string, error) {
+	if branch == "" {
+		branch = "main"
+	}
+	out, err := scm.LsRemote(remote, fmt.Sprintf("refs/heads/%s", branch))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("branch %q not found in %q", branch, remote)
+	}
+	return fields[0], nil
+}
+
+// lockedRevisions returns the revisions projects are locked to by the
+// lockfiles of the manifest at manifestPath, keyed by project lock key.
+// The lockfile closest to the manifest wins.
+func lockedRevisions(jirix *jiri.X, manifestPath string) (map[string]string, error) {
+	lockedRevs := make(map[string]string)
+	for _, lockfile := range lockfilesForManifest(jirix, manifestPath) {
+		data, err := os.ReadFile(lockfile)
+		if err != nil {
+			return nil, err
+		}
+		projectLocks, _, err := project.UnmarshalLockEntries(data)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range projectLocks {
+			if _, ok := lockedRevs[k.String()]; !ok {
+				lockedRevs[k.String()] = v.Revision
+			}
+		}
+	}
+	return lockedRevs, nil
+}
+
+// updateManifest updates the revisions and versions of projects, imports
+// and packages in the manifest at manifestPath and returns the changes.
+func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, imports, packages map[string]string) (*editChanges, error) {
 	ec := &editChanges{
 		Projects: []projectChanges{},
 		Imports:  []importChanges{},
@@ -283,13 +340,19 @@ func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, i
 
 	m, err := project.ManifestFromFile(jirix, manifestPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	content, err := os.ReadFile(manifestPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	manifestContent := string(content)
+	lockedRevs := make(map[string]string)
+	if len(projects) != 0 && c.editMode != manifest {
+		if lockedRevs, err = lockedRevisions(jirix, manifestPath); err != nil {
+			return nil, err
+		}
+	}
 	editedProjects := make(map[string]string)
 	scm := gitutil.New(jirix, gitutil.RootDirOpt(filepath.Dir(manifestPath)))
 	for _, p := range m.Projects {
@@ -300,23 +363,25 @@ func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, i
 			newRevision = rev
 		}
 		if newRevision == "" {
-			branch := "main"
-			if p.RemoteBranch != "" {
-				branch = p.RemoteBranch
+			if newRevision, err = remoteBranchTip(scm, p.Remote, p.RemoteBranch); err != nil {
+				return nil, err
 			}
-			out, err := scm.LsRemote(p.Remote, fmt.Sprintf("refs/heads/%s", branch))
-			if err != nil {
-				return err
-			}
-			newRevision = strings.Fields(string(out))[0]
 		}
-		if p.Revision == newRevision {
+		// The project is skipped only if the files edited in the current
+		// edit mode already pin it to newRevision, the lockfile may be
+		// behind the manifest.
+		oldRevision := p.Revision
+		lockedRev, locked := lockedRevs[project.ProjectLockKey(p.Key()).String()]
+		if locked && c.editMode == lockfile {
+			oldRevision = lockedRev
+		}
+		if oldRevision == newRevision && (!locked || lockedRev == newRevision) {
 			continue
 		}
-		if c.editMode == manifest || c.editMode == both {
+		if (c.editMode == manifest || c.editMode == both) && p.Revision != newRevision {
 			manifestContent, err = updateRevision(manifestContent, "project", p.Revision, newRevision, p.Name)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 		editedProjects[p.Key().String()] = newRevision
@@ -324,7 +389,7 @@ func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, i
 			Name:   p.Name,
 			Remote: p.Remote,
 			Path:   p.Path,
-			OldRev: p.Revision,
+			OldRev: oldRevision,
 			NewRev: newRevision,
 		})
 	}
@@ -337,22 +402,16 @@ func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, i
 			newRevision = rev
 		}
 		if newRevision == "" {
-			branch := "main"
-			if i.RemoteBranch != "" {
-				branch = i.RemoteBranch
-			}
-			out, err := scm.LsRemote(i.Remote, fmt.Sprintf("refs/heads/%s", branch))
-			if err != nil {
-				return err
+			if newRevision, err = remoteBranchTip(scm, i.Remote, i.RemoteBranch); err != nil {
+				return nil, err
 			}
-			newRevision = strings.Fields(string(out))[0]
 		}
 		if i.Revision == newRevision {
 			continue
 		}
 		manifestContent, err = updateRevision(manifestContent, "import", i.Revision, newRevision, i.Name)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ec.Imports = append(ec.Imports, importChanges{
 			Name:   i.Name,
@@ -379,17 +438,17 @@ func (c *editCmd) updateManifest(jirix *jiri.X, manifestPath string, projects, i
 		}
 		manifestContent, err = updateVersion(manifestContent, "package", pc)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ec.Packages = append(ec.Packages, pc)
 	}
 	if c.jsonOutput != "" {
 		if err := ec.toFile(c.jsonOutput); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return c.writeManifest(jirix, manifestPath, manifestContent, editedProjects)
+	return ec, c.writeManifest(jirix, manifestPath, manifestContent, editedProjects)
 }
 
 type arrayFlag []string
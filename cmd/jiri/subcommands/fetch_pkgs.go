@@ -22,6 +22,7 @@ type fetchPkgsCmd struct {
 	skipLocalProjects     bool
 	packagesToSkip        arrayFlag
 	localManifestProjects arrayFlag
+	platforms             string
 }
 
 func (c *fetchPkgsCmd) Name() string { return "fetch-packages" }
@@ -32,6 +33,14 @@ func (c *fetchPkgsCmd) Usage() string {
 	return `Fetch cipd packages using local manifest JIRI_HEAD version if -local-manifest flag is
 false, otherwise it fetches cipd packages using current manifest checkout version.
 
+Packages are fetched for the current platform. The -platforms flag, or the
+"fetchPlatforms" setting written by "jiri init -fetch-platforms", adds
+platforms whose packages are also installed, e.g. for cross-compilation. A
+package is fetched for such a platform if it supports it, at its path
+expanded for that platform, unless that path is already used by the instance
+for an earlier platform. Lockfiles already cover every platform a package
+supports.
+
 Usage:
   jiri fetch-packages [flags]
 `
@@ -44,6 +53,7 @@ func (c *fetchPkgsCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.skipLocalProjects, "skip-local-projects", false, "Skip checking local project state.")
 	f.Var(&c.packagesToSkip, "package-to-skip", "Skip fetching this package. Repeatable.")
 	f.Var(&c.localManifestProjects, "local-manifest-project", "Import projects whose local manifests should be respected. Repeatable.")
+	f.StringVar(&c.platforms, "platforms", "", "Comma separated list of additional platforms to fetch packages for, e.g. \"linux-arm64,mac-amd64\". Overrides the fetchPlatforms setting.")
 }
 
 func (c *fetchPkgsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -62,6 +72,9 @@ func (c *fetchPkgsCmd) run(jirix *jiri.X, args []string) (err error) {
 		return jirix.UsageErrorf("Number of attempts should be >= 1")
 	}
 	jirix.Attempts = c.attempts
+	if c.platforms != "" {
+		jirix.FetchPlatforms = c.platforms
+	}
 
 	// Get pkgs.
 	var pkgs project.Packages
@@ -13,14 +13,18 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/google/subcommands"
 	"go.fuchsia.dev/jiri"
 	"go.fuchsia.dev/jiri/analytics_util"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/project"
 )
 
 const (
-	optionalAttrsNotSet = "[ATTRIBUTES_NOT_SET]"
+	optionalAttrsNotSet  = "[ATTRIBUTES_NOT_SET]"
+	fetchPlatformsNotSet = "[PLATFORMS_NOT_SET]"
 )
 
 type initCmd struct {
@@ -36,9 +40,11 @@ type initCmd struct {
 	keepGitHooks      string
 	enableLockfile    string
 	lockfileName      string
+	lockfileVersion   int
 	prebuiltJSON      string
 	enableSubmodules  string
 	optionalAttrs     string
+	fetchPlatforms    string
 	partial           bool
 	partialSkip       arrayFlag
 	offloadPackfiles  bool
@@ -74,11 +80,13 @@ func (c *initCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.keepGitHooks, "keep-git-hooks", "", "Whether to keep current git hooks in '.git/hooks' when doing 'jiri update'. Takes true/false.")
 	f.StringVar(&c.enableLockfile, "enable-lockfile", "", "Enable lockfile enforcement")
 	f.StringVar(&c.lockfileName, "lockfile-name", "", "Set up filename of lockfile")
+	f.IntVar(&c.lockfileVersion, "lockfile-version", 0, "Set up the version of the lockfile format written by \"jiri resolve\"")
 	f.StringVar(&c.prebuiltJSON, "prebuilt-json", "", "Set up filename for prebuilt json file")
 	f.StringVar(&c.enableSubmodules, "enable-submodules", "", "Enable submodules structure")
 	// Empty string is not used as default value for optionalAttrs as we
 	// use empty string to clear existing saved attributes.
 	f.StringVar(&c.optionalAttrs, "fetch-optional", optionalAttrsNotSet, "Set up attributes of optional projects and packages that should be fetched by jiri.")
+	f.StringVar(&c.fetchPlatforms, "fetch-platforms", fetchPlatformsNotSet, "Set up additional platforms, e.g. \"linux-arm64,mac-amd64\", that packages should be fetched for.")
 	f.BoolVar(&c.partial, "partial", false, "Whether to use a partial checkout.")
 	f.Var(&c.partialSkip, "skip-partial", "Skip using partial checkouts for these remotes.")
 	f.BoolVar(&c.offloadPackfiles, "offload-packfiles", true, "Whether to use a CDN for packfiles if available.")
@@ -194,6 +202,18 @@ func (c *initCmd) run(_ context.Context, args []string) error {
 		config.FetchingAttrs = c.optionalAttrs
 	}
 
+	if c.fetchPlatforms != fetchPlatformsNotSet {
+		for _, plat := range strings.Split(c.fetchPlatforms, ",") {
+			if plat = strings.TrimSpace(plat); plat == "" {
+				continue
+			}
+			if _, err := cipd.NewPlatform(plat); err != nil {
+				return fmt.Errorf("'fetch-platforms' should be a comma separated list of platforms: %v", err)
+			}
+		}
+		config.FetchPlatforms = c.fetchPlatforms
+	}
+
 	if c.partial {
 		config.Partial = c.partial
 	}
@@ -218,6 +238,13 @@ func (c *initCmd) run(_ context.Context, args []string) error {
 		config.PrebuiltJSON = c.prebuiltJSON
 	}
 
+	if c.lockfileVersion != 0 {
+		if c.lockfileVersion < 0 || c.lockfileVersion > project.LatestLockFileVersion {
+			return fmt.Errorf("'lockfile-version' should be between 1 and %d", project.LatestLockFileVersion)
+		}
+		config.LockfileVersion = c.lockfileVersion
+	}
+
 	if c.enableLockfile != "" {
 		if _, err := strconv.ParseBool(c.enableLockfile); err != nil {
 			return fmt.Errorf("'enable-lockfile' should be true or false")
@@ -0,0 +1,141 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri/cmdline"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type lockfileCmd struct {
+	cmdBase
+
+	output string
+}
+
+func (c *lockfileCmd) Name() string     { return "lockfile" }
+func (c *lockfileCmd) Synopsis() string { return "Manipulate jiri lockfiles" }
+func (c *lockfileCmd) Usage() string {
+	return `Manipulate jiri lockfiles.
+
+Usage:
+  jiri lockfile merge [flags] <base> <ours> <theirs>
+
+"jiri lockfile merge" does a three-way merge of the lockfiles <ours> and
+<theirs>, whose common ancestor is <base>. Locks changed on one side only are
+taken from that side. Locks that both sides changed to different revisions or
+instances are reported as conflicts, in which case the command fails and the
+lock of <ours> is kept. The result is written to <ours>, or to -output, using
+the lockfile format version of <ours>.
+
+It can be used as a git merge driver by adding the following to the git
+config:
+
+  [merge "jiri-lockfile"]
+    name = jiri lockfile merge driver
+    driver = jiri lockfile merge %O %A %B
+
+and the following to .gitattributes:
+
+  jiri.lock merge=jiri-lockfile
+`
+}
+
+func (c *lockfileCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.output, "output", "", "Path to write the merged lockfile to. Defaults to <ours>.")
+}
+
+func (c *lockfileCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	// Merge drivers may run outside of a jiri root, so no jiri.X is
+	// created.
+	stderr := io.Writer(os.Stderr)
+	if env := cmdline.EnvFromContext(ctx); env != nil && env.Stderr != nil {
+		stderr = env.Stderr
+	}
+	return errToExitStatus(ctx, c.run(stderr, f.Args()))
+}
+
+func (c *lockfileCmd) run(stderr io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing action, expected \"merge\"")
+	}
+	switch args[0] {
+	case "merge":
+		return c.runMerge(stderr, args[1:])
+	}
+	return fmt.Errorf("unknown action %q, expected \"merge\"", args[0])
+}
+
+// readLockFile returns the locks in the lockfile at path and the version of
+// its format. Empty files contain no locks.
+func readLockFile(path string) (project.ProjectLocks, project.PackageLocks, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return project.ProjectLocks{}, project.PackageLocks{}, 1, nil
+	}
+	version, err := project.LockFileVersion(data)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse lockfile %q: %v", path, err)
+	}
+	projectLocks, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse lockfile %q: %v", path, err)
+	}
+	return projectLocks, pkgLocks, version, nil
+}
+
+func (c *lockfileCmd) runMerge(stderr io.Writer, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected <base> <ours> <theirs>, got %d arguments", len(args))
+	}
+	baseProjects, basePkgs, _, err := readLockFile(args[0])
+	if err != nil {
+		return err
+	}
+	ourProjects, ourPkgs, version, err := readLockFile(args[1])
+	if err != nil {
+		return err
+	}
+	theirProjects, theirPkgs, _, err := readLockFile(args[2])
+	if err != nil {
+		return err
+	}
+
+	projectLocks, projectConflicts := project.MergeProjectLocks(baseProjects, ourProjects, theirProjects)
+	pkgLocks, pkgConflicts := project.MergePackageLocks(basePkgs, ourPkgs, theirPkgs)
+	data, err := project.MarshalLockFile(version, projectLocks, pkgLocks)
+	if err != nil {
+		return err
+	}
+	output := c.output
+	if output == "" {
+		output = args[1]
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return err
+	}
+
+	for _, v := range projectConflicts {
+		fmt.Fprintf(stderr, "CONFLICT: %s\n", v)
+	}
+	for _, v := range pkgConflicts {
+		fmt.Fprintf(stderr, "CONFLICT: %s\n", v)
+	}
+	if n := len(projectConflicts) + len(pkgConflicts); n != 0 {
+		return fmt.Errorf("%d conflicting locks, kept ours in %q", n, output)
+	}
+	return nil
+}
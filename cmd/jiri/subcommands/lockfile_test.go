@@ -0,0 +1,115 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/project"
+)
+
+func TestLockfileMerge(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeLocks := func(name string, projectLocks []project.ProjectLock, pkgLocks []project.PackageLock) string {
+		p := project.ProjectLocks{}
+		for _, v := range projectLocks {
+			p[v.Key()] = v
+		}
+		k := project.PackageLocks{}
+		for _, v := range pkgLocks {
+			k[v.Key()] = v
+		}
+		data, err := project.MarshalLockEntries(p, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	foo := project.ProjectLock{Remote: "https://example.com/foo", Name: "foo", Revision: "foo-1"}
+	bar := project.ProjectLock{Remote: "https://example.com/bar", Name: "bar", Revision: "bar-1"}
+	tool := project.PackageLock{PackageName: "fuchsia/tool", VersionTag: "version:1", InstanceID: "tool-1"}
+	fooOurs, barTheirs := foo, bar
+	fooOurs.Revision = "foo-2"
+	barTheirs.Revision = "bar-2"
+	newTool := project.PackageLock{PackageName: "fuchsia/tool", VersionTag: "version:2", InstanceID: "tool-2"}
+
+	// Each side rolls a different project, theirs also bumps a package.
+	base := writeLocks("base", []project.ProjectLock{foo, bar}, []project.PackageLock{tool})
+	ours := writeLocks("ours", []project.ProjectLock{fooOurs, bar}, []project.PackageLock{tool})
+	theirs := writeLocks("theirs", []project.ProjectLock{foo, barTheirs}, []project.PackageLock{newTool})
+	var stderr bytes.Buffer
+	cmd := lockfileCmd{}
+	if err := cmd.run(&stderr, []string{"merge", base, ours, theirs}); err != nil {
+		t.Fatalf("merge failed: %v\n%s", err, stderr.String())
+	}
+	data, err := os.ReadFile(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectLocks, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (project.ProjectLocks{foo.Key(): fooOurs, bar.Key(): barTheirs}); !reflect.DeepEqual(projectLocks, want) {
+		t.Errorf("unexpected project locks %v, want %v", projectLocks, want)
+	}
+	if want := (project.PackageLocks{newTool.Key(): newTool}); !reflect.DeepEqual(pkgLocks, want) {
+		t.Errorf("unexpected package locks %v, want %v", pkgLocks, want)
+	}
+
+	// Both sides roll the same project to different revisions.
+	fooTheirs := foo
+	fooTheirs.Revision = "foo-3"
+	ours = writeLocks("ours", []project.ProjectLock{fooOurs, bar}, []project.PackageLock{tool})
+	theirs = writeLocks("theirs", []project.ProjectLock{fooTheirs, barTheirs}, []project.PackageLock{tool})
+	stderr.Reset()
+	if err := cmd.run(&stderr, []string{"merge", base, ours, theirs}); err == nil {
+		t.Fatalf("expected merge to fail")
+	}
+	if got := stderr.String(); !strings.Contains(got, "foo-2") || !strings.Contains(got, "foo-3") || strings.Contains(got, "bar") {
+		t.Errorf("unexpected conflicts reported:\n%s", got)
+	}
+	if data, err = os.ReadFile(ours); err != nil {
+		t.Fatal(err)
+	}
+	if projectLocks, _, err = project.UnmarshalLockEntries(data); err != nil {
+		t.Fatal(err)
+	}
+	if projectLocks[foo.Key()] != fooOurs || projectLocks[bar.Key()] != barTheirs {
+		t.Errorf("unexpected project locks after conflict %v", projectLocks)
+	}
+
+	// Both sides bump the same package to different versions.
+	otherTool := project.PackageLock{PackageName: "fuchsia/tool", VersionTag: "version:3", InstanceID: "tool-3"}
+	ours = writeLocks("ours", []project.ProjectLock{foo, bar}, []project.PackageLock{newTool})
+	theirs = writeLocks("theirs", []project.ProjectLock{foo, bar}, []project.PackageLock{otherTool})
+	stderr.Reset()
+	if err := cmd.run(&stderr, []string{"merge", base, ours, theirs}); err == nil {
+		t.Fatalf("expected merge to fail")
+	}
+	if got := stderr.String(); !strings.Contains(got, "tool-2") || !strings.Contains(got, "tool-3") {
+		t.Errorf("unexpected conflicts reported:\n%s", got)
+	}
+	if data, err = os.ReadFile(ours); err != nil {
+		t.Fatal(err)
+	}
+	if _, pkgLocks, err = project.UnmarshalLockEntries(data); err != nil {
+		t.Fatal(err)
+	}
+	if want := (project.PackageLocks{newTool.Key(): newTool}); !reflect.DeepEqual(pkgLocks, want) {
+		t.Errorf("unexpected package locks after conflict %v, want %v", pkgLocks, want)
+	}
+}
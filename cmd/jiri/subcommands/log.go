@@ -0,0 +1,257 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+// defaultLogSince is the default value of "jiri log -since".
+const defaultLogSince = "1 day ago"
+
+type logCmd struct {
+	cmdBase
+
+	since           string
+	author          string
+	projects        string
+	sinceLastUpdate bool
+	maxCount        int
+	jsonOutput      bool
+}
+
+func (c *logCmd) Name() string { return "log" }
+func (c *logCmd) Synopsis() string {
+	return "Show the commits of all the projects"
+}
+func (c *logCmd) Usage() string {
+	return `Shows the commits of all the projects, most recent first, each prefixed with
+the name of its project.
+
+Usage:
+  jiri log [flags]
+
+By default, the commits reachable from the current revision of every project
+and committed since -since are shown. With -since-last-update, the commits
+brought in by the last "jiri update" are shown instead, by comparing the
+latest two snapshots of the update history.
+`
+}
+
+func (c *logCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.since, "since", "", fmt.Sprintf("Show the commits more recent than this date, in any format accepted by git. Defaults to %q without -since-last-update.", defaultLogSince))
+	f.StringVar(&c.author, "author", "", "Show the commits whose author matches this pattern.")
+	f.StringVar(&c.projects, "projects", "", "A regular expression matching the names of the projects to show the commits of.")
+	f.BoolVar(&c.sinceLastUpdate, "since-last-update", false, "Show the commits brought in by the last update.")
+	f.IntVar(&c.maxCount, "n", 0, "Show at most this many commits. 0 shows all of them.")
+	f.BoolVar(&c.jsonOutput, "json", false, "Print the commits as JSON.")
+}
+
+func (c *logCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+// LogEntry is a commit printed by "jiri log".
+type LogEntry struct {
+	Project string    `json:"project"`
+	Path    string    `json:"path"`
+	Commit  string    `json:"commit"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// logRange is the range of commits of a project to show.
+type logRange struct {
+	project project.Project
+	revs    []string
+}
+
+func (c *logCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) != 0 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+	if c.maxCount < 0 {
+		return jirix.UsageErrorf("-n must not be negative")
+	}
+	var re *regexp.Regexp
+	if c.projects != "" {
+		var err error
+		if re, err = regexp.Compile(c.projects); err != nil {
+			return fmt.Errorf("failed to compile regexp %v: %v", c.projects, err)
+		}
+	}
+	var ranges []logRange
+	var err error
+	if c.sinceLastUpdate {
+		ranges, err = lastUpdateRanges(jirix)
+	} else {
+		if c.since == "" {
+			c.since = defaultLogSince
+		}
+		ranges, err = currentRanges(jirix)
+	}
+	if err != nil {
+		return err
+	}
+	if re != nil {
+		var matching []logRange
+		for _, r := range ranges {
+			if re.MatchString(r.project.Name) {
+				matching = append(matching, r)
+			}
+		}
+		ranges = matching
+	}
+
+	entries, err := c.logEntries(jirix, ranges)
+	if err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		e := json.NewEncoder(jirix.Stdout())
+		e.SetIndent("", " ")
+		return e.Encode(entries)
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(jirix.Stdout(), "%s %s %s %s (%s)\n", jirix.Color.Yellow("%s", shortRev(entry.Commit)), entry.Date.Local().Format("2006-01-02 15:04"), jirix.Color.Green("%s:", entry.Project), entry.Subject, entry.Author)
+	}
+	return nil
+}
+
+// currentRanges returns the ranges of the commits reachable from the
+// current revision of the local projects.
+func currentRanges(jirix *jiri.X) ([]logRange, error) {
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return nil, err
+	}
+	var ranges []logRange
+	for _, p := range localProjects {
+		ranges = append(ranges, logRange{project: p, revs: []string{"HEAD"}})
+	}
+	return ranges, nil
+}
+
+// lastUpdateRanges returns the ranges of the commits of the projects
+// updated between the latest two snapshots of the update history.
+func lastUpdateRanges(jirix *jiri.X) ([]logRange, error) {
+	latest, _, _, err := project.LoadSnapshotFile(jirix, jirix.UpdateHistoryLatestLink())
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the latest update snapshot: %v", err)
+	}
+	previous, _, _, err := project.LoadSnapshotFile(jirix, jirix.UpdateHistorySecondLatestLink())
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the second latest update snapshot: %v", err)
+	}
+	var ranges []logRange
+	for key, p := range latest {
+		old, ok := previous[key]
+		if !ok {
+			jirix.Logger.Debugf("Project %s was added by the last update, its commits are not shown", p.Name)
+			continue
+		}
+		if old.Revision == p.Revision {
+			continue
+		}
+		ranges = append(ranges, logRange{project: p, revs: []string{old.Revision + ".." + p.Revision}})
+	}
+	return ranges, nil
+}
+
+// logEntries runs "git log" for every range in parallel and returns the
+// commits sorted by date, most recent first.
+func (c *logCmd) logEntries(jirix *jiri.X, ranges []logRange) ([]LogEntry, error) {
+	args := []string{"--no-merges"}
+	if c.since != "" {
+		args = append(args, "--since="+c.since)
+	}
+	if c.author != "" {
+		args = append(args, "--author="+c.author)
+	}
+
+	entries := []LogEntry{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(ranges))
+	limit := make(chan struct{}, jirix.Jobs)
+	for i, r := range ranges {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, r logRange) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			relativePath, err := filepath.Rel(jirix.Root, r.project.Path)
+			if err != nil {
+				relativePath = r.project.Path
+			}
+			scm := gitutil.New(jirix, gitutil.RootDirOpt(r.project.Path))
+			commits, err := scm.LogCommits("%H%n%an%n%ae%n%cI%n%s", append(append([]string{}, args...), r.revs...)...)
+			if err != nil {
+				errs[i] = fmt.Errorf("project %s(%s): %v", r.project.Name, relativePath, err)
+				return
+			}
+			var projectEntries []LogEntry
+			for _, commit := range commits {
+				if len(commit) < 4 {
+					continue
+				}
+				entry := LogEntry{
+					Project: r.project.Name,
+					Path:    relativePath,
+					Commit:  commit[0],
+					Author:  commit[1],
+					Email:   commit[2],
+				}
+				if len(commit) > 4 {
+					entry.Subject = commit[4]
+				}
+				if entry.Date, err = time.Parse(time.RFC3339, commit[3]); err != nil {
+					errs[i] = fmt.Errorf("project %s(%s): invalid date of commit %s: %v", r.project.Name, relativePath, commit[0], err)
+					return
+				}
+				projectEntries = append(projectEntries, entry)
+			}
+			mu.Lock()
+			entries = append(entries, projectEntries...)
+			mu.Unlock()
+		}(i, r)
+	}
+	wg.Wait()
+	failures := 0
+	for _, err := range errs {
+		if err != nil {
+			jirix.Logger.Errorf("%s\n", err)
+			failures++
+		}
+	}
+	if failures != 0 {
+		return nil, fmt.Errorf("failed to get the commits of %d project(s)", failures)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.After(entries[j].Date)
+		}
+		return entries[i].Project < entries[j].Project
+	})
+	if c.maxCount != 0 && len(entries) > c.maxCount {
+		entries = entries[:c.maxCount]
+	}
+	return entries, nil
+}
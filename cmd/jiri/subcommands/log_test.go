@@ -0,0 +1,84 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/tool"
+)
+
+func TestLog(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	remote0, remote1 := fake.Projects[localProjects[0].Name], fake.Projects[localProjects[1].Name]
+	a := commitFileAt(t, fake, remote0, "a", "2000-01-01T00:00:01Z")
+	b := commitFileAt(t, fake, remote1, "b", "2000-01-01T00:00:03Z")
+	c := commitFileAt(t, fake, remote0, "c", "2000-01-01T00:00:02Z")
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	runLog := func(cmd *logCmd) []LogEntry {
+		t.Helper()
+		stdout.Reset()
+		cmd.jsonOutput = true
+		if err := cmd.run(fake.X, nil); err != nil {
+			t.Fatal(err)
+		}
+		var entries []LogEntry
+		if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	checkCommits := func(entries []LogEntry, want ...string) {
+		t.Helper()
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Commit)
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("got commits %v, want %v", got, want)
+		}
+	}
+
+	// The commits of the last update are merged by date.
+	entries := runLog(&logCmd{sinceLastUpdate: true})
+	checkCommits(entries, b, c, a)
+	if entries[0].Project != localProjects[1].Name || entries[0].Path != "path-1" || entries[0].Author != "John Doe" || entries[0].Subject != "add b" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+	checkCommits(runLog(&logCmd{sinceLastUpdate: true, projects: "project-0$"}), c, a)
+	checkCommits(runLog(&logCmd{sinceLastUpdate: true, author: "Nobody"}))
+
+	// By default the commits of the current revisions are shown.
+	entries = runLog(&logCmd{since: "2000-01-01T00:00:02Z", projects: "project-[01]$"})
+	if len(entries) != 4 {
+		t.Fatalf("expected the 2 README commits, b and c, got %+v", entries)
+	}
+	checkCommits(entries[2:], b, c)
+	for _, entry := range entries {
+		if entry.Project == localProjects[1].Name {
+			checkCommits(runLog(&logCmd{since: "2000-01-01T00:00:02Z", projects: "project-1$", maxCount: 1}), entry.Commit)
+			break
+		}
+	}
+
+	stdout.Reset()
+	if err := (&logCmd{sinceLastUpdate: true}).run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], shortRev(b)+" ") || !strings.Contains(lines[0], localProjects[1].Name+": add b (John Doe)") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+}
@@ -30,6 +30,18 @@ type manifestCmd struct {
 	// The invoker of Jiri is expected to form this template
 	// themselves.
 	Template string
+
+	// lint is a flag specifying whether the manifests are checked instead
+	// of read.
+	lint bool
+
+	// auditPins is a flag specifying whether "jiri manifest -lint" checks that
+	// pinned revisions are reachable.
+	auditPins bool
+
+	// allowedRefs are the ref patterns pinned revisions may be reachable from
+	// instead of the remote branch of their project.
+	allowedRefs arrayFlag
 }
 
 func (c *manifestCmd) Name() string { return "manifest" }
@@ -54,14 +66,24 @@ manifest -element=$PACKAGE_NAME -template="{{.Version}}"
 
 Usage:
   jiri manifest [flags] <manifest>
+  jiri manifest -lint [flags] <manifest ...>
 
 <manifest> is the manifest file.
+
+"jiri manifest -lint" checks that the given manifests, or .jiri_manifest if
+none is given, are valid. With -audit-pins, it also reports the revisions
+projects are pinned to by the manifests or the lockfiles next to them which
+are not reachable from the remote branch of their project or from a ref
+matching one of the -allow-ref patterns, as "jiri resolve -audit-pins" does.
 `
 }
 
 func (c *manifestCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.ElementName, "element", "", "Name of the <project>, <import> or <package>.")
 	f.StringVar(&c.Template, "template", "", "The template for the fields to display.")
+	f.BoolVar(&c.lint, "lint", false, "Check that the manifests are valid instead of reading them.")
+	f.BoolVar(&c.auditPins, "audit-pins", false, "Report pinned revisions which are not reachable when linting.")
+	f.Var(&c.allowedRefs, "allow-ref", "Ref pattern pinned revisions may be reachable from instead of the remote branch of their project. Repeatable.")
 }
 
 func (c *manifestCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -69,6 +91,9 @@ func (c *manifestCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) su
 }
 
 func (c *manifestCmd) run(jirix *jiri.X, args []string) error {
+	if c.lint {
+		return c.runLint(jirix, args)
+	}
 	if len(args) != 1 {
 		return jirix.UsageErrorf("Wrong number of args")
 	}
@@ -122,3 +147,22 @@ func (c *manifestCmd) readManifest(jirix *jiri.X, manifestPath string, tmpl *tem
 	// Found nothing.
 	return fmt.Errorf("found no project/import/package named %s", c.ElementName)
 }
+
+func (c *manifestCmd) runLint(jirix *jiri.X, manifestFiles []string) error {
+	if len(manifestFiles) == 0 {
+		manifestFiles = []string{jirix.JiriManifestFile()}
+	}
+	for _, manifestFile := range manifestFiles {
+		if _, err := project.ManifestFromFile(jirix, manifestFile); err != nil {
+			return fmt.Errorf("invalid manifest %q: %v", manifestFile, err)
+		}
+	}
+	if !c.auditPins {
+		return nil
+	}
+	orphans, err := project.AuditPins(jirix, manifestFiles, nil, "", c.allowedRefs)
+	if err != nil {
+		return err
+	}
+	return reportOrphanedPins(jirix, orphans)
+}
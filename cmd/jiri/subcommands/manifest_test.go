@@ -6,6 +6,7 @@ package subcommands
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -216,4 +217,17 @@ func TestManifest(t *testing.T) {
 			t.Errorf("Unexpected diff (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("should lint manifests with -lint", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := runCommand(t, manifestCmd{lint: true}, []string{testManifestFile.Name()}); err != nil {
+			t.Errorf("expected the manifest to be valid, got %v", err)
+		}
+		invalid := filepath.Join(t.TempDir(), "invalid")
+		if err := os.WriteFile(invalid, []byte("<manifest>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, manifestCmd{lint: true}, testManifestFile.Name(), invalid)
+	})
 }
@@ -25,6 +25,13 @@ type packageCmd struct {
 
 	jsonOutput string
 	regexp     bool
+	policy     string
+	outdated   bool
+	bump       bool
+	verify     bool
+
+	repair           bool
+	fetchPkgsTimeout uint
 }
 
 func (c *packageCmd) Name() string     { return "package" }
@@ -39,29 +46,75 @@ Usage:
   jiri package [flags] <package ...>
 
 <package ...> is a list of packages to give info about.
+
+With -outdated, jiri reports cipd packages whose pinned instances differ
+from the instances selected by -policy. By default, the version tags in the
+manifests are resolved again. With -bump, jiri rewrites the versions of the
+given packages in their manifests to the ones selected by -policy, which
+defaults to "ref=latest", and resolves their entries in the lockfiles next
+to the manifests again.
+
+With -verify, jiri compares the installed packages with the instances
+pinned by the lockfiles and reports packages which are not installed, whose
+installed instance differs or whose files are missing or modified. Packages
+fetched through cipd are checked using the cipd site metadata in the root and
+other packages using the stamp file jiri writes next to them. With -repair,
+the broken packages are fetched again.
+
+The -policy flag accepts:
+  ref=<ref>  use the tag of the instance <ref> points to which has the same
+             key as the current version, e.g. "git_revision"
+  tag=<key>  use the newest "<key>:..." tag of the package
 `
 }
 
 func (c *packageCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.jsonOutput, "json-output", "", "Path to write operation results to.")
 	f.BoolVar(&c.regexp, "regexp", false, "Use argument as regular expression.")
+	f.BoolVar(&c.outdated, "outdated", false, "Report the cipd packages whose pinned instances differ from the ones selected by -policy.")
+	f.BoolVar(&c.bump, "bump", false, "Bump the versions of the given cipd packages to the ones selected by -policy.")
+	f.BoolVar(&c.verify, "verify", false, "Verify that the installed packages match the lockfiles.")
+	f.StringVar(&c.policy, "policy", "", "Policy used by -outdated and -bump to select package versions.")
+	f.BoolVar(&c.repair, "repair", false, "Re-install the packages which are missing or modified, with -verify.")
+	f.UintVar(&c.fetchPkgsTimeout, "fetch-packages-timeout", project.DefaultPackageTimeout, "Timeout in minutes for fetching prebuilt packages, with -verify -repair.")
 }
 
 func (c *packageCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
 	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
 }
 
-// runPackageInfo provides structured info on packages.
 func (c *packageCmd) run(jirix *jiri.X, args []string) error {
-	var err error
+	modes := 0
+	for _, mode := range []bool{c.outdated, c.bump, c.verify} {
+		if mode {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return jirix.UsageErrorf("only one of -outdated, -bump and -verify can be used")
+	}
+	switch {
+	case c.outdated:
+		return c.runOutdated(jirix, args)
+	case c.bump:
+		return c.runBump(jirix, args)
+	case c.verify:
+		return c.runVerify(jirix, args)
+	}
+	return c.runPackageInfo(jirix, args)
+}
 
+// loadPackages returns the packages in the manifest whose names match args
+// together with their sorted keys. All packages are returned if args is
+// empty.
+func (c *packageCmd) loadPackages(jirix *jiri.X, args []string) (project.Packages, project.PackageKeys, error) {
 	regexps := make([]*regexp.Regexp, 0)
 	for _, arg := range args {
 		if !c.regexp {
 			arg = "^" + regexp.QuoteMeta(arg) + "$"
 		}
 		if re, err := regexp.Compile(arg); err != nil {
-			return fmt.Errorf("failed to compile regexp %v: %v", arg, err)
+			return nil, nil, fmt.Errorf("failed to compile regexp %v: %v", arg, err)
 		} else {
 			regexps = append(regexps, re)
 		}
@@ -69,15 +122,15 @@ func (c *packageCmd) run(jirix *jiri.X, args []string) error {
 
 	projects, err := project.LocalProjects(jirix, project.FastScan)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	localManifestProjects, err := getDefaultLocalManifestProjects(jirix)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	_, _, pkgs, err := project.LoadManifestFile(jirix, jirix.JiriManifestFile(), projects, localManifestProjects)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	var keys project.PackageKeys
 	for k, v := range pkgs {
@@ -94,6 +147,15 @@ func (c *packageCmd) run(jirix *jiri.X, args []string) error {
 	}
 
 	sort.Sort(keys)
+	return pkgs, keys, nil
+}
+
+// runPackageInfo provides structured info on packages.
+func (c *packageCmd) runPackageInfo(jirix *jiri.X, args []string) error {
+	pkgs, keys, err := c.loadPackages(jirix, args)
+	if err != nil {
+		return err
+	}
 
 	info := make([]packageInfoOutput, 0)
 	for _, key := range keys {
@@ -0,0 +1,93 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"fmt"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/project"
+)
+
+func (c *packageCmd) runVerify(jirix *jiri.X, args []string) error {
+	pkgs, keys, err := c.loadPackages(jirix, args)
+	if err != nil {
+		return err
+	}
+	if err := project.FilterOptionalProjectsPackages(jirix, jirix.FetchingAttrs, nil, pkgs); err != nil {
+		return err
+	}
+	selected := make(project.Packages)
+	for _, key := range keys {
+		if pkg, ok := pkgs[key]; ok {
+			selected[key] = pkg
+		}
+	}
+
+	statuses, err := project.VerifyPackages(jirix, selected)
+	if err != nil {
+		return err
+	}
+	broken := 0
+	for _, s := range statuses {
+		if !s.OK() {
+			broken++
+		}
+	}
+	if broken != 0 && c.repair {
+		jirix.Logger.Infof("Repairing %d packages\n", broken)
+		if err := project.RepairPackages(jirix, pkgs, statuses, c.fetchPkgsTimeout); err != nil {
+			return err
+		}
+		if statuses, err = project.VerifyPackages(jirix, selected); err != nil {
+			return err
+		}
+		broken = 0
+		for _, s := range statuses {
+			if !s.OK() {
+				broken++
+			}
+		}
+	}
+
+	for _, s := range statuses {
+		printPackageStatus(jirix, s)
+	}
+	if c.jsonOutput != "" {
+		if err := writeJSONOutput(c.jsonOutput, statuses); err != nil {
+			return err
+		}
+	}
+	if broken != 0 {
+		if c.repair {
+			return fmt.Errorf("%d packages could not be repaired", broken)
+		}
+		return fmt.Errorf("%d packages are missing or modified, run \"jiri package -verify -repair\" to re-install them", broken)
+	}
+	return nil
+}
+
+func printPackageStatus(jirix *jiri.X, s project.PackageStatus) {
+	fmt.Fprintf(jirix.Stdout(), "* package %s\n", s.Name)
+	fmt.Fprintf(jirix.Stdout(), "  Path:   %s\n", s.Path)
+	fmt.Fprintf(jirix.Stdout(), "  Source: %s\n", s.Source)
+	fmt.Fprintf(jirix.Stdout(), "  State:  %s\n", s.State)
+	if s.OK() {
+		return
+	}
+	if s.LockedID != "" {
+		fmt.Fprintf(jirix.Stdout(), "  Locked instance:    %s\n", s.LockedID)
+	}
+	if s.InstalledID != "" {
+		fmt.Fprintf(jirix.Stdout(), "  Installed instance: %s\n", s.InstalledID)
+	}
+	if len(s.MissingFiles) != 0 {
+		fmt.Fprintf(jirix.Stdout(), "  Missing files:  %s\n", strings.Join(s.MissingFiles, ", "))
+	}
+	if len(s.ModifiedFiles) != 0 {
+		fmt.Fprintf(jirix.Stdout(), "  Modified files: %s\n", strings.Join(s.ModifiedFiles, ", "))
+	}
+}
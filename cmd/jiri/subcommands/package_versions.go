@@ -0,0 +1,468 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/project"
+)
+
+// maxPolicyInstances is the number of recent instances searched by the
+// "tag=<key>" policy.
+const maxPolicyInstances = 20
+
+// packageVersionPolicy selects the version a package should be pinned to.
+type packageVersionPolicy struct {
+	// ref selects the tag of the instance that ref points to.
+	ref string
+	// tagKey selects the newest tag with this key.
+	tagKey string
+}
+
+func parsePackageVersionPolicy(s string) (packageVersionPolicy, error) {
+	var policy packageVersionPolicy
+	if s == "" {
+		return policy, nil
+	}
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		return policy, fmt.Errorf("invalid policy %q, expecting \"ref=<ref>\" or \"tag=<key>\"", s)
+	}
+	switch kv[0] {
+	case "ref":
+		policy.ref = kv[1]
+	case "tag":
+		policy.tagKey = kv[1]
+	default:
+		return policy, fmt.Errorf("invalid policy %q, expecting \"ref=<ref>\" or \"tag=<key>\"", s)
+	}
+	return policy, nil
+}
+
+// tagKey returns the key of version if it is a cipd tag.
+func tagKey(version string) string {
+	if i := strings.Index(version, ":"); i > 0 {
+		return version[:i]
+	}
+	return ""
+}
+
+// newestTag returns the most recently registered tag with key in tags.
+func newestTag(tags []cipd.InstanceTag, key string) string {
+	newest := cipd.InstanceTag{RegisteredTs: -1}
+	for _, t := range tags {
+		if tagKey(t.Tag) == key && t.RegisteredTs > newest.RegisteredTs {
+			newest = t
+		}
+	}
+	return newest.Tag
+}
+
+// version returns the version of package pkg selected by the policy. The
+// instance of pkg called name is used to look up tags.
+func (p packageVersionPolicy) version(jirix *jiri.X, pkg project.Package, name string) (string, error) {
+	switch {
+	case p.ref != "":
+		key := tagKey(pkg.Version)
+		if key == "" {
+			return "", fmt.Errorf("version %q of package %q is not a tag, use a \"tag=<key>\" policy instead", pkg.Version, pkg.Name)
+		}
+		desc, err := cipd.Describe(jirix, name, p.ref)
+		if err != nil {
+			return "", err
+		}
+		if tag := newestTag(desc.Tags, key); tag != "" {
+			return tag, nil
+		}
+		return "", fmt.Errorf("instance %q of package %q has no %q tag", p.ref, name, key)
+	case p.tagKey != "":
+		ids, err := cipd.ListInstances(jirix, name, maxPolicyInstances)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range ids {
+			desc, err := cipd.Describe(jirix, name, id)
+			if err != nil {
+				return "", err
+			}
+			if tag := newestTag(desc.Tags, p.tagKey); tag != "" {
+				return tag, nil
+			}
+		}
+		return "", fmt.Errorf("none of the %d newest instances of package %q has a %q tag", maxPolicyInstances, name, p.tagKey)
+	}
+	return pkg.Version, nil
+}
+
+// packageInstanceStatus defines JSON format of a single platform in
+// 'package -outdated' output.
+type packageInstanceStatus struct {
+	Name      string   `json:"name"`
+	Platforms []string `json:"platforms,omitempty"`
+	Pinned    string   `json:"pinned_instance_id"`
+	Selected  string   `json:"selected_instance_id"`
+}
+
+// packageVersionStatus defines JSON format for 'package -outdated' output.
+type packageVersionStatus struct {
+	Name       string                  `json:"name"`
+	Path       string                  `json:"path"`
+	Manifest   string                  `json:"manifest,omitempty"`
+	Version    string                  `json:"version"`
+	NewVersion string                  `json:"new_version"`
+	Instances  []packageInstanceStatus `json:"instances"`
+	Outdated   bool                    `json:"outdated"`
+	Error      string                  `json:"error,omitempty"`
+}
+
+// checkPackageVersion computes the version of pkg selected by policy and
+// compares its instances with the pinned ones.
+func checkPackageVersion(jirix *jiri.X, pkg project.Package, policy packageVersionPolicy) packageVersionStatus {
+	status := packageVersionStatus{
+		Name:      pkg.Name,
+		Path:      pkg.Path,
+		Manifest:  pkg.ManifestPath,
+		Version:   pkg.Version,
+		Instances: []packageInstanceStatus{},
+	}
+	fail := func(err error) packageVersionStatus {
+		status.Error = err.Error()
+		return status
+	}
+	plats, err := pkg.GetPlatforms()
+	if err != nil {
+		return fail(err)
+	}
+	names, err := cipd.Expand(pkg.Name, plats)
+	if err != nil {
+		return fail(err)
+	}
+	if len(names) == 0 {
+		return fail(fmt.Errorf("package %q does not support any of platforms %v", pkg.Name, plats))
+	}
+	// Prefer the instance for the current platform to look up tags.
+	name := names[0]
+	if hostNames, err := cipd.Expand(pkg.Name, []cipd.Platform{cipd.CipdPlatform}); err == nil && len(hostNames) != 0 {
+		name = hostNames[0]
+	}
+	if status.NewVersion, err = policy.version(jirix, pkg, name); err != nil {
+		return fail(err)
+	}
+	status.Outdated = status.NewVersion != pkg.Version
+
+	pinned := make(map[string]string)
+	for _, ins := range pkg.Instances {
+		pinned[ins.Name] = ins.ID
+	}
+	// The platforms of the instances key their locks.
+	platforms := make(map[string][]string)
+	if cipd.MustExpand(pkg.Name) {
+		for _, plat := range plats {
+			expanded, err := cipd.Expand(pkg.Name, []cipd.Platform{plat})
+			if err != nil {
+				return fail(err)
+			}
+			for _, name := range expanded {
+				platforms[name] = append(platforms[name], plat.String())
+			}
+		}
+	}
+	for _, name := range names {
+		selected, err := cipd.Describe(jirix, name, status.NewVersion)
+		if err != nil {
+			return fail(err)
+		}
+		ins := packageInstanceStatus{
+			Name:      name,
+			Platforms: platforms[name],
+			Pinned:    pinned[name],
+			Selected:  selected.Pin.InstanceID,
+		}
+		if ins.Pinned == "" {
+			// Without a lockfile, the pinned instance is the one the
+			// version in the manifest points to.
+			current, err := cipd.Describe(jirix, name, pkg.Version)
+			if err != nil {
+				return fail(err)
+			}
+			ins.Pinned = current.Pin.InstanceID
+		}
+		if ins.Pinned != ins.Selected {
+			status.Outdated = true
+		}
+		status.Instances = append(status.Instances, ins)
+	}
+	return status
+}
+
+// cipdPackages returns the cipd packages among keys, in order.
+func cipdPackages(jirix *jiri.X, pkgs project.Packages, keys project.PackageKeys) []project.Package {
+	var ret []project.Package
+	for _, key := range keys {
+		pkg := pkgs[key]
+		if pkg.GetSource() != project.PackageSourceCIPD {
+			jirix.Logger.Debugf("Skipping package %q from source %q", pkg.Name, pkg.GetSource())
+			continue
+		}
+		ret = append(ret, pkg)
+	}
+	return ret
+}
+
+func (c *packageCmd) runOutdated(jirix *jiri.X, args []string) error {
+	policy, err := parsePackageVersionPolicy(c.policy)
+	if err != nil {
+		return jirix.UsageErrorf("%v", err)
+	}
+	pkgs, keys, err := c.loadPackages(jirix, args)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]packageVersionStatus, 0)
+	for _, pkg := range cipdPackages(jirix, pkgs, keys) {
+		status := checkPackageVersion(jirix, pkg, policy)
+		switch {
+		case status.Error != "":
+			jirix.Logger.Errorf("Failed to check package %q: %s", pkg.Name, status.Error)
+			jirix.IncrementFailures()
+		case status.Outdated:
+			fmt.Fprintf(jirix.Stdout(), "* package %s\n", status.Name)
+			fmt.Fprintf(jirix.Stdout(), "  Manifest:    %s\n", status.Manifest)
+			fmt.Fprintf(jirix.Stdout(), "  Version:     %s\n", status.Version)
+			fmt.Fprintf(jirix.Stdout(), "  New version: %s\n", status.NewVersion)
+			for _, ins := range status.Instances {
+				if ins.Pinned != ins.Selected {
+					fmt.Fprintf(jirix.Stdout(), "  %s: %s -> %s\n", ins.Name, ins.Pinned, ins.Selected)
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	if c.jsonOutput != "" {
+		if err := writeJSONOutput(c.jsonOutput, statuses); err != nil {
+			return err
+		}
+	}
+	if jirix.Failures() != 0 {
+		return fmt.Errorf("failed to check %d packages", jirix.Failures())
+	}
+	return nil
+}
+
+func (c *packageCmd) runBump(jirix *jiri.X, args []string) error {
+	if len(args) == 0 {
+		return jirix.UsageErrorf("Please provide packages to bump")
+	}
+	if c.policy == "" {
+		c.policy = "ref=latest"
+	}
+	policy, err := parsePackageVersionPolicy(c.policy)
+	if err != nil {
+		return jirix.UsageErrorf("%v", err)
+	}
+	pkgs, keys, err := c.loadPackages(jirix, args)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no packages match %v", args)
+	}
+
+	// Compute all changes before writing any file.
+	ec := &editChanges{
+		Projects: []projectChanges{},
+		Imports:  []importChanges{},
+		Packages: []packageChanges{},
+	}
+	var bumped []packageVersionStatus
+	for _, pkg := range cipdPackages(jirix, pkgs, keys) {
+		status := checkPackageVersion(jirix, pkg, policy)
+		if status.Error != "" {
+			return fmt.Errorf("failed to bump package %q: %s", pkg.Name, status.Error)
+		}
+		if status.NewVersion == pkg.Version {
+			jirix.Logger.Debugf("Package %q is already at version %q", pkg.Name, pkg.Version)
+			continue
+		}
+		bumped = append(bumped, status)
+		ec.Packages = append(ec.Packages, packageChanges{
+			Name:   pkg.Name,
+			OldVer: pkg.Version,
+			NewVer: status.NewVersion,
+		})
+	}
+
+	manifests := make(map[string]string)
+	lockfiles := make(map[string]bool)
+	for _, status := range bumped {
+		content, ok := manifests[status.Manifest]
+		if !ok {
+			data, err := os.ReadFile(status.Manifest)
+			if err != nil {
+				return err
+			}
+			content = string(data)
+			for _, lockfile := range lockfilesForManifest(jirix, status.Manifest) {
+				lockfiles[lockfile] = true
+			}
+		}
+		content, err = updateVersion(content, "package", packageChanges{
+			Name:   status.Name,
+			OldVer: status.Version,
+			NewVer: status.NewVersion,
+		})
+		if err != nil {
+			return err
+		}
+		manifests[status.Manifest] = content
+	}
+
+	// The new versions are locked by the resolve machinery, once for each
+	// lockfile version.
+	bumpedPkgs := make(project.Packages)
+	for _, status := range bumped {
+		for _, key := range keys {
+			if pkg := pkgs[key]; pkg.Name == status.Name && pkg.Path == status.Path {
+				pkg.Version = status.NewVersion
+				pkg.Instances = nil
+				bumpedPkgs[key] = pkg
+			}
+		}
+	}
+	resolvedLocks := make(map[int]project.PackageLocks)
+	resolve := func(version int) (project.PackageLocks, error) {
+		if locks, ok := resolvedLocks[version]; ok {
+			return locks, nil
+		}
+		locks, err := project.LockPackages(jirix, bumpedPkgs, version, true)
+		if err != nil {
+			return nil, err
+		}
+		resolvedLocks[version] = locks
+		return locks, nil
+	}
+	lockContents := make(map[string][]byte)
+	for lockfile := range lockfiles {
+		data, err := bumpPackageLocks(lockfile, bumped, resolve)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			lockContents[lockfile] = data
+		}
+	}
+
+	for manifestPath, content := range manifests {
+		if err := os.WriteFile(manifestPath, []byte(content), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	for lockfile, data := range lockContents {
+		info, err := os.Stat(lockfile)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(lockfile, data, info.Mode()); err != nil {
+			return err
+		}
+		jirix.Logger.Debugf("updated lockfile %q", lockfile)
+	}
+	for _, pc := range ec.Packages {
+		fmt.Fprintf(jirix.Stdout(), "Bumped package %s from %s to %s\n", pc.Name, pc.OldVer, pc.NewVer)
+	}
+
+	if c.jsonOutput != "" {
+		if err := ec.toFile(c.jsonOutput); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bumpPackageLocks replaces the locks of the old versions of bumped packages
+// in lockfile with the locks of their new versions, which resolve returns as
+// "jiri resolve" records them in a lockfile of the given version. It returns
+// nil if lockfile does not need to be changed.
+func bumpPackageLocks(lockfile string, bumped []packageVersionStatus, resolve func(version int) (project.PackageLocks, error)) ([]byte, error) {
+	data, err := os.ReadFile(lockfile)
+	if err != nil {
+		return nil, err
+	}
+	version, err := project.LockFileVersion(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %q: %v", lockfile, err)
+	}
+	projectLocks, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %q: %v", lockfile, err)
+	}
+	var resolved project.PackageLocks
+	changed := false
+	for _, status := range bumped {
+		for _, ins := range status.Instances {
+			// Only the locks of the bumped package are replaced, other
+			// packages may use the same version at other paths or for
+			// other platforms. Locks without a path or a platform apply
+			// to any.
+			plats := ins.Platforms
+			if len(plats) == 0 {
+				plats = []string{""}
+			}
+			oldLocks := make(map[project.PackageLockKey]project.PackageLock)
+			for _, plat := range plats {
+				for _, candidate := range []project.PackageLock{
+					{PackageName: ins.Name, VersionTag: status.Version, LocalPath: status.Path, Platform: plat},
+					{PackageName: ins.Name, VersionTag: status.Version, LocalPath: status.Path},
+					{PackageName: ins.Name, VersionTag: status.Version, Platform: plat},
+					{PackageName: ins.Name, VersionTag: status.Version},
+				} {
+					if lock, ok := pkgLocks[candidate.Key()]; ok {
+						oldLocks[lock.Key()] = lock
+						break
+					}
+				}
+			}
+			if len(oldLocks) != 0 && resolved == nil {
+				if resolved, err = resolve(version); err != nil {
+					return nil, err
+				}
+			}
+			for _, oldLock := range oldLocks {
+				delete(pkgLocks, oldLock.Key())
+				found := false
+				for _, lock := range resolved {
+					if lock.PackageName != oldLock.PackageName || lock.VersionTag != status.NewVersion ||
+						(lock.LocalPath != "" && lock.LocalPath != status.Path) ||
+						(lock.Platform != "" && oldLock.Platform != "" && lock.Platform != oldLock.Platform) {
+						continue
+					}
+					if version < 2 {
+						lock.LocalPath = oldLock.LocalPath
+					}
+					if oldLock.ResolvedAt == "" {
+						lock.ResolvedAt = ""
+					}
+					pkgLocks[lock.Key()] = lock
+					found = true
+				}
+				if !found {
+					return nil, fmt.Errorf("version %q of package %q was not resolved", status.NewVersion, oldLock.PackageName)
+				}
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	return project.MarshalLockFile(version, projectLocks, pkgLocks)
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/project"
+)
+
+func TestParsePackageVersionPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy  string
+		want    packageVersionPolicy
+		wantErr bool
+	}{
+		{policy: "", want: packageVersionPolicy{}},
+		{policy: "ref=latest", want: packageVersionPolicy{ref: "latest"}},
+		{policy: "tag=git_revision", want: packageVersionPolicy{tagKey: "git_revision"}},
+		{policy: "ref=", wantErr: true},
+		{policy: "latest", wantErr: true},
+		{policy: "branch=main", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parsePackageVersionPolicy(test.policy)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parsePackageVersionPolicy(%q): expected error", test.policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePackageVersionPolicy(%q) failed: %v", test.policy, err)
+		}
+		if got != test.want {
+			t.Errorf("parsePackageVersionPolicy(%q) = %+v, want %+v", test.policy, got, test.want)
+		}
+	}
+}
+
+func TestNewestTag(t *testing.T) {
+	t.Parallel()
+	tags := []cipd.InstanceTag{
+		{Tag: "git_revision:aaa", RegisteredTs: 10},
+		{Tag: "version:1.2", RegisteredTs: 30},
+		{Tag: "git_revision:bbb", RegisteredTs: 20},
+	}
+	if got, want := newestTag(tags, "git_revision"), "git_revision:bbb"; got != want {
+		t.Errorf("newestTag() = %q, want %q", got, want)
+	}
+	if got := newestTag(tags, "build_id"); got != "" {
+		t.Errorf("newestTag() = %q, want no tag", got)
+	}
+}
+
+func TestBumpPackageLocks(t *testing.T) {
+	t.Parallel()
+	oldLock := project.PackageLock{
+		PackageName: "fuchsia/tools/linux-amd64",
+		LocalPath:   "prebuilt/tools",
+		VersionTag:  "git_revision:aaa",
+		InstanceID:  "old-id",
+	}
+	otherLock := project.PackageLock{
+		PackageName: "fuchsia/other",
+		VersionTag:  "git_revision:aaa",
+		InstanceID:  "other-id",
+	}
+	// The same package at the same version is also installed to another
+	// path, which is not bumped.
+	otherPathLock := project.PackageLock{
+		PackageName: "fuchsia/tools/linux-amd64",
+		LocalPath:   "prebuilt/other-tools",
+		VersionTag:  "git_revision:aaa",
+		InstanceID:  "old-id",
+	}
+	data, err := project.MarshalLockEntries(nil, project.PackageLocks{
+		oldLock.Key():       oldLock,
+		otherLock.Key():     otherLock,
+		otherPathLock.Key(): otherPathLock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockfile := filepath.Join(t.TempDir(), "jiri.lock")
+	if err := os.WriteFile(lockfile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bumped := []packageVersionStatus{{
+		Name:       "fuchsia/tools/${platform}",
+		Path:       "prebuilt/tools",
+		Version:    "git_revision:aaa",
+		NewVersion: "git_revision:bbb",
+		Instances: []packageInstanceStatus{{
+			Name:      "fuchsia/tools/linux-amd64",
+			Platforms: []string{"linux-amd64"},
+			Pinned:    "old-id",
+			Selected:  "new-id",
+		}},
+	}}
+	// The new version is resolved as by "jiri resolve", which does not
+	// record the path of version 1 locks.
+	resolve := func(version int) (project.PackageLocks, error) {
+		if version != 1 {
+			t.Errorf("got lockfile version %d, want 1", version)
+		}
+		lock := project.PackageLock{
+			PackageName: "fuchsia/tools/linux-amd64",
+			VersionTag:  "git_revision:bbb",
+			InstanceID:  "new-id",
+		}
+		return project.PackageLocks{lock.Key(): lock}, nil
+	}
+	data, err = bumpPackageLocks(lockfile, bumped, resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLock := project.PackageLock{
+		PackageName: "fuchsia/tools/linux-amd64",
+		LocalPath:   "prebuilt/tools",
+		VersionTag:  "git_revision:bbb",
+		InstanceID:  "new-id",
+	}
+	want := project.PackageLocks{
+		newLock.Key():       newLock,
+		otherLock.Key():     otherLock,
+		otherPathLock.Key(): otherPathLock,
+	}
+	if !reflect.DeepEqual(pkgLocks, want) {
+		t.Errorf("unexpected locks after bump, got %v, want %v", pkgLocks, want)
+	}
+
+	// Lockfiles without the bumped packages are left alone.
+	if data, err := bumpPackageLocks(lockfile, []packageVersionStatus{{Name: "fuchsia/missing", Version: "v1", NewVersion: "v2"}}, resolve); err != nil || data != nil {
+		t.Errorf("expected no changes, got %q, %v", data, err)
+	}
+}
@@ -6,10 +6,12 @@ package subcommands
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -20,6 +22,7 @@ import (
 	"go.fuchsia.dev/jiri/gerrit"
 	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/reviewhost"
 )
 
 type patchCmd struct {
@@ -36,6 +39,8 @@ type patchCmd struct {
 	cherryPick     bool
 	detachedHead   bool
 	project        string
+	file           string
+	update         bool
 	rebaseFailures uint32
 }
 
@@ -65,10 +70,34 @@ will try to create branch name out of it. If this fails default branch name
 will be same as topic. Currently patch does not support the scenario when
 change "B" is created on top of "A" and both have same topic.
 
+Several changes, for instance a stack of changes across projects which do
+not share a topic, can be passed as arguments or listed in the file passed
+with -file, one per line. They are applied as one operation: if any of them
+cannot be applied, the projects patched so far are restored. Each change is
+applied on its default branch in its project, and at most one change can be
+patched per project. The set of changes is recorded in .jiri_root, and
+"jiri patch -update" later fetches the latest patchsets of exactly these
+changes and rebases the local branches onto them, keeping the local commits
+made on top of the changes.
+
+The changes of a set are looked up on the Gerrit host given with -host, or
+on the review host of the current project. A change written as
+"<project>:<change>" is looked up on the review host of <project> instead,
+so that a set can contain changes from several Gerrit hosts and GitHub pull
+requests.
+
 Usage:
   jiri patch [flags] <change or topic>
+  jiri patch [flags] [<project>:]<change>...
+  jiri patch [flags] -file <file>
+  jiri patch [flags] -update
 
 <change or topic> is a change ID, full reference or topic when -topic is true.
+
+For the projects whose changes are GitHub pull requests (see the "reviewhost"
+attribute of the manifest), the change is the number of the pull request or
+its "refs/pull/<number>/head" reference, and the default branch name is
+"change/<number>". -topic is not supported for them.
 `
 }
 
@@ -84,6 +113,8 @@ func (c *patchCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.topic, "topic", false, `Patch whole topic.`)
 	f.BoolVar(&c.cherryPick, "cherry-pick", false, `Cherry-pick patches instead of checking out.`)
 	f.BoolVar(&c.detachedHead, "no-branch", false, `Don't create the branch for the patch.`)
+	f.StringVar(&c.file, "file", "", `File listing the changes to patch, one per line. Lines starting with '#' are ignored.`)
+	f.BoolVar(&c.update, "update", false, `Update the changes patched together to their latest patchsets.`)
 }
 
 func (c *patchCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -91,6 +122,9 @@ func (c *patchCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subco
 }
 
 func (c *patchCmd) run(jirix *jiri.X, args []string) error {
+	if c.update || c.file != "" || len(args) > 1 {
+		return c.runSet(jirix, args)
+	}
 	if expected, got := 1, len(args); expected != got {
 		return jirix.UsageErrorf("unexpected number of arguments: expected %v, got %v", expected, got)
 	}
@@ -111,7 +145,9 @@ func (c *patchCmd) run(jirix *jiri.X, args []string) error {
 	remoteBranch := ""
 	if !c.topic {
 		cl, ps, err = gerrit.ParseRefString(arg)
-		if err != nil {
+		if n, perr := reviewhost.ParsePullRef(arg); err != nil && perr == nil {
+			cl, changeRef = n, arg
+		} else if err != nil {
 			if c.project != "" {
 				return fmt.Errorf("Please pass change ref with -project flag (refs/changes/<ps>/<cl>/<patch-set>)")
 			}
@@ -153,7 +189,7 @@ func (c *patchCmd) run(jirix *jiri.X, args []string) error {
 		}
 	} else if project, perr := currentProject(jirix); perr == nil {
 		p = &project
-		if host == "" {
+		if host == "" && !reviewhost.IsGitHub(*p) {
 			if p.GerritHost == "" {
 				return fmt.Errorf("no Gerrit host; use the '--host' flag, or add a 'gerrithost' attribute for project %q", p.Name)
 			}
@@ -162,18 +198,18 @@ func (c *patchCmd) run(jirix *jiri.X, args []string) error {
 	}
 	if !c.topic && p != nil {
 		if remoteBranch == "" || changeRef == "" {
-			hostUrl, err := url.Parse(host)
+			hostProject := *p
+			hostProject.GerritHost = host
+			h, err := reviewhost.New(jirix, hostProject)
 			if err != nil {
-				return fmt.Errorf("invalid Gerrit host %q: %s", host, err)
+				return err
 			}
-			g := gerrit.New(jirix, hostUrl)
-
-			change, err := g.GetChange(cl)
+			change, err := h.GetChange(cl)
 			if err != nil {
 				return err
 			}
 			remoteBranch = change.Branch
-			changeRef = change.Reference()
+			changeRef = change.Ref
 		}
 		branch := c.branch
 		if ps != -1 {
@@ -319,11 +355,10 @@ func (c *patchCmd) patchProject(jirix *jiri.X, local project.Project, ref, branc
 	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
 	if !c.detachedHead {
 		if branch == "" {
-			cl, ps, err := gerrit.ParseRefString(ref)
-			if err != nil {
+			var err error
+			if branch, err = patchBranchName(ref); err != nil {
 				return false, err
 			}
-			branch = fmt.Sprintf("change/%v/%v", cl, ps)
 		}
 		jirix.Logger.Infof("Patching project %s(%s) on branch %q to ref %q\n", local.Name, local.Path, branch, ref)
 		branchExists, err := scm.BranchExists(branch)
@@ -442,6 +477,19 @@ func (c *patchCmd) patchProject(jirix *jiri.X, local project.Project, ref, branc
 	return true, nil
 }
 
+// patchBranchName returns the default name of the branch a change is patched
+// on.
+func patchBranchName(ref string) (string, error) {
+	if n, err := reviewhost.ParsePullRef(ref); err == nil {
+		return fmt.Sprintf("change/%v", n), nil
+	}
+	cl, ps, err := gerrit.ParseRefString(ref)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("change/%v/%v", cl, ps), nil
+}
+
 // rebaseProject rebases one branch of a project on top of a remote branch.
 func (c *patchCmd) rebaseProject(jirix *jiri.X, project project.Project, branch, remoteBranch string) error {
 	jirix.Logger.Infof("Rebasing branch %s in project %s(%s)\n", branch, project.Name, project.Path)
@@ -534,3 +582,446 @@ func (c *patchCmd) findProject(jirix *jiri.X, projectName string, projects proje
 	}
 	return projectToPatch
 }
+
+// patchSet is the set of changes patched together by "jiri patch", stored in
+// .jiri_root so that "jiri patch -update" can update them.
+type patchSet struct {
+	Rebase  bool            `json:"rebase,omitempty"`
+	Changes []patchedChange `json:"changes"`
+}
+
+// patchedChange is a change of a patch set.
+type patchedChange struct {
+	Number int `json:"number"`
+	// GerritHost and ReviewHost are the attributes of the project the change
+	// was looked up with, which select its review host.
+	GerritHost   string `json:"gerrithost,omitempty"`
+	ReviewHost   string `json:"reviewhost,omitempty"`
+	Ref          string `json:"ref"`
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Branch       string `json:"branch"`
+	RemoteBranch string `json:"remote_branch"`
+	// Revision is the revision of the change on the local branch, below the
+	// local commits made on top of it.
+	Revision string `json:"revision"`
+}
+
+func patchSetFile(jirix *jiri.X) string {
+	return filepath.Join(jirix.RootMetaDir(), "patches.json")
+}
+
+func readPatchSet(jirix *jiri.X) (*patchSet, error) {
+	data, err := os.ReadFile(patchSetFile(jirix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	set := &patchSet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", patchSetFile(jirix), err)
+	}
+	return set, nil
+}
+
+func (s *patchSet) write(jirix *jiri.X) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return project.SafeWriteFile(jirix, patchSetFile(jirix), data)
+}
+
+// patchRollback records the state of a project before a change of a patch
+// set is applied to it, to restore it if the patch set cannot be applied.
+type patchRollback struct {
+	project project.Project
+	path    string
+	// head is the branch checked out, or headRev if the project is detached.
+	head    string
+	headRev string
+	branch  string
+	// branchRev is the revision of branch, empty if it does not exist.
+	branchRev string
+}
+
+func newPatchRollback(jirix *jiri.X, p project.Project, path, branch string) (*patchRollback, error) {
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+	r := &patchRollback{project: p, path: path, branch: branch}
+	var err error
+	if r.headRev, err = scm.CurrentRevision(); err != nil {
+		return nil, err
+	}
+	r.head = r.headRev
+	if scm.IsOnBranch() {
+		if r.head, err = scm.CurrentBranchName(); err != nil {
+			return nil, err
+		}
+	}
+	if exists, err := scm.BranchExists(branch); err != nil {
+		return nil, err
+	} else if exists {
+		if r.branchRev, err = scm.CurrentRevisionForRef(branch); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *patchRollback) restore(jirix *jiri.X) error {
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(r.project.Path))
+	submodules := gitutil.RecurseSubmodulesOpt(r.project.GitSubmodules && jirix.EnableSubmodules)
+	if err := scm.Checkout(r.headRev, gitutil.DetachOpt(true), submodules); err != nil {
+		return err
+	}
+	exists, err := scm.BranchExists(r.branch)
+	if err != nil {
+		return err
+	}
+	switch {
+	case r.branchRev == "":
+		if exists {
+			if err := scm.DeleteBranch(r.branch, gitutil.ForceOpt(true)); err != nil {
+				return err
+			}
+		}
+	case !exists:
+		if err := scm.CreateBranchFromRef(r.branch, r.branchRev); err != nil {
+			return err
+		}
+	default:
+		if err := scm.Checkout(r.branch); err != nil {
+			return err
+		}
+		if err := scm.Reset(r.branchRev); err != nil {
+			return err
+		}
+	}
+	return scm.Checkout(r.head, gitutil.DetachOpt(r.head == r.headRev), submodules)
+}
+
+// rollback restores the projects patched before err, in reverse order.
+func (c *patchCmd) rollback(jirix *jiri.X, rollbacks []*patchRollback, err error) error {
+	failures := 0
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		r := rollbacks[i]
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", r.project.Name, r.path)
+		if err := r.restore(jirix); err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while restoring: %s\n", err))
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Restored %s", r.head))
+	}
+	if failures != 0 {
+		return fmt.Errorf("%s\nfailed to restore %d project(s)", err, failures)
+	}
+	if c.rebaseFailures != 0 {
+		jirix.Logger.Errorf("%s\n", err)
+		return rebaseFailedErr
+	}
+	return err
+}
+
+// checkPatchSetProjects returns an error if any of the projects has
+// uncommitted changes.
+func checkPatchSetProjects(jirix *jiri.X, projects []project.Project) error {
+	var dirty []string
+	for _, p := range projects {
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+		if uncommitted, err := scm.HasUncommittedChanges(); err != nil {
+			return err
+		} else if uncommitted {
+			dirty = append(dirty, p.Name)
+		}
+	}
+	if len(dirty) != 0 {
+		return fmt.Errorf("cannot patch, commit or stash the uncommitted changes of %s first", strings.Join(dirty, ", "))
+	}
+	return nil
+}
+
+// runSet patches several changes as one operation, or updates the changes
+// patched together with -update.
+func (c *patchCmd) runSet(jirix *jiri.X, args []string) error {
+	if c.topic || c.project != "" || c.branch != "" || c.detachedHead || c.cherryPick || c.rebaseRevision != "" {
+		return jirix.UsageErrorf("-topic, -project, -branch, -no-branch, -cherry-pick and -rebase-revision cannot be used to patch several changes")
+	}
+	if c.update {
+		if len(args) != 0 || c.file != "" {
+			return jirix.UsageErrorf("-update does not take any change")
+		}
+		return c.runUpdate(jirix)
+	}
+	changes := args
+	if c.file != "" {
+		data, err := os.ReadFile(c.file)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				changes = append(changes, strings.Fields(line)...)
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no change listed in %s", c.file)
+	}
+
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	var current *project.Project
+	if p, err := currentProject(jirix); err == nil {
+		current = &p
+	}
+
+	// Find all the changes and their projects before patching any of them.
+	set := &patchSet{Rebase: c.rebase}
+	var projects []project.Project
+	patched := make(map[project.ProjectKey]int)
+	for _, arg := range changes {
+		p, hostProject, cl, ref, err := c.findSetChange(jirix, arg, localProjects, current)
+		if err != nil {
+			return err
+		}
+		h, err := reviewhost.New(jirix, hostProject)
+		if err != nil {
+			return err
+		}
+		change, err := h.GetChange(cl)
+		if err != nil {
+			return err
+		}
+		if ref == "" {
+			ref = change.Ref
+		}
+		if p == nil {
+			if reviewhost.IsGitHub(hostProject) {
+				// Pull requests are numbered per repository.
+				p = current
+			} else {
+				hostUrl, err := url.Parse(hostProject.GerritHost)
+				if err != nil {
+					return fmt.Errorf("invalid Gerrit host %q: %v", hostProject.GerritHost, err)
+				}
+				p = c.findProject(jirix, change.Project, localProjects, hostProject.GerritHost, hostUrl, change.URL)
+			}
+			if p == nil {
+				return fmt.Errorf("cannot find project to patch CL %s", change.URL)
+			}
+		}
+		if other, ok := patched[p.Key()]; ok {
+			return fmt.Errorf("changes %d and %d are both in project %s, only patch the last change of a stack", other, cl, p.Name)
+		}
+		patched[p.Key()] = cl
+		branch, err := patchBranchName(ref)
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(jirix.Root, p.Path)
+		if err != nil {
+			relativePath = p.Path
+		}
+		set.Changes = append(set.Changes, patchedChange{
+			Number:       cl,
+			GerritHost:   hostProject.GerritHost,
+			ReviewHost:   hostProject.ReviewHost,
+			Ref:          ref,
+			Key:          p.Key().String(),
+			Name:         p.Name,
+			Path:         relativePath,
+			Branch:       branch,
+			RemoteBranch: change.Branch,
+		})
+		projects = append(projects, *p)
+	}
+	if err := checkPatchSetProjects(jirix, projects); err != nil {
+		return err
+	}
+
+	var rollbacks []*patchRollback
+	for i := range set.Changes {
+		pc := &set.Changes[i]
+		r, err := newPatchRollback(jirix, projects[i], pc.Path, pc.Branch)
+		if err != nil {
+			return c.rollback(jirix, rollbacks, err)
+		}
+		rollbacks = append(rollbacks, r)
+		failures := jirix.Failures()
+		ok, err := c.patchProject(jirix, projects[i], pc.Ref, pc.Branch, pc.RemoteBranch)
+		if err == nil && (!ok || jirix.Failures() != failures) {
+			err = fmt.Errorf("see the errors above")
+		}
+		if err == nil {
+			pc.Revision, err = gitutil.New(jirix, gitutil.RootDirOpt(projects[i].Path)).CurrentRevisionForRef(pc.Branch)
+		}
+		if err != nil {
+			return c.rollback(jirix, rollbacks, fmt.Errorf("cannot patch change %d in project %s(%s): %s", pc.Number, pc.Name, pc.Path, err))
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): %s\n", pc.Name, pc.Path, jirix.Color.Green("Patched change %d on branch %q", pc.Number, pc.Branch))
+	}
+	return set.write(jirix)
+}
+
+// findSetChange parses a change of a patch set, "[<project>:]<change>", and
+// returns the number of the change and its ref if given. It also returns the
+// local project named by the change if any, and the project whose review
+// host the change is looked up on: the named project, or the current project
+// or the host given with -host otherwise.
+func (c *patchCmd) findSetChange(jirix *jiri.X, arg string, localProjects project.Projects, current *project.Project) (*project.Project, project.Project, int, string, error) {
+	name, change, ok := strings.Cut(arg, ":")
+	if !ok {
+		name, change = "", arg
+	}
+	ref := change
+	cl, _, err := gerrit.ParseRefString(change)
+	if err != nil {
+		if cl, err = reviewhost.ParsePullRef(change); err != nil {
+			ref = ""
+			if cl, err = strconv.Atoi(change); err != nil {
+				return nil, project.Project{}, 0, "", fmt.Errorf("invalid change %q", arg)
+			}
+		}
+	}
+
+	if name != "" {
+		p := c.findProject(jirix, name, localProjects, "", nil, arg)
+		if p == nil {
+			return nil, project.Project{}, 0, "", fmt.Errorf("cannot find project %q to patch change %q", name, arg)
+		}
+		hostProject := *p
+		if hostProject.GerritHost == "" && !reviewhost.IsGitHub(hostProject) {
+			hostProject.GerritHost = c.host
+		}
+		if hostProject.GerritHost == "" && !reviewhost.IsGitHub(hostProject) {
+			return nil, project.Project{}, 0, "", fmt.Errorf("no Gerrit host; use the '--host' flag, or add a 'gerrithost' attribute for project %q", p.Name)
+		}
+		return p, hostProject, cl, ref, nil
+	}
+	var hostProject project.Project
+	if current != nil {
+		hostProject = *current
+	}
+	if c.host != "" {
+		hostProject.GerritHost, hostProject.ReviewHost = c.host, ""
+	}
+	if hostProject.GerritHost == "" && !reviewhost.IsGitHub(hostProject) {
+		return nil, project.Project{}, 0, "", fmt.Errorf("no Gerrit host; use the '--host' flag, run this from inside a project or pass the change as <project>:<change>")
+	}
+	return nil, hostProject, cl, ref, nil
+}
+
+// runUpdate updates the changes of the recorded patch set to their latest
+// patchsets.
+func (c *patchCmd) runUpdate(jirix *jiri.X) error {
+	set, err := readPatchSet(jirix)
+	if err != nil {
+		return err
+	}
+	if set == nil {
+		return fmt.Errorf("no changes were patched together, pass several changes to \"jiri patch\" first")
+	}
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	var projects []project.Project
+	for _, pc := range set.Changes {
+		key, ok := project.ProjectKeyFromString(pc.Key)
+		p, found := localProjects[key]
+		if !ok || !found {
+			return fmt.Errorf("project %s(%s) does not exist anymore", pc.Name, pc.Path)
+		}
+		projects = append(projects, p)
+	}
+	if err := checkPatchSetProjects(jirix, projects); err != nil {
+		return err
+	}
+
+	rebase := set.Rebase || c.rebase
+	var rollbacks []*patchRollback
+	for i := range set.Changes {
+		pc := &set.Changes[i]
+		hostProject := projects[i]
+		hostProject.GerritHost, hostProject.ReviewHost = pc.GerritHost, pc.ReviewHost
+		h, err := reviewhost.New(jirix, hostProject)
+		if err != nil {
+			return c.rollback(jirix, rollbacks, err)
+		}
+		change, err := h.GetChange(pc.Number)
+		if err != nil {
+			return c.rollback(jirix, rollbacks, err)
+		}
+		// The ref of a pull request does not change when it is updated.
+		ref := change.Ref
+		if ref == pc.Ref && !rebase && !reviewhost.IsGitHub(hostProject) {
+			fmt.Fprintf(jirix.Stdout(), "Project %s(%s): %s\n", pc.Name, pc.Path, jirix.Color.Green("Change %d is up to date", pc.Number))
+			continue
+		}
+		r, err := newPatchRollback(jirix, projects[i], pc.Path, pc.Branch)
+		if err != nil {
+			return c.rollback(jirix, rollbacks, err)
+		}
+		rollbacks = append(rollbacks, r)
+		if err := c.updateProject(jirix, projects[i], pc, ref, rebase); err != nil {
+			return c.rollback(jirix, rollbacks, fmt.Errorf("cannot update change %d in project %s(%s): %s", pc.Number, pc.Name, pc.Path, err))
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): %s\n", pc.Name, pc.Path, jirix.Color.Green("Updated change %d on branch %q to %s", pc.Number, pc.Branch, ref))
+	}
+	return set.write(jirix)
+}
+
+// updateProject moves the local commits made on top of the patched change pc
+// onto ref, the latest patchset of the change, and rebases the branch of the
+// change onto its remote branch if rebase is set.
+func (c *patchCmd) updateProject(jirix *jiri.X, p project.Project, pc *patchedChange, ref string, rebase bool) error {
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+	if exists, err := scm.BranchExists(pc.Branch); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("branch %q does not exist anymore", pc.Branch)
+	}
+	if ok, err := scm.IsAncestor(pc.Revision, pc.Branch); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("branch %q does not contain the patched change anymore", pc.Branch)
+	}
+	if err := scm.FetchRefspec("origin", ref, gitutil.RecurseSubmodulesOpt(jirix.EnableSubmodules)); err != nil {
+		return err
+	}
+	rev, err := scm.CurrentRevisionForRef("FETCH_HEAD")
+	if err != nil {
+		return err
+	}
+	if err := scm.RebaseBranch(pc.Branch, pc.Revision, gitutil.OntoOpt(rev)); err != nil {
+		if err2 := scm.RebaseAbort(); err2 != nil {
+			return err2
+		}
+		return fmt.Errorf("cannot rebase the local commits: %s", err)
+	}
+	local, err := scm.CountCommits(pc.Branch, rev)
+	if err != nil {
+		return err
+	}
+	if rebase {
+		failures := jirix.Failures()
+		if err := c.rebaseProject(jirix, p, pc.Branch, pc.RemoteBranch); err != nil {
+			return err
+		}
+		if jirix.Failures() != failures {
+			return fmt.Errorf("cannot rebase onto %s", pc.RemoteBranch)
+		}
+	}
+	revision, err := scm.CurrentRevisionForRef(fmt.Sprintf("%s~%d", pc.Branch, local))
+	if err != nil {
+		return err
+	}
+	pc.Ref, pc.Revision = ref, revision
+	return nil
+}
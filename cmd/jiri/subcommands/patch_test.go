@@ -0,0 +1,248 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/jiritest"
+	"go.fuchsia.dev/jiri/project"
+)
+
+// uploadPatchset pushes a commit adding file on top of origin/main to the ref
+// of a patchset of a change, replacing the ref if it exists, and restores the
+// project.
+func uploadPatchset(t *testing.T, fake *jiritest.FakeJiriRoot, p project.Project, ref, file string) {
+	t.Helper()
+	git := gitutil.New(fake.X, gitutil.RootDirOpt(p.Path))
+	head, err := git.CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Checkout("origin/main", gitutil.DetachOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, p.Path, file, "add "+file)
+	if err := git.Push("origin", "HEAD:"+ref, gitutil.ForceOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Checkout(head, gitutil.DetachOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPatchSet(t *testing.T) {
+	// The refs of the latest patchsets of the changes, by change number.
+	var mu sync.Mutex
+	refs := map[int]string{
+		1: "refs/changes/01/1/1",
+		2: "refs/changes/02/2/1",
+		3: "refs/changes/03/3/1",
+	}
+	projects := map[int]string{1: "project-0", 2: "project-1", 3: "project-1"}
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc("/changes/", func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.ParseForm()
+		var n int
+		fmt.Sscanf(r.Form.Get("q"), "change:%d", &n)
+		if refs[n] == "" {
+			rw.Write([]byte(")]}'\n[]"))
+			return
+		}
+		fmt.Fprintf(rw, `)]}'
+[{"_number":%d,"project":%q,"branch":"main","current_revision":"r","revisions":{"r":{"fetch":{"http":{"ref":%q}}}}}]`, n, projects[n], refs[n])
+	})
+	serverMux.HandleFunc("/tools/hooks/commit-msg", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("#!/bin/sh"))
+	})
+	server := httptest.NewServer(serverMux)
+	defer server.Close()
+
+	localProjects, fake := setupGerritUniverse(t, server.URL)
+	for _, p := range localProjects {
+		configureUser(t, fake.X, p.Path)
+	}
+	uploadPatchset(t, fake, localProjects[0], refs[1], "change1")
+	uploadPatchset(t, fake, localProjects[1], refs[2], "change2")
+	git0 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path))
+	git1 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[1].Path))
+	head0, err := git0.CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is patched if a change cannot be applied: the ref of change 3
+	// does not exist.
+	cmd := &patchCmd{host: server.URL}
+	if err := cmd.run(fake.X, []string{"1", "3"}); err == nil || !strings.Contains(err.Error(), "change 3") {
+		t.Errorf("expected change 3 not to be patched, got %v", err)
+	}
+	if exists, err := git0.BranchExists("change/1/1"); err != nil || exists {
+		t.Errorf("expected branch change/1/1 to be deleted, got %v, %v", exists, err)
+	}
+	if head, err := git0.CurrentRevision(); err != nil || head != head0 || git0.IsOnBranch() {
+		t.Errorf("expected %s to be restored to %s, got %s, %v", localProjects[0].Name, head0, head, err)
+	}
+	if _, err := os.Stat(patchSetFile(fake.X)); !os.IsNotExist(err) {
+		t.Errorf("expected no patch set to be recorded, got %v", err)
+	}
+
+	// At most one change is patched per project.
+	if err := cmd.run(fake.X, []string{"2", "3"}); err == nil || !strings.Contains(err.Error(), "both in project") {
+		t.Errorf("expected changes 2 and 3 to be rejected, got %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "changes")
+	if err := os.WriteFile(file, []byte("# A stack of changes.\n1\n\nrefs/changes/02/2/1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = &patchCmd{host: server.URL, file: file}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i, git := range []*gitutil.Git{git0, git1} {
+		branch := fmt.Sprintf("change/%d/1", i+1)
+		if current, err := git.CurrentBranchName(); err != nil || current != branch {
+			t.Errorf("expected %s to be on branch %s, got %q, %v", localProjects[i].Name, branch, current, err)
+		}
+	}
+	set, err := readPatchSet(fake.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set == nil || len(set.Changes) != 2 || set.Changes[0].Number != 1 || set.Changes[1].Ref != refs[2] {
+		t.Fatalf("unexpected patch set %+v", set)
+	}
+
+	// A new patchset of change 1 replaces the old one below the local
+	// commits.
+	writeFile(t, fake.X, localProjects[0].Path, "local", "local change")
+	mu.Lock()
+	refs[1] = "refs/changes/01/1/2"
+	mu.Unlock()
+	uploadPatchset(t, fake, localProjects[0], refs[1], "change1-v2")
+	cmd = &patchCmd{update: true}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := git0.Checkout("change/1/1"); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]bool{"change1": false, "change1-v2": true, "local": true} {
+		if _, err := os.Stat(filepath.Join(localProjects[0].Path, file)); (err == nil) != want {
+			t.Errorf("expected file %s to exist: %v, got %v", file, want, err)
+		}
+	}
+	set, err = readPatchSet(fake.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := set.Changes[0]; got.Ref != refs[1] {
+		t.Errorf("got ref %q for change 1, want %q", got.Ref, refs[1])
+	}
+	if rev, err := git0.CurrentRevisionForRef("change/1/1~1"); err != nil || rev != set.Changes[0].Revision {
+		t.Errorf("expected the revision of change 1 to be recorded, got %s, want %s (%v)", set.Changes[0].Revision, rev, err)
+	}
+}
+
+func TestPatchSetHosts(t *testing.T) {
+	// fakeGerrit serves the change number of a Gerrit host, in project.
+	fakeGerrit := func(number int, project, ref string) *httptest.Server {
+		serverMux := http.NewServeMux()
+		serverMux.HandleFunc("/changes/", func(rw http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			if r.Form.Get("q") != fmt.Sprintf("change:%d", number) {
+				rw.Write([]byte(")]}'\n[]"))
+				return
+			}
+			fmt.Fprintf(rw, `)]}'
+[{"_number":%d,"project":%q,"branch":"main","current_revision":"r","revisions":{"r":{"fetch":{"http":{"ref":%q}}}}}]`, number, project, ref)
+		})
+		serverMux.HandleFunc("/tools/hooks/commit-msg", func(rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte("#!/bin/sh"))
+		})
+		return httptest.NewServer(serverMux)
+	}
+	server1 := fakeGerrit(1, "project-0", "refs/changes/01/1/1")
+	defer server1.Close()
+	server2 := fakeGerrit(1, "project-1", "refs/changes/01/1/3")
+	defer server2.Close()
+	github := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/pulls/5") {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Write([]byte(`{"number":5,"state":"open","base":{"ref":"main"},"html_url":"https://github.com/owner/project-2/pull/5"}`))
+	}))
+	defer github.Close()
+
+	localProjects, fake := setupGerritUniverse(t, server1.URL)
+	m, err := fake.ReadRemoteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range m.Projects {
+		switch p.Name {
+		case localProjects[1].Name:
+			m.Projects[i].GerritHost = server2.URL
+		case localProjects[2].Name:
+			m.Projects[i].GerritHost = ""
+			m.Projects[i].ReviewHost = "github:" + github.URL
+		}
+	}
+	if err := fake.WriteRemoteManifest(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	uploadPatchset(t, fake, localProjects[0], "refs/changes/01/1/1", "change1")
+	uploadPatchset(t, fake, localProjects[1], "refs/changes/01/1/3", "other-change1")
+	uploadPatchset(t, fake, localProjects[2], "refs/pull/5/head", "pull5")
+
+	// Change 1 of the current project's host, change 1 of the host of
+	// project-1 and pull request 5 of project-2 are patched together.
+	fake.X.Cwd = localProjects[0].Path
+	cmd := &patchCmd{}
+	if err := cmd.run(fake.X, []string{"1", localProjects[1].Name + ":1", localProjects[2].Name + ":5"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, branch := range []string{"change/1/1", "change/1/3", "change/5"} {
+		git := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[i].Path))
+		if current, err := git.CurrentBranchName(); err != nil || current != branch {
+			t.Errorf("expected %s to be on branch %s, got %q, %v", localProjects[i].Name, branch, current, err)
+		}
+	}
+	set, err := readPatchSet(fake.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set == nil || len(set.Changes) != 3 ||
+		set.Changes[0].GerritHost != server1.URL ||
+		set.Changes[1].GerritHost != server2.URL ||
+		set.Changes[2].ReviewHost != "github:"+github.URL {
+		t.Fatalf("unexpected patch set %+v", set)
+	}
+
+	// Updating a pull request keeps its ref.
+	uploadPatchset(t, fake, localProjects[2], "refs/pull/5/head", "pull5-v2")
+	cmd = &patchCmd{update: true}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(localProjects[2].Path, "pull5-v2")); err != nil {
+		t.Errorf("expected pull request 5 to be updated: %v", err)
+	}
+}
@@ -18,9 +18,10 @@ import (
 type projectConfigCmd struct {
 	cmdBase
 
-	ignore   string
-	noUpdate string
-	noRebase string
+	ignore    string
+	noUpdate  string
+	noRebase  string
+	autoStash string
 }
 
 func (c *projectConfigCmd) Name() string     { return "project-config" }
@@ -38,6 +39,7 @@ func (c *projectConfigCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.ignore, "ignore", "", `This can be true or false. If set to true project would be completely ignored while updating`)
 	f.StringVar(&c.noUpdate, "no-update", "", `This can be true or false. If set to true project won't be updated`)
 	f.StringVar(&c.noRebase, "no-rebase", "", `This can be true or false. If set to true local branch won't be rebased or merged.`)
+	f.StringVar(&c.autoStash, "autostash", "", `This can be true or false. If set to true uncommitted changes are stashed before updating the project and re-applied afterwards.`)
 }
 
 func (c *projectConfigCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -49,7 +51,7 @@ func (c *projectConfigCmd) run(jirix *jiri.X, args []string) error {
 	if err != nil {
 		return err
 	}
-	if c.ignore == "" && c.noUpdate == "" && c.noRebase == "" {
+	if c.ignore == "" && c.noUpdate == "" && c.noRebase == "" && c.autoStash == "" {
 		displayConfig(jirix, p.LocalConfig)
 		return nil
 	}
@@ -63,6 +65,9 @@ func (c *projectConfigCmd) run(jirix *jiri.X, args []string) error {
 	if err := setBoolVar(c.noRebase, &lc.NoRebase, "no-rebase"); err != nil {
 		return err
 	}
+	if err := setBoolVar(c.autoStash, &lc.AutoStash, "autostash"); err != nil {
+		return err
+	}
 	return project.WriteLocalConfig(jirix, p, lc)
 }
 
@@ -83,4 +88,5 @@ func displayConfig(jirix *jiri.X, lc project.LocalConfig) {
 	fmt.Fprintf(jirix.Stdout(), "ignore: %t\n", lc.Ignore)
 	fmt.Fprintf(jirix.Stdout(), "no-update: %t\n", lc.NoUpdate)
 	fmt.Fprintf(jirix.Stdout(), "no-rebase: %t\n", lc.NoRebase)
+	fmt.Fprintf(jirix.Stdout(), "autostash: %t\n", lc.AutoStash)
 }
@@ -55,6 +55,16 @@ func testConfig(t *testing.T, fake *jiritest.FakeJiriRoot, localProjects []proje
 	if newConfig.NoRebase != expectedOutput {
 		t.Errorf("local config no-rebase: got %t, want %t", newConfig.NoRebase, expectedOutput)
 	}
+
+	expectedOutput = oldConfig.AutoStash
+	if cmd.autoStash != "" {
+		if expectedOutput, err = strconv.ParseBool(cmd.autoStash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if newConfig.AutoStash != expectedOutput {
+		t.Errorf("local config autostash: got %t, want %t", newConfig.AutoStash, expectedOutput)
+	}
 }
 
 func TestConfig(t *testing.T) {
@@ -73,6 +83,10 @@ func TestConfig(t *testing.T) {
 		noRebase: "true",
 	})
 
+	testConfig(t, fake, localProjects, projectConfigCmd{
+		autoStash: "true",
+	})
+
 	testConfig(t, fake, localProjects, projectConfigCmd{})
 
 	testConfig(t, fake, localProjects, projectConfigCmd{
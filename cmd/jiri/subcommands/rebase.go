@@ -0,0 +1,304 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type rebaseCmd struct {
+	cmdBase
+
+	onto        string
+	branch      string
+	continueOpt bool
+	abort       bool
+}
+
+func (c *rebaseCmd) Name() string { return "rebase" }
+func (c *rebaseCmd) Synopsis() string {
+	return "Rebase the topic branch in all the projects"
+}
+func (c *rebaseCmd) Usage() string {
+	return `Rebases the current topic branch in all the projects which have it checked
+out, and tracks the projects with conflicts until they are resolved.
+
+Usage:
+  jiri rebase [flags]
+  jiri rebase -continue
+  jiri rebase -abort
+
+The topic branch is the current branch of the project containing the current
+directory, or the branch given by -branch. It is rebased onto -onto, which is
+JIRI_HEAD or a branch, in every project where it is checked out.
+
+If the rebase stops because of conflicts in some projects, they are recorded
+in the jiri root. Resolve the conflicts and stage the changes in each of them
+and run "jiri rebase -continue", or run "jiri rebase -abort" to restore all
+the projects to their state before the rebase.
+`
+}
+
+func (c *rebaseCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.onto, "onto", "JIRI_HEAD", "JIRI_HEAD or the branch to rebase the topic branch onto.")
+	f.StringVar(&c.branch, "branch", "", "The topic branch to rebase. Defaults to the current branch of the current project.")
+	f.BoolVar(&c.continueOpt, "continue", false, "Continue the rebase in the projects with conflicts once they are resolved.")
+	f.BoolVar(&c.abort, "abort", false, "Abort the rebase and restore all the projects.")
+}
+
+func (c *rebaseCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+// rebaseProject is a project rebased by "jiri rebase".
+type rebaseProject struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Revision is the revision of the topic branch before the rebase.
+	Revision string `json:"revision"`
+	// Conflict is set while the rebase of the project is stopped.
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// rebaseState is the state of a rebase stopped because of conflicts,
+// stored in .jiri_root.
+type rebaseState struct {
+	Branch   string          `json:"branch"`
+	Onto     string          `json:"onto"`
+	Projects []rebaseProject `json:"projects"`
+}
+
+func rebaseStateFile(jirix *jiri.X) string {
+	return filepath.Join(jirix.RootMetaDir(), "rebase.json")
+}
+
+func readRebaseState(jirix *jiri.X) (*rebaseState, error) {
+	data, err := os.ReadFile(rebaseStateFile(jirix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &rebaseState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", rebaseStateFile(jirix), err)
+	}
+	return state, nil
+}
+
+// write records the state if some projects have conflicts, and removes the
+// recorded state otherwise.
+func (s *rebaseState) write(jirix *jiri.X) error {
+	if len(s.conflicts()) == 0 {
+		if err := os.Remove(rebaseStateFile(jirix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return project.SafeWriteFile(jirix, rebaseStateFile(jirix), data)
+}
+
+// conflicts returns the projects whose rebase is stopped.
+func (s *rebaseState) conflicts() []string {
+	var ret []string
+	for _, p := range s.Projects {
+		if p.Conflict {
+			ret = append(ret, fmt.Sprintf("%s(%s)", p.Name, p.Path))
+		}
+	}
+	return ret
+}
+
+// stoppedError returns the error reporting the projects with conflicts.
+func (s *rebaseState) stoppedError() error {
+	return fmt.Errorf("rebase of %q stopped because of conflicts in %s.\nResolve them and run \"jiri rebase -continue\", or run \"jiri rebase -abort\"", s.Branch, strings.Join(s.conflicts(), ", "))
+}
+
+func (c *rebaseCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) != 0 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+	if c.continueOpt && c.abort {
+		return jirix.UsageErrorf("-continue and -abort cannot be combined")
+	}
+	state, err := readRebaseState(jirix)
+	if err != nil {
+		return err
+	}
+	if c.continueOpt || c.abort {
+		if state == nil {
+			return fmt.Errorf("no rebase in progress")
+		}
+		localProjects, err := project.LocalProjects(jirix, project.FastScan)
+		if err != nil {
+			return err
+		}
+		if c.abort {
+			return c.runAbort(jirix, localProjects, state)
+		}
+		return c.runContinue(jirix, localProjects, state)
+	}
+	if state != nil {
+		return fmt.Errorf("a rebase of %q is in progress in %s, run \"jiri rebase -continue\" or \"jiri rebase -abort\"", state.Branch, strings.Join(state.conflicts(), ", "))
+	}
+	return c.runRebase(jirix)
+}
+
+func (c *rebaseCmd) runRebase(jirix *jiri.X) error {
+	branch, projects, err := topicBranchProjects(jirix, c.branch)
+	if err != nil {
+		return err
+	}
+	// Check all the projects before rebasing any of them.
+	var dirty []string
+	for _, p := range projects {
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+		if uncommitted, err := scm.HasUncommittedChanges(); err != nil {
+			return err
+		} else if uncommitted {
+			dirty = append(dirty, p.Name)
+		}
+	}
+	if len(dirty) != 0 {
+		return fmt.Errorf("cannot rebase, commit or stash the uncommitted changes of %s first", strings.Join(dirty, ", "))
+	}
+
+	state := &rebaseState{Branch: branch, Onto: c.onto}
+	failures := 0
+	for _, p := range projects {
+		relativePath, err := filepath.Rel(jirix.Root, p.Path)
+		if err != nil {
+			relativePath = p.Path
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(p.Path))
+		rev, err := scm.CurrentRevision()
+		if err != nil {
+			return err
+		}
+		rp := rebaseProject{Key: p.Key().String(), Name: p.Name, Path: relativePath, Revision: rev}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", p.Name, relativePath)
+		if err := scm.Rebase(c.onto); err != nil {
+			inProgress, err2 := scm.IsRebaseInProgress()
+			if err2 != nil || !inProgress {
+				failures++
+				fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while rebasing: %s\n", err))
+				continue
+			}
+			rp.Conflict = true
+			files, _ := scm.UnmergedFiles()
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Conflicts in %s\n", strings.Join(files, ", ")))
+		} else {
+			fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Rebased onto %s", c.onto))
+		}
+		state.Projects = append(state.Projects, rp)
+	}
+	if err := state.write(jirix); err != nil {
+		return err
+	}
+	if len(state.conflicts()) != 0 {
+		return state.stoppedError()
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to rebase %d project(s)", failures)
+	}
+	return nil
+}
+
+// stateProjectGit returns the git repository of the project recorded with
+// the given name, path and key.
+func stateProjectGit(jirix *jiri.X, localProjects project.Projects, name, path, key string) (*gitutil.Git, error) {
+	k, ok := project.ProjectKeyFromString(key)
+	local, found := localProjects[k]
+	if !ok || !found {
+		return nil, fmt.Errorf("project %s(%s) does not exist anymore", name, path)
+	}
+	return gitutil.New(jirix, gitutil.RootDirOpt(local.Path)), nil
+}
+
+func (c *rebaseCmd) runContinue(jirix *jiri.X, localProjects project.Projects, state *rebaseState) error {
+	for i, p := range state.Projects {
+		if !p.Conflict {
+			continue
+		}
+		scm, err := stateProjectGit(jirix, localProjects, p.Name, p.Path, p.Key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", p.Name, p.Path)
+		if inProgress, err := scm.IsRebaseInProgress(); err != nil {
+			return err
+		} else if inProgress {
+			if err := scm.RebaseContinue(); err != nil {
+				if files, _ := scm.UnmergedFiles(); len(files) != 0 {
+					fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Conflicts in %s\n", strings.Join(files, ", ")))
+				} else {
+					fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Cannot continue: %s\n", err))
+				}
+				continue
+			}
+		}
+		// The rebase was completed, possibly by the user.
+		state.Projects[i].Conflict = false
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Rebased onto %s", state.Onto))
+	}
+	if err := state.write(jirix); err != nil {
+		return err
+	}
+	if len(state.conflicts()) != 0 {
+		return state.stoppedError()
+	}
+	return nil
+}
+
+func (c *rebaseCmd) runAbort(jirix *jiri.X, localProjects project.Projects, state *rebaseState) error {
+	failures := 0
+	for _, p := range state.Projects {
+		scm, err := stateProjectGit(jirix, localProjects, p.Name, p.Path, p.Key)
+		if err != nil {
+			jirix.Logger.Warningf("%s\n", err)
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", p.Name, p.Path)
+		if inProgress, err := scm.IsRebaseInProgress(); err != nil {
+			return err
+		} else if inProgress {
+			err = scm.RebaseAbort()
+		} else if current, err2 := scm.CurrentBranchName(); err2 != nil || current != state.Branch {
+			err = fmt.Errorf("branch %q is not checked out anymore", state.Branch)
+		} else {
+			err = scm.Reset(p.Revision, gitutil.ModeOpt("keep"))
+		}
+		if err != nil {
+			failures++
+			fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("Error while aborting: %s\n", err))
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "%s\n", jirix.Color.Green("Restored %s to %s", state.Branch, shortRev(p.Revision)))
+	}
+	if failures != 0 {
+		return fmt.Errorf("failed to restore %d project(s)", failures)
+	}
+	if err := os.Remove(rebaseStateFile(jirix)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
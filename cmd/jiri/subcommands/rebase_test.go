@@ -0,0 +1,121 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+func TestRebase(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	fake.X.Cwd = fake.X.Root
+	branch := "topic"
+	create := branchCmd{create: branch, projects: "project-[01]$"}
+	if err := create.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	gits := make([]*gitutil.Git, 2)
+	revs := make([]string, 2)
+	for i := range gits {
+		configureUser(t, fake.X, localProjects[i].Path)
+		gits[i] = gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[i].Path), gitutil.UserNameOpt("John Doe"), gitutil.UserEmailOpt("john.doe@example.com"))
+	}
+	commitFile(t, gits[0], "a", "topic")
+	commitFile(t, gits[1], "README", "topic change")
+	for i := range gits {
+		rev, err := gits[i].CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		revs[i] = rev
+	}
+	// Advance JIRI_HEAD, with a conflicting change in project 1.
+	writeFile(t, fake.X, fake.Projects[localProjects[0].Name], "b", "remote")
+	writeReadme(t, fake.X, fake.Projects[localProjects[1].Name], "remote change")
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	fake.X.Cwd = localProjects[0].Path
+
+	cmd := &rebaseCmd{onto: "JIRI_HEAD"}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Fatalf("expected the rebase to stop on conflicts")
+	}
+	state, err := readRebaseState(fake.X)
+	if err != nil || state == nil {
+		t.Fatalf("expected the rebase state to be recorded, got %v, %v", state, err)
+	}
+	if conflicts := state.conflicts(); len(conflicts) != 1 || conflicts[0] != localProjects[1].Name+"(path-1)" {
+		t.Errorf("unexpected conflicts %v", conflicts)
+	}
+	if _, err := os.Stat(filepath.Join(localProjects[0].Path, "b")); err != nil {
+		t.Errorf("expected project 0 to be rebased: %v", err)
+	}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Errorf("expected a new rebase to fail while one is in progress")
+	}
+
+	// Aborting restores all the projects.
+	cmd = &rebaseCmd{abort: true}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range gits {
+		if rev, err := gits[i].CurrentRevision(); err != nil || rev != revs[i] {
+			t.Errorf("project %d: got revision %s, want %s, %v", i, rev, revs[i], err)
+		}
+		if inProgress, err := gits[i].IsRebaseInProgress(); err != nil || inProgress {
+			t.Errorf("project %d: expected no rebase in progress, got %v, %v", i, inProgress, err)
+		}
+	}
+	if _, err := os.Stat(rebaseStateFile(fake.X)); !os.IsNotExist(err) {
+		t.Errorf("expected the rebase state to be removed, got %v", err)
+	}
+
+	// Continuing once the conflicts are resolved completes the rebase.
+	cmd = &rebaseCmd{onto: "JIRI_HEAD"}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Fatalf("expected the rebase to stop on conflicts")
+	}
+	cmd = &rebaseCmd{continueOpt: true}
+	if err := cmd.run(fake.X, nil); err == nil {
+		t.Errorf("expected continuing with unresolved conflicts to fail")
+	}
+	if err := os.WriteFile(filepath.Join(localProjects[1].Path, "README"), []byte("resolved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := gits[1].Add("README"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(rebaseStateFile(fake.X)); !os.IsNotExist(err) {
+		t.Errorf("expected the rebase state to be removed, got %v", err)
+	}
+	for i := range gits {
+		head, err := gits[i].CurrentRevisionForRef("JIRI_HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parent, err := gits[i].CurrentRevisionForRef("HEAD^"); err != nil || parent != head {
+			t.Errorf("project %d: expected the topic branch on top of JIRI_HEAD %s, got %s, %v", i, head, parent, err)
+		}
+		if current, err := gits[i].CurrentBranchName(); err != nil || current != branch {
+			t.Errorf("project %d: expected branch %q, got %q, %v", i, branch, current, err)
+		}
+	}
+}
@@ -6,7 +6,10 @@ package subcommands
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/google/subcommands"
@@ -24,6 +27,11 @@ type resolveCmd struct {
 	enablePackageVersion  bool
 	allowFloatingRefs     bool
 	fullResolve           bool
+	recordResolveTime     bool
+	migrate               bool
+	check                 bool
+	auditPins             bool
+	allowedRefs           arrayFlag
 	hostnameAllowList     string
 	localManifestProjects arrayFlag
 }
@@ -69,6 +77,10 @@ func (c *resolveCmd) FullResolve() bool {
 	return c.fullResolve
 }
 
+func (c *resolveCmd) RecordResolveTime() bool {
+	return c.recordResolveTime
+}
+
 func (c *resolveCmd) Name() string     { return "resolve" }
 func (c *resolveCmd) Synopsis() string { return "Generate jiri lockfile" }
 func (c *resolveCmd) Usage() string {
@@ -79,6 +91,25 @@ Usage:
   jiri resolve [flags] <manifest ...>
 
 <manifest ...> is a list of manifest files for lockfile generation
+
+The lockfile is written in version 1 of the lockfile format unless the
+"lockfile>version" setting of the jiri config is 2. Version 2 lockfiles key
+package locks by their package name, path and platform and record the
+manifest that requested each entry, and optionally the time it was resolved.
+With -migrate, an existing lockfile is converted to version 2, reusing the
+locks it contains.
+
+With -check, nothing is written. Instead the computed locks are compared
+with the ones recorded in the lockfile set by -output and in each of the
+lockfiles next to the manifests, and jiri exits with an error listing every
+missing, extra and mismatched lock of the lockfiles that are out of sync.
+
+With -audit-pins, nothing is written either. Instead every revision projects
+are pinned to by the manifests or the lockfiles is checked, using the git
+cache, to be reachable from the remote branch of its project or from a ref
+matching one of the -allow-ref patterns, e.g. "refs/heads/releases/*" or
+"refs/tags/". Pins which are not, and which may therefore be garbage
+collected by the remote, are reported as orphaned.
 `
 }
 
@@ -91,6 +122,11 @@ func (c *resolveCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.hostnameAllowList, "allow-hosts", "", "List of hostnames that can be used in the url of a repository, separated by comma. It will not be enforced if it is left empty.")
 	f.BoolVar(&c.fullResolve, "full-resolve", false, "Resolve all project and packages, not just those are changed.")
 	f.Var(&c.localManifestProjects, "local-manifest-project", "Import projects whose local manifests should be respected. Repeatable.")
+	f.BoolVar(&c.recordResolveTime, "record-time", false, "Record the time new locks are resolved at in version 2 lockfiles.")
+	f.BoolVar(&c.migrate, "migrate", false, "Convert the existing lockfile to version 2 of the lockfile format.")
+	f.BoolVar(&c.check, "check", false, "Check that the lockfiles are in sync with the manifests instead of writing them.")
+	f.BoolVar(&c.auditPins, "audit-pins", false, "Report pinned revisions which are not reachable from the remote branch of their project or an allowed ref instead of writing lockfiles.")
+	f.Var(&c.allowedRefs, "allow-ref", "Ref pattern, such as \"refs/tags/\", pinned revisions may be reachable from instead of the remote branch of their project. Repeatable.")
 }
 
 func (c *resolveCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -105,6 +141,28 @@ func (c *resolveCmd) run(jirix *jiri.X, args []string) error {
 	} else {
 		manifestFiles = append(manifestFiles, args...)
 	}
+	if c.migrate && (c.check || c.auditPins) {
+		return jirix.UsageErrorf("-migrate cannot be used with -check or -audit-pins")
+	}
+	if c.migrate {
+		if c.fullResolve {
+			return jirix.UsageErrorf("-migrate cannot be used with -full-resolve")
+		}
+		if _, err := os.Stat(c.lockFilePath); err != nil {
+			return fmt.Errorf("cannot migrate lockfile: %v", err)
+		}
+		jirix.LockfileVersion = project.LatestLockFileVersion
+	} else if data, err := os.ReadFile(c.lockFilePath); err == nil {
+		// Keep the version of an existing lockfile, which may have been
+		// migrated to a newer version than the configured one.
+		version, err := project.LockFileVersion(data)
+		if err != nil {
+			return err
+		}
+		if version > jirix.LockfileVersion {
+			jirix.LockfileVersion = version
+		}
+	}
 	if c.localManifestFlag && len(c.localManifestProjects) == 0 {
 		var err error
 		c.localManifestProjects, err = getDefaultLocalManifestProjects(jirix)
@@ -121,5 +179,98 @@ func (c *resolveCmd) run(jirix *jiri.X, args []string) error {
 	// Jiri will halt when detecting conflicts in locks. So to make it work,
 	// we need to temporarily disable the conflicts detection.
 	jirix.IgnoreLockConflicts = true
+	if c.check || c.auditPins {
+		var errs []error
+		if c.check {
+			errs = append(errs, c.runCheck(jirix, manifestFiles))
+		}
+		if c.auditPins {
+			orphans, err := project.AuditPins(jirix, manifestFiles, c.localManifestProjects, c.lockFilePath, c.allowedRefs)
+			if err == nil {
+				err = reportOrphanedPins(jirix, orphans)
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
 	return project.GenerateJiriLockFile(jirix, manifestFiles, c)
 }
+
+// reportOrphanedPins prints orphans and returns an error if there are any.
+func reportOrphanedPins(jirix *jiri.X, orphans []project.OrphanedPin) error {
+	if len(orphans) == 0 {
+		jirix.Logger.Infof("All pinned revisions are reachable\n")
+		return nil
+	}
+	w := jirix.Stdout()
+	fmt.Fprintf(w, "Found %d orphaned pins:\n", len(orphans))
+	for _, o := range orphans {
+		fmt.Fprintf(w, "  %s\n", o)
+	}
+	return fmt.Errorf("pinned revisions are not reachable from the remote branches of their projects, pin them to merged revisions")
+}
+
+func (c *resolveCmd) runCheck(jirix *jiri.X, manifestFiles []string) error {
+	diffs, err := project.CheckJiriLockFiles(jirix, manifestFiles, c)
+	if err != nil {
+		return err
+	}
+	var lockFiles, staleLockFiles []string
+	for _, d := range diffs {
+		lockFiles = append(lockFiles, d.LockFile)
+		if !d.Empty() {
+			staleLockFiles = append(staleLockFiles, d.LockFile)
+		}
+	}
+	if len(staleLockFiles) == 0 {
+		jirix.Logger.Infof("Lockfiles %s are in sync with manifests\n", strings.Join(lockFiles, ", "))
+		return nil
+	}
+	w := jirix.Stdout()
+	fmt.Fprintf(w, "Lockfiles %s are out of sync with manifests:\n", strings.Join(staleLockFiles, ", "))
+	for _, d := range diffs {
+		for _, v := range d.MissingProjects {
+			fmt.Fprintf(w, "  %s: missing project %s\n", d.LockFile, formatProjectLock(v))
+		}
+		for _, v := range d.ExtraProjects {
+			fmt.Fprintf(w, "  %s: extra project %s\n", d.LockFile, formatProjectLock(v))
+		}
+		for _, v := range d.MismatchedProjects {
+			fmt.Fprintf(w, "  %s: mismatched project %s(remote: %s): locked to %s, resolved to %s\n", d.LockFile, v.Computed.Name, v.Computed.Remote, v.Recorded.Revision, v.Computed.Revision)
+		}
+		for _, v := range d.MissingPackages {
+			fmt.Fprintf(w, "  %s: missing package %s\n", d.LockFile, formatPackageLock(v))
+		}
+		for _, v := range d.ExtraPackages {
+			fmt.Fprintf(w, "  %s: extra package %s\n", d.LockFile, formatPackageLock(v))
+		}
+		for _, v := range d.MismatchedPackages {
+			fmt.Fprintf(w, "  %s: mismatched package %s: locked to %s, resolved to %s\n", d.LockFile, formatPackageKey(v.Computed), packageLockID(v.Recorded), packageLockID(v.Computed))
+		}
+	}
+	return fmt.Errorf("lockfiles are out of sync with manifests, run \"jiri resolve\" to update them")
+}
+
+func formatProjectLock(l project.ProjectLock) string {
+	return fmt.Sprintf("%s(remote: %s) at %s", l.Name, l.Remote, l.Revision)
+}
+
+func formatPackageKey(l project.PackageLock) string {
+	s := fmt.Sprintf("%s@%s", l.PackageName, l.VersionTag)
+	if l.LocalPath != "" {
+		s += fmt.Sprintf(" (path: %s)", l.LocalPath)
+	}
+	return s
+}
+
+func formatPackageLock(l project.PackageLock) string {
+	return fmt.Sprintf("%s: %s", formatPackageKey(l), packageLockID(l))
+}
+
+// packageLockID returns the identifier of the instance locked by l.
+func packageLockID(l project.PackageLock) string {
+	if l.Digest != "" && l.Digest != l.InstanceID {
+		return l.InstanceID + " (" + l.Digest + ")"
+	}
+	return l.InstanceID
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/jiritest"
 	"go.fuchsia.dev/jiri/project"
 )
@@ -58,6 +59,37 @@ func TestResolveProjects(t *testing.T) {
 	}
 }
 
+func TestResolveMigrate(t *testing.T) {
+	t.Parallel()
+
+	_, fakeroot := setupUniverse(t)
+	if err := fakeroot.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(fakeroot.X.Root, "jiri.lock")
+	cmd := resolveCmd{lockFilePath: lockPath, enableProjectLock: true}
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	cmd.migrate = true
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A later resolve keeps the migrated version.
+	fakeroot.X.LockfileVersion = 1
+	cmd.migrate = false
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := project.LockFileVersion(data); err != nil || version != project.LatestLockFileVersion {
+		t.Errorf("got lockfile version %d, %v, want %d", version, err, project.LatestLockFileVersion)
+	}
+}
+
 func TestResolvePackages(t *testing.T) {
 	t.Parallel()
 
@@ -241,3 +273,276 @@ func TestResolvePackagesPartial(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveCheck(t *testing.T) {
+	t.Parallel()
+
+	_, fakeroot := setupUniverse(t)
+
+	if err := fakeroot.UpdateUniverse(false); err != nil {
+		t.Fatalf("%v", err)
+	}
+	lockPath := filepath.Join(fakeroot.X.Root, "jiri.lock")
+	cmd := resolveCmd{
+		lockFilePath:      lockPath,
+		enablePackageLock: true,
+		enableProjectLock: true,
+	}
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Fatalf("resolve failed due to error %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.check = true
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Errorf("expected lockfile to be in sync, got error %v", err)
+	}
+
+	// Drop one lock and change another.
+	projLocks, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projLocks) < 2 {
+		t.Fatalf("expected at least 2 project locks, got %v", projLocks)
+	}
+	var changed, dropped project.ProjectLock
+	i := 0
+	for k, v := range projLocks {
+		if i == 0 {
+			dropped = v
+			delete(projLocks, k)
+		} else if i == 1 {
+			changed = v
+			v.Revision = "0000000000000000000000000000000000000000"
+			projLocks[k] = v
+		}
+		i++
+	}
+	if data, err = project.MarshalLockEntries(projLocks, pkgLocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.run(fakeroot.X, nil); err == nil {
+		t.Fatalf("expected out of sync lockfile to be reported")
+	}
+	if got, err := os.ReadFile(lockPath); err != nil || string(got) != string(data) {
+		t.Errorf("lockfile was modified by check")
+	}
+	diffs, err := project.CheckJiriLockFiles(fakeroot.X, []string{fakeroot.X.JiriManifestFile()}, &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].LockFile != lockPath {
+		t.Fatalf("expected only %q to be checked, got %+v", lockPath, diffs)
+	}
+	diff := diffs[0]
+	if len(diff.MissingProjects) != 1 || diff.MissingProjects[0].Name != dropped.Name {
+		t.Errorf("expected project %q to be missing, got %+v", dropped.Name, diff.MissingProjects)
+	}
+	if len(diff.MismatchedProjects) != 1 || diff.MismatchedProjects[0].Computed.Revision != changed.Revision {
+		t.Errorf("expected project %q to mismatch, got %+v", changed.Name, diff.MismatchedProjects)
+	}
+	if len(diff.ExtraProjects) != 0 {
+		t.Errorf("unexpected extra projects %+v", diff.ExtraProjects)
+	}
+}
+
+func TestResolveCheckStaleManifestLockFile(t *testing.T) {
+	t.Parallel()
+
+	_, fakeroot := setupUniverse(t)
+	if err := fakeroot.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	fakeroot.X.LockfileName = "jiri.lock"
+	rootLockPath := filepath.Join(fakeroot.X.Root, "root.lock")
+	manifestLockPath := filepath.Join(filepath.Dir(fakeroot.X.JiriManifestFile()), fakeroot.X.LockfileName)
+	cmd := resolveCmd{
+		lockFilePath:      rootLockPath,
+		enablePackageLock: true,
+		enableProjectLock: true,
+	}
+	if err := cmd.run(fakeroot.X, nil); err != nil {
+		t.Fatalf("resolve failed due to error %v", err)
+	}
+	data, err := os.ReadFile(rootLockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the root lockfile fresh and make the one next to the manifest stale.
+	projLocks, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed project.ProjectLock
+	for k, v := range projLocks {
+		changed = v
+		v.Revision = "0000000000000000000000000000000000000000"
+		projLocks[k] = v
+		break
+	}
+	if data, err = project.MarshalLockEntries(projLocks, pkgLocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifestLockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.check = true
+	if err := cmd.run(fakeroot.X, nil); err == nil {
+		t.Fatalf("expected stale lockfile %q to be reported", manifestLockPath)
+	}
+	diffs, err := project.CheckJiriLockFiles(fakeroot.X, []string{fakeroot.X.JiriManifestFile()}, &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("expected 2 checked lockfiles, got %+v", diffs)
+	}
+	for _, d := range diffs {
+		switch d.LockFile {
+		case rootLockPath:
+			if !d.Empty() {
+				t.Errorf("expected %q to be in sync, got %+v", d.LockFile, d.LockDiff)
+			}
+		case manifestLockPath:
+			if len(d.MismatchedProjects) != 1 || d.MismatchedProjects[0].Computed.Revision != changed.Revision {
+				t.Errorf("expected project %q to mismatch in %q, got %+v", changed.Name, d.LockFile, d.LockDiff)
+			}
+		default:
+			t.Errorf("unexpected lockfile %q checked", d.LockFile)
+		}
+	}
+}
+
+func TestResolveAuditPins(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fakeroot := setupUniverse(t)
+	if err := fakeroot.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	fakeroot.X.Cache = t.TempDir()
+
+	// Commits a change to a new branch of the remote of p and returns its
+	// revision.
+	commitOnBranch := func(p project.Project, branch string) string {
+		remote := fakeroot.Projects[p.Name]
+		scm := gitutil.New(fakeroot.X, gitutil.RootDirOpt(remote))
+		if err := scm.CreateAndCheckoutBranch(branch); err != nil {
+			t.Fatal(err)
+		}
+		writeReadme(t, fakeroot.X, remote, "change on "+branch)
+		rev, err := scm.CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := scm.Checkout("main"); err != nil {
+			t.Fatal(err)
+		}
+		return rev
+	}
+	deleted, release, merged := localProjects[0], localProjects[1], localProjects[2]
+	deletedRev := commitOnBranch(deleted, "feature")
+	if err := gitutil.New(fakeroot.X, gitutil.RootDirOpt(fakeroot.Projects[deleted.Name])).DeleteBranch("feature", gitutil.ForceOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	releaseRev := commitOnBranch(release, "releases/1")
+	mergedRev, err := gitutil.New(fakeroot.X, gitutil.RootDirOpt(fakeroot.Projects[merged.Name])).CurrentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lockPath := filepath.Join(fakeroot.X.Root, "jiri.lock")
+	projectLocks := project.ProjectLocks{}
+	for _, v := range []struct {
+		p   project.Project
+		rev string
+	}{{deleted, deletedRev}, {release, releaseRev}, {merged, mergedRev}} {
+		lock := project.ProjectLock{Remote: v.p.Remote, Name: v.p.Name, Revision: v.rev}
+		projectLocks[lock.Key()] = lock
+	}
+	data, err := project.MarshalLockEntries(projectLocks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifestFiles := []string{fakeroot.X.JiriManifestFile()}
+	orphans, err := project.AuditPins(fakeroot.X, manifestFiles, nil, lockPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 2 {
+		t.Fatalf("expected 2 orphaned pins, got %+v", orphans)
+	}
+	for _, o := range orphans {
+		switch o.Name {
+		case deleted.Name:
+			if o.Revision != deletedRev || o.Source != lockPath {
+				t.Errorf("unexpected orphaned pin %+v", o)
+			}
+		case release.Name:
+			if o.Revision != releaseRev || o.Missing {
+				t.Errorf("unexpected orphaned pin %+v", o)
+			}
+		default:
+			t.Errorf("unexpected orphaned pin %+v", o)
+		}
+	}
+
+	cmd := resolveCmd{
+		lockFilePath: lockPath,
+		auditPins:    true,
+		allowedRefs:  arrayFlag{"refs/heads/releases/"},
+	}
+	if err := cmd.run(fakeroot.X, nil); err == nil {
+		t.Fatalf("expected orphaned pin to be reported")
+	}
+	if got, err := os.ReadFile(lockPath); err != nil || string(got) != string(data) {
+		t.Errorf("lockfile was modified by -audit-pins")
+	}
+	if orphans, err = project.AuditPins(fakeroot.X, manifestFiles, nil, lockPath, cmd.allowedRefs); err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0].Name != deleted.Name {
+		t.Errorf("expected only %q to be orphaned, got %+v", deleted.Name, orphans)
+	}
+
+	// Replacing the release branch with a tag is seen even though the cache
+	// already has the branch.
+	scm := gitutil.New(fakeroot.X, gitutil.RootDirOpt(fakeroot.Projects[release.Name]))
+	if err := scm.Checkout("releases/1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.CreateLightweightTag("release-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.Checkout("main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.DeleteBranch("releases/1", gitutil.ForceOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	if orphans, err = project.AuditPins(fakeroot.X, manifestFiles, nil, lockPath, cmd.allowedRefs); err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 2 {
+		t.Errorf("expected 2 orphaned pins after deleting the release branch, got %+v", orphans)
+	}
+	if orphans, err = project.AuditPins(fakeroot.X, manifestFiles, nil, lockPath, []string{"refs/tags/"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0].Name != deleted.Name {
+		t.Errorf("expected only %q to be orphaned, got %+v", deleted.Name, orphans)
+	}
+}
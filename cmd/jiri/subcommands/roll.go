@@ -0,0 +1,360 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type rollCmd struct {
+	cmdBase
+
+	projects   arrayFlag
+	imports    arrayFlag
+	regexp     bool
+	attributes string
+	editMode   string
+	cls        bool
+	jsonOutput string
+}
+
+func (c *rollCmd) Name() string     { return "roll" }
+func (c *rollCmd) Synopsis() string { return "Roll projects and imports to their remote branches" }
+func (c *rollCmd) Usage() string {
+	return `Roll the revisions of the selected projects and imports in <manifest> to
+the tips of their remote branches, and print a commit message listing the
+commits that were rolled.
+
+Usage:
+  jiri roll [flags] <manifest>
+
+<manifest> is path of the manifest
+
+Projects are selected by name using -project or by attribute using
+-attributes, imports by name using -import. With -regexp, the names are
+regular expressions. The revisions are updated in the same way as "jiri edit"
+does, according to -edit-mode. The commits between the old and new revisions
+are listed using the local checkouts of the projects, which are fetched
+first; projects that are not checked out are rolled without listing their
+commits.
+
+With -json-output, the rolled projects, their commits and the Gerrit CLs of
+these commits are written in json format.
+`
+}
+
+func (c *rollCmd) SetFlags(f *flag.FlagSet) {
+	f.Var(&c.projects, "project", "Name of a project to roll. It can be specified multiple times.")
+	f.Var(&c.imports, "import", "Name of an import to roll. It can be specified multiple times.")
+	f.BoolVar(&c.regexp, "regexp", false, "Use the names given to -project and -import as regular expressions.")
+	f.StringVar(&c.attributes, "attributes", "", "Roll the projects with any of these attributes, separated by comma.")
+	f.StringVar(&c.editMode, "edit-mode", both, "Edit mode. It can be 'manifest' for updating project revisions in manifest only, 'lockfile' for updating project revisions in lockfile only or 'both' for updating project revisions in both files.")
+	f.BoolVar(&c.cls, "cls", true, "Look up the Gerrit CLs of the rolled commits.")
+	f.StringVar(&c.jsonOutput, "json-output", "", "File to print the rolled projects to, in json format.")
+}
+
+func (c *rollCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+type rolledCommit struct {
+	Revision string `json:"revision"`
+	Author   string `json:"author"`
+	Subject  string `json:"subject"`
+}
+
+type rolledProject struct {
+	Name       string         `json:"name"`
+	Remote     string         `json:"remote"`
+	Type       string         `json:"type"`
+	OldRev     string         `json:"old_revision"`
+	NewRev     string         `json:"new_revision"`
+	NumCommits int            `json:"num_commits"`
+	Commits    []rolledCommit `json:"commits,omitempty"`
+	Cls        []DiffCl       `json:"cls,omitempty"`
+	Error      string         `json:"error,omitempty"`
+
+	gerritHost string
+}
+
+type rollResult struct {
+	Projects      []rolledProject `json:"projects"`
+	CommitMessage string          `json:"commit_message"`
+}
+
+// nameMatcher returns a function reporting whether a name matches one of
+// names, which are regular expressions if useRegexp is set.
+func nameMatcher(names []string, useRegexp bool) (func(string) bool, error) {
+	var regexps []*regexp.Regexp
+	for _, name := range names {
+		if !useRegexp {
+			name = "^" + regexp.QuoteMeta(name) + "$"
+		}
+		re, err := regexp.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile regexp %v: %v", name, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return func(s string) bool {
+		for _, re := range regexps {
+			if re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// splitAttributes returns the attributes in the comma separated list attrs.
+func splitAttributes(attrs string) map[string]bool {
+	ret := make(map[string]bool)
+	for _, attr := range strings.Split(attrs, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			ret[attr] = true
+		}
+	}
+	return ret
+}
+
+// hasAnyAttribute returns true if the comma separated list attrs contains
+// any of the attributes in want.
+func hasAnyAttribute(attrs string, want map[string]bool) bool {
+	for attr := range splitAttributes(attrs) {
+		if want[attr] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *rollCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) != 1 {
+		return jirix.UsageErrorf("Wrong number of args")
+	}
+	c.editMode = strings.ToLower(c.editMode)
+	if c.editMode != manifest && c.editMode != lockfile && c.editMode != both {
+		return fmt.Errorf("unsupported edit-mode: %q", c.editMode)
+	}
+	if len(c.projects) == 0 && len(c.imports) == 0 && c.attributes == "" {
+		return jirix.UsageErrorf("Please provide -project, -import and/or -attributes flag")
+	}
+	manifestPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	matchProject, err := nameMatcher(c.projects, c.regexp)
+	if err != nil {
+		return err
+	}
+	matchImport, err := nameMatcher(c.imports, c.regexp)
+	if err != nil {
+		return err
+	}
+	attrs := splitAttributes(c.attributes)
+
+	m, err := project.ManifestFromFile(jirix, manifestPath)
+	if err != nil {
+		return err
+	}
+	lockedRevs, err := lockedRevisions(jirix, manifestPath)
+	if err != nil {
+		return err
+	}
+	// oldRevision returns the revision a project is currently pinned to by
+	// the files edited in the current edit mode.
+	oldRevision := func(key project.ProjectKey, revision string) string {
+		if rev, ok := lockedRevs[project.ProjectLockKey(key).String()]; ok && (c.editMode != manifest || revision == "") {
+			return rev
+		}
+		return revision
+	}
+
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(filepath.Dir(manifestPath)))
+	var rolled []rolledProject
+	projectRevs := make(map[string]string)
+	importRevs := make(map[string]string)
+	for _, p := range m.Projects {
+		if !matchProject(p.Name) && !hasAnyAttribute(p.Attributes, attrs) {
+			continue
+		}
+		newRev, err := remoteBranchTip(scm, p.Remote, p.RemoteBranch)
+		if err != nil {
+			return err
+		}
+		oldRev := oldRevision(p.Key(), p.Revision)
+		if oldRev == newRev {
+			jirix.Logger.Infof("Project %s is already at %s\n", p.Name, newRev)
+			continue
+		}
+		projectRevs[p.Name] = newRev
+		rolled = append(rolled, rolledProject{
+			Name:       p.Name,
+			Remote:     p.Remote,
+			Type:       "project",
+			OldRev:     oldRev,
+			NewRev:     newRev,
+			gerritHost: p.GerritHost,
+		})
+	}
+	for _, i := range m.Imports {
+		if !matchImport(i.Name) {
+			continue
+		}
+		newRev, err := remoteBranchTip(scm, i.Remote, i.RemoteBranch)
+		if err != nil {
+			return err
+		}
+		if i.Revision == newRev {
+			jirix.Logger.Infof("Import %s is already at %s\n", i.Name, newRev)
+			continue
+		}
+		importRevs[i.Name] = newRev
+		rolled = append(rolled, rolledProject{
+			Name:   i.Name,
+			Remote: i.Remote,
+			Type:   "import",
+			OldRev: i.Revision,
+			NewRev: newRev,
+		})
+	}
+	if len(rolled) == 0 {
+		jirix.Logger.Infof("Nothing to roll\n")
+		return nil
+	}
+
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	for i := range rolled {
+		c.listCommits(jirix, localProjects, &rolled[i])
+	}
+
+	ec := editCmd{editMode: c.editMode}
+	if _, err := ec.updateManifest(jirix, manifestPath, projectRevs, importRevs, nil); err != nil {
+		return err
+	}
+
+	result := rollResult{Projects: rolled, CommitMessage: rollCommitMessage(rolled)}
+	fmt.Fprint(jirix.Stdout(), result.CommitMessage)
+	if c.jsonOutput != "" {
+		return writeJSONOutput(c.jsonOutput, result)
+	}
+	return nil
+}
+
+// listCommits records the commits between the old and new revisions of r,
+// and their CLs, using the local checkout of r. Failures are recorded in
+// r.Error.
+func (c *rollCmd) listCommits(jirix *jiri.X, localProjects project.Projects, r *rolledProject) {
+	key := project.MakeProjectKey(r.Name, r.Remote)
+	local, ok := localProjects[key]
+	if !ok {
+		r.Error = "project is not checked out locally"
+		jirix.Logger.Warningf("Not listing commits of %s: %s\n", r.Name, r.Error)
+		return
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+	if err := scm.FetchRefspec(r.Remote, r.NewRev); err != nil {
+		r.Error = fmt.Sprintf("failed to fetch %s: %s", r.NewRev, err)
+		jirix.Logger.Warningf("Not listing commits of %s: %s\n", r.Name, r.Error)
+		return
+	}
+	base := r.OldRev
+	if base == "" || base == "HEAD" {
+		r.Error = "project is not pinned"
+		return
+	}
+	entries, err := scm.Log(r.NewRev, base, "%H%n%an%n%s")
+	if err != nil {
+		r.Error = fmt.Sprintf("failed to list commits: %s", err)
+		jirix.Logger.Warningf("Not listing commits of %s: %s\n", r.Name, r.Error)
+		return
+	}
+	for _, e := range entries {
+		if len(e) < 3 {
+			continue
+		}
+		r.Commits = append(r.Commits, rolledCommit{Revision: e[0], Author: e[1], Subject: e[2]})
+	}
+	r.NumCommits = len(r.Commits)
+
+	if !c.cls || r.gerritHost == "" {
+		return
+	}
+	hostURL, err := url.Parse(r.gerritHost)
+	if err != nil {
+		r.Error = fmt.Sprintf("invalid gerrit host %q: %s", r.gerritHost, err)
+		return
+	}
+	g := gerrit.New(jirix, hostURL)
+	for _, commit := range r.Commits {
+		cls, err := g.ListChangesByCommit(commit.Revision)
+		if err != nil {
+			r.Error = fmt.Sprintf("not able to get CL for revision %s: %s", commit.Revision, err)
+			return
+		}
+		for _, cl := range cls {
+			r.Cls = append(r.Cls, DiffCl{
+				Commit:  commit.Revision,
+				Number:  cl.Number,
+				Subject: cl.Subject,
+				URL:     fmt.Sprintf("%s/c/%d", strings.TrimSuffix(r.gerritHost, "/"), cl.Number),
+			})
+		}
+	}
+}
+
+func shortRev(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
+
+// rollCommitMessage returns a commit message describing the rolled projects.
+func rollCommitMessage(rolled []rolledProject) string {
+	var b strings.Builder
+	if len(rolled) == 1 {
+		r := rolled[0]
+		fmt.Fprintf(&b, "[roll] Roll %s %s..%s", r.Name, shortRev(r.OldRev), shortRev(r.NewRev))
+		if r.NumCommits != 0 {
+			fmt.Fprintf(&b, " (%d commits)", r.NumCommits)
+		}
+		b.WriteString("\n")
+	} else {
+		names := make([]string, 0, len(rolled))
+		for _, r := range rolled {
+			names = append(names, r.Name)
+		}
+		fmt.Fprintf(&b, "[roll] Roll %s\n", strings.Join(names, ", "))
+	}
+	for _, r := range rolled {
+		b.WriteString("\n")
+		if len(rolled) != 1 {
+			fmt.Fprintf(&b, "%s %s..%s\n", r.Name, shortRev(r.OldRev), shortRev(r.NewRev))
+		}
+		for _, commit := range r.Commits {
+			fmt.Fprintf(&b, "%s %s\n", shortRev(commit.Revision), commit.Subject)
+		}
+		if len(r.Commits) == 0 {
+			fmt.Fprintf(&b, "%s %s..%s\n", r.Remote, r.OldRev, r.NewRev)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+func TestRoll(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	revision := func(name string) string {
+		rev, err := gitutil.New(fake.X, gitutil.RootDirOpt(fake.Projects[name])).CurrentRevisionOfBranch("HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rev
+	}
+
+	// Pin two projects in a manifest with a lockfile next to it.
+	fake.X.LockfileName = "jiri.lock"
+	dir := filepath.Join(fake.X.Root, "manifests")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, "manifest")
+	lockPath := filepath.Join(dir, fake.X.LockfileName)
+	m := project.Manifest{}
+	projectLocks := project.ProjectLocks{}
+	oldRevs := make(map[string]string)
+	for _, p := range localProjects[:2] {
+		p.Revision = revision(p.Name)
+		p.Path = filepath.Base(p.Path)
+		oldRevs[p.Name] = p.Revision
+		m.Projects = append(m.Projects, p)
+		lock := project.ProjectLock{Remote: p.Remote, Name: p.Name, Revision: p.Revision}
+		projectLocks[lock.Key()] = lock
+	}
+	if err := m.ToFile(fake.X, manifestPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := project.MarshalLockEntries(projectLocks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rolledName, otherName := localProjects[0].Name, localProjects[1].Name
+	writeReadme(t, fake.X, fake.Projects[rolledName], "first change")
+	writeReadme(t, fake.X, fake.Projects[rolledName], "second change")
+	newRev := revision(rolledName)
+
+	jsonOutput := filepath.Join(t.TempDir(), "roll.json")
+	cmd := rollCmd{
+		projects:   arrayFlag{rolledName},
+		editMode:   both,
+		jsonOutput: jsonOutput,
+	}
+	if err := cmd.run(fake.X, []string{manifestPath}); err != nil {
+		t.Fatalf("roll failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), newRev) || !strings.Contains(string(content), oldRevs[otherName]) {
+		t.Errorf("unexpected manifest after roll:\n%s", content)
+	}
+	if data, err = os.ReadFile(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if projectLocks, _, err = project.UnmarshalLockEntries(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range projectLocks {
+		want := oldRevs[v.Name]
+		if v.Name == rolledName {
+			want = newRev
+		}
+		if v.Revision != want {
+			t.Errorf("project %q is locked to %q, want %q", v.Name, v.Revision, want)
+		}
+	}
+
+	if data, err = os.ReadFile(jsonOutput); err != nil {
+		t.Fatal(err)
+	}
+	var result rollResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Projects) != 1 {
+		t.Fatalf("expected 1 rolled project, got %+v", result.Projects)
+	}
+	r := result.Projects[0]
+	if r.Name != rolledName || r.OldRev != oldRevs[rolledName] || r.NewRev != newRev || r.NumCommits != 2 || r.Commits[0].Revision != newRev {
+		t.Errorf("unexpected rolled project %+v", r)
+	}
+	if !strings.HasPrefix(result.CommitMessage, "[roll] Roll "+rolledName+" ") || !strings.Contains(result.CommitMessage, "(2 commits)") {
+		t.Errorf("unexpected commit message:\n%s", result.CommitMessage)
+	}
+
+	// In lockfile mode, a stale lock is rolled even if the manifest is
+	// already at the new revision.
+	for k, v := range projectLocks {
+		v.Revision = oldRevs[v.Name]
+		projectLocks[k] = v
+	}
+	if data, err = project.MarshalLockEntries(projectLocks, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = rollCmd{projects: arrayFlag{rolledName}, editMode: lockfile}
+	if err := cmd.run(fake.X, []string{manifestPath}); err != nil {
+		t.Fatalf("roll failed: %v", err)
+	}
+	if data, err = os.ReadFile(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if projectLocks, _, err = project.UnmarshalLockEntries(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range projectLocks {
+		if v.Name == rolledName && v.Revision != newRev {
+			t.Errorf("project %q is locked to %q, want %q", v.Name, v.Revision, newRev)
+		}
+	}
+}
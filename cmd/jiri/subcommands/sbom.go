@@ -0,0 +1,100 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type sbomCmd struct {
+	cmdBase
+
+	format   string
+	snapshot string
+	name     string
+}
+
+func (c *sbomCmd) Name() string { return "sbom" }
+func (c *sbomCmd) Synopsis() string {
+	return "Generate a software bill of materials of the checkout"
+}
+func (c *sbomCmd) Usage() string {
+	return `Generates a software bill of materials (SBOM) listing the projects
+checked out under the jiri root and the packages installed in it, or the
+projects and package instances recorded in the snapshot set by -snapshot.
+
+Usage:
+  jiri sbom [flags] [<sbom>]
+
+<sbom> is the file the SBOM is written to. It is printed if no file is given.
+
+The -format flag selects "spdx-json" (SPDX 2.3) or "cyclonedx-json"
+(CycloneDX 1.5). Projects are identified by package urls of their
+repositories at their revisions and packages by package urls referring to
+their instances, such as cipd instance ids. Suppliers are derived from the
+hostnames of the remotes and licenses are taken from the "license" attribute
+of projects and packages in the manifest.
+
+The output is deterministic: the same projects and packages produce the same
+SBOM. The creation time recorded in the SBOM is read from the
+SOURCE_DATE_EPOCH environment variable and defaults to the Unix epoch.
+`
+}
+
+func (c *sbomCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.format, "format", project.SBOMFormatSPDX, "Format of the SBOM, \"spdx-json\" or \"cyclonedx-json\".")
+	f.StringVar(&c.snapshot, "snapshot", "", "Snapshot file to generate the SBOM from instead of the checkout.")
+	f.StringVar(&c.name, "name", "jiri-checkout", "Name of the SBOM document.")
+}
+
+func (c *sbomCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+func (c *sbomCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) > 1 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	}
+	if c.format != project.SBOMFormatSPDX && c.format != project.SBOMFormatCycloneDX {
+		return jirix.UsageErrorf("unsupported format %q", c.format)
+	}
+	created := time.Unix(0, 0)
+	if epoch := jirix.Context.Env()["SOURCE_DATE_EPOCH"]; epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %v", epoch, err)
+		}
+		created = time.Unix(secs, 0)
+	}
+
+	var sbom *project.SBOM
+	var err error
+	if c.snapshot != "" {
+		sbom, err = project.NewSnapshotSBOM(jirix, c.name, c.snapshot)
+	} else {
+		sbom, err = project.NewCheckoutSBOM(jirix, c.name)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := sbom.Marshal(c.format, created)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		_, err = jirix.Stdout().Write(data)
+		return err
+	}
+	return os.WriteFile(args[0], data, 0644)
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+func TestSBOMSnapshot(t *testing.T) {
+	t.Parallel()
+
+	_, fake := setupUniverse(t)
+	rev := "0123456789abcdef0123456789abcdef01234567"
+	snapshot := project.Manifest{
+		Projects: []project.Project{
+			{Name: "lib", Path: "third_party/lib", Remote: "https://github.com/Example/lib.git", Revision: rev, License: "MIT"},
+			{Name: "tool", Path: "tools/tool", Remote: "https://fuchsia.googlesource.com/tool", Revision: rev},
+		},
+		Packages: []project.Package{{
+			Name:      "fuchsia/clang/${platform}",
+			Version:   "git_revision:aaa",
+			Path:      "prebuilt/clang/{{.OS}}-{{.Arch}}",
+			Platforms: "linux-amd64,mac-amd64",
+			License:   "Apache-2.0",
+			Instances: []project.PackageInstance{
+				{Name: "fuchsia/clang/mac-amd64", ID: "mac-id"},
+				{Name: "fuchsia/clang/linux-amd64", ID: "linux-id"},
+			},
+		}},
+	}
+	dir := t.TempDir()
+	snapshotFile := filepath.Join(dir, "snapshot")
+	if err := snapshot.ToFile(fake.X, snapshotFile); err != nil {
+		t.Fatal(err)
+	}
+
+	generate := func(format string) []byte {
+		out := filepath.Join(dir, "sbom.json")
+		cmd := sbomCmd{format: format, snapshot: snapshotFile, name: "test"}
+		if err := cmd.run(fake.X, []string{out}); err != nil {
+			t.Fatalf("sbom failed: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	spdx := generate(project.SBOMFormatSPDX)
+	if again := generate(project.SBOMFormatSPDX); !bytes.Equal(spdx, again) {
+		t.Errorf("sbom is not deterministic:\n%s\n%s", spdx, again)
+	}
+	var doc struct {
+		Packages []struct {
+			Name             string
+			VersionInfo      string
+			Supplier         string
+			DownloadLocation string
+			LicenseDeclared  string
+			ExternalRefs     []struct{ ReferenceLocator string }
+		}
+	}
+	if err := json.Unmarshal(spdx, &doc); err != nil {
+		t.Fatal(err)
+	}
+	type pkg struct{ name, version, supplier, license, purl string }
+	var got []pkg
+	for _, p := range doc.Packages {
+		got = append(got, pkg{p.Name, p.VersionInfo, p.Supplier, p.LicenseDeclared, p.ExternalRefs[0].ReferenceLocator})
+	}
+	want := []pkg{
+		{"fuchsia/clang/linux-amd64", "linux-id", "Organization: chrome-infra-packages.appspot.com", "Apache-2.0",
+			"pkg:generic/fuchsia/clang/linux-amd64@linux-id?download_url=https://chrome-infra-packages.appspot.com/p/fuchsia/clang/linux-amd64/+/linux-id"},
+		{"fuchsia/clang/mac-amd64", "mac-id", "Organization: chrome-infra-packages.appspot.com", "Apache-2.0",
+			"pkg:generic/fuchsia/clang/mac-amd64@mac-id?download_url=https://chrome-infra-packages.appspot.com/p/fuchsia/clang/mac-amd64/+/mac-id"},
+		{"lib", rev, "Organization: Example", "MIT", "pkg:github/example/lib@" + rev},
+		{"tool", rev, "Organization: fuchsia", "NOASSERTION",
+			"pkg:generic/tool@" + rev + "?vcs_url=git+https://fuchsia.googlesource.com/tool@" + rev},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got packages %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got package %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	cyclonedx := generate(project.SBOMFormatCycloneDX)
+	if again := generate(project.SBOMFormatCycloneDX); !bytes.Equal(cyclonedx, again) {
+		t.Errorf("sbom is not deterministic:\n%s\n%s", cyclonedx, again)
+	}
+	var bom struct {
+		BOMFormat  string
+		Components []struct {
+			Name, Version, PURL string
+			Licenses            []struct{ Expression string }
+		}
+	}
+	if err := json.Unmarshal(cyclonedx, &bom); err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != "CycloneDX" || len(bom.Components) != len(want) {
+		t.Fatalf("unexpected bom %+v", bom)
+	}
+	for i, c := range bom.Components {
+		if c.PURL != want[i].purl || c.Version != want[i].version {
+			t.Errorf("got component %+v, want %+v", c, want[i])
+		}
+	}
+}
+
+func TestSBOMCheckout(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+	cmd := sbomCmd{format: project.SBOMFormatCycloneDX, name: "test"}
+	if err := cmd.run(fake.X, nil); err != nil {
+		t.Fatalf("sbom failed: %v", err)
+	}
+	for _, p := range localProjects {
+		rev, err := gitutil.New(fake.X, gitutil.RootDirOpt(p.Path)).CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "pkg:generic/" + p.Name + "@" + rev; !strings.Contains(stdout.String(), want) {
+			t.Errorf("sbom does not contain %q:\n%s", want, stdout.String())
+		}
+	}
+}
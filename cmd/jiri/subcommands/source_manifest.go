@@ -15,6 +15,10 @@ import (
 
 type sourceManifestCmd struct {
 	cmdBase
+
+	packages                 bool
+	packageAttributes        string
+	excludePackageAttributes string
 }
 
 func (c *sourceManifestCmd) Name() string { return "source-manifest" }
@@ -25,13 +29,23 @@ func (c *sourceManifestCmd) Usage() string {
 	return `This command captures the current project state in a source-manifest format.
 
 Usage:
-  jiri source-manifest <source-manifest>
+  jiri source-manifest [flags] <source-manifest>
 
 <source-manifest> is the source-manifest file.
+
+With -packages, the cipd packages of the manifest are also recorded with the
+instances locked by the lockfiles, under the directories they are installed
+to. Optional packages are included if they have any of the attributes jiri
+fetches or any of the ones set by -package-attributes, and packages with any
+of the attributes set by -exclude-package-attributes are left out.
 `
 }
 
-func (c *sourceManifestCmd) SetFlags(f *flag.FlagSet) {}
+func (c *sourceManifestCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.packages, "packages", false, "Include cipd packages.")
+	f.StringVar(&c.packageAttributes, "package-attributes", "", "Also include optional packages with any of these attributes, separated by comma.")
+	f.StringVar(&c.excludePackageAttributes, "exclude-package-attributes", "", "Exclude packages with any of these attributes, separated by comma.")
+}
 
 func (c *sourceManifestCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
 	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
@@ -54,5 +68,41 @@ func (c *sourceManifestCmd) run(jirix *jiri.X, args []string) error {
 	if err != nil {
 		return err
 	}
+	if c.packages {
+		pkgs, err := c.loadPackages(jirix, localProjects)
+		if err != nil {
+			return err
+		}
+		if err := sm.AddPackages(jirix, pkgs); err != nil {
+			return err
+		}
+	}
 	return sm.ToFile(jirix, args[0])
 }
+
+// loadPackages returns the packages of the manifest that are selected by
+// the attribute flags.
+func (c *sourceManifestCmd) loadPackages(jirix *jiri.X, localProjects project.Projects) (project.Packages, error) {
+	localManifestProjects, err := getDefaultLocalManifestProjects(jirix)
+	if err != nil {
+		return nil, err
+	}
+	_, _, pkgs, err := project.LoadManifestFile(jirix, jirix.JiriManifestFile(), localProjects, localManifestProjects)
+	if err != nil {
+		return nil, err
+	}
+	attrs := jirix.FetchingAttrs
+	if c.packageAttributes != "" {
+		attrs += "," + c.packageAttributes
+	}
+	if err := project.FilterOptionalProjectsPackages(jirix, attrs, nil, pkgs); err != nil {
+		return nil, err
+	}
+	exclude := splitAttributes(c.excludePackageAttributes)
+	for k, pkg := range pkgs {
+		if hasAnyAttribute(pkg.ComputedAttributes.String(), exclude) {
+			delete(pkgs, k)
+		}
+	}
+	return pkgs, nil
+}
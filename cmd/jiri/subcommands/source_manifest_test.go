@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"go.fuchsia.dev/jiri/cipd"
 	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/jiritest"
 	"go.fuchsia.dev/jiri/project"
@@ -132,3 +134,95 @@ func TestSourceManifest(t *testing.T) {
 		t.Fatalf("GOT:\n%s, \nWANT:\n%s", (string(got)), string(want))
 	}
 }
+
+// TestSourceManifestPackages tests that cipd packages are added to the
+// source manifest.
+func TestSourceManifestPackages(t *testing.T) {
+	t.Parallel()
+
+	fake := jiritest.NewFakeJiriRoot(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	toolsName := "fuchsia/tools/" + cipd.CipdPlatform.String()
+	m, err := fake.ReadJiriManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Packages = []project.Package{
+		{
+			Name:      "fuchsia/tools/${platform}",
+			Version:   "git_revision:aaa",
+			Path:      "prebuilt/tools",
+			Instances: []project.PackageInstance{{Name: toolsName, ID: "tools-id"}},
+		},
+		{
+			Name:       "fuchsia/extra",
+			Version:    "version:1",
+			Path:       "prebuilt/extra",
+			Attributes: "extra",
+			Instances:  []project.PackageInstance{{Name: "fuchsia/extra", ID: "extra-id"}},
+		},
+		{
+			Name:       "fuchsia/internal",
+			Version:    "version:2",
+			Path:       "prebuilt/internal",
+			Attributes: "internal",
+			Instances:  []project.PackageInstance{{Name: "fuchsia/internal", ID: "internal-id"}},
+		},
+		{
+			Name:       "fuchsia/optional",
+			Version:    "version:3",
+			Path:       "prebuilt/optional",
+			Attributes: "optional",
+			Instances:  []project.PackageInstance{{Name: "fuchsia/optional", ID: "optional-id"}},
+		},
+	}
+	if err := fake.WriteJiriManifest(m); err != nil {
+		t.Fatal(err)
+	}
+
+	smFile := filepath.Join(t.TempDir(), "sm.json")
+	cmd := sourceManifestCmd{
+		packages:                 true,
+		packageAttributes:        "extra,internal",
+		excludePackageAttributes: "internal",
+	}
+	if err := cmd.run(fake.X, []string{smFile}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(smFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sm project.SourceManifest
+	if err := json.Unmarshal(data, &sm); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*project.SourceManifest_Directory{
+		"prebuilt/tools": {
+			CipdServerHost: cipd.ServiceURL,
+			CipdPackage: map[string]*project.SourceManifest_CIPDPackage{
+				toolsName: {PackagePattern: "fuchsia/tools/${platform}", Version: "git_revision:aaa", InstanceId: "tools-id"},
+			},
+		},
+		"prebuilt/extra": {
+			CipdServerHost: cipd.ServiceURL,
+			CipdPackage: map[string]*project.SourceManifest_CIPDPackage{
+				"fuchsia/extra": {PackagePattern: "fuchsia/extra", Version: "version:1", InstanceId: "extra-id"},
+			},
+		},
+	}
+	for path, dir := range sm.Directories {
+		if dir.GitCheckout != nil {
+			continue
+		}
+		if !reflect.DeepEqual(dir, want[path]) {
+			t.Errorf("unexpected directory %q: %+v", path, dir)
+		}
+		delete(want, path)
+	}
+	for path := range want {
+		t.Errorf("directory %q is missing", path)
+	}
+}
@@ -0,0 +1,471 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/subcommands"
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type stashCmd struct {
+	cmdBase
+
+	includeUntracked bool
+	message          string
+}
+
+func (c *stashCmd) Name() string { return "stash" }
+func (c *stashCmd) Synopsis() string {
+	return "Stash the local changes of all the projects"
+}
+func (c *stashCmd) Usage() string {
+	return `Stashes the local changes of all the dirty projects of the workspace under a
+single named entry, and restores or drops them as one unit.
+
+Usage:
+  jiri stash [flags] save [<name>]
+  jiri stash [flags] list
+  jiri stash [flags] pop [<name>]
+  jiri stash [flags] drop [<name>]
+
+"jiri stash save" stashes the uncommitted changes of every project which has
+some, like "git stash push" does, and records the stash entries of the
+projects in the jiri root under <name>. <name> defaults to "stash-<n>".
+
+"jiri stash list" lists the recorded entries, the most recent first.
+
+"jiri stash pop" applies the stash entries of the projects of <name>, the
+most recent entry if <name> is not given, and drops them. The projects must
+not have uncommitted changes. If the changes of any project conflict, the
+conflicts are reported, all the projects are restored to their state before
+the pop and the entry is kept.
+
+"jiri stash drop" drops the stash entries of the projects of <name>, the
+most recent entry if <name> is not given.
+`
+}
+
+func (c *stashCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.includeUntracked, "u", false, "Also stash the untracked files.")
+	f.StringVar(&c.message, "m", "", "Message describing the changes to stash.")
+}
+
+func (c *stashCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	return executeWrapper(ctx, c.run, c.topLevelFlags, f.Args())
+}
+
+// stashProject is the stash entry of a project.
+type stashProject struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Revision string `json:"revision"`
+}
+
+// stashEntry is a set of stash entries of projects saved by "jiri stash".
+type stashEntry struct {
+	Name      string         `json:"name"`
+	Message   string         `json:"message,omitempty"`
+	Time      time.Time      `json:"time"`
+	Untracked bool           `json:"untracked,omitempty"`
+	Projects  []stashProject `json:"projects"`
+}
+
+func stashFile(jirix *jiri.X) string {
+	return filepath.Join(jirix.RootMetaDir(), "stash.json")
+}
+
+// readStashes returns the entries saved by "jiri stash", the most recent
+// first.
+func readStashes(jirix *jiri.X) ([]stashEntry, error) {
+	data, err := os.ReadFile(stashFile(jirix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []stashEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", stashFile(jirix), err)
+	}
+	return entries, nil
+}
+
+func writeStashes(jirix *jiri.X, entries []stashEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(stashFile(jirix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return project.SafeWriteFile(jirix, stashFile(jirix), data)
+}
+
+// findStash returns the index of the entry with the given name, or of the
+// most recent entry if name is empty.
+func findStash(entries []stashEntry, name string) (int, error) {
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("no stash entries")
+	}
+	if name == "" {
+		return 0, nil
+	}
+	for i, entry := range entries {
+		if entry.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("stash entry %q not found", name)
+}
+
+// removeStash removes the entry with the given name from the recorded
+// entries.
+func removeStash(jirix *jiri.X, name string) error {
+	entries, err := readStashes(jirix)
+	if err != nil {
+		return err
+	}
+	var kept []stashEntry
+	for _, entry := range entries {
+		if entry.Name != name {
+			kept = append(kept, entry)
+		}
+	}
+	return writeStashes(jirix, kept)
+}
+
+func (c *stashCmd) run(jirix *jiri.X, args []string) error {
+	if len(args) == 0 {
+		return jirix.UsageErrorf("no action given")
+	}
+	name := ""
+	if len(args) > 2 {
+		return jirix.UsageErrorf("unexpected number of arguments")
+	} else if len(args) == 2 {
+		name = args[1]
+	}
+	switch action := args[0]; action {
+	case "save":
+		return c.runSave(jirix, name)
+	case "list":
+		if len(args) != 1 {
+			return jirix.UsageErrorf("unexpected number of arguments")
+		}
+		return c.runList(jirix)
+	case "pop":
+		return c.runPop(jirix, name)
+	case "drop":
+		return c.runDrop(jirix, name)
+	default:
+		return jirix.UsageErrorf("unknown action %q", action)
+	}
+}
+
+func (c *stashCmd) runSave(jirix *jiri.X, name string) error {
+	entries, err := readStashes(jirix)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, entry := range entries {
+		used[entry.Name] = true
+	}
+	if name == "" {
+		for i := 1; name == "" || used[name]; i++ {
+			name = fmt.Sprintf("stash-%d", i)
+		}
+	} else if used[name] {
+		return fmt.Errorf("stash entry %q already exists", name)
+	}
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	entry, err := stashProjects(jirix, localProjects, name, c.message, c.includeUntracked)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		fmt.Fprintln(jirix.Stdout(), "No local changes to save")
+		return nil
+	}
+	fmt.Fprintf(jirix.Stdout(), "Saved the local changes of %d project(s) as %s\n", len(entry.Projects), name)
+	return nil
+}
+
+// stashProjects stashes the local changes of the dirty projects of
+// localProjects and records them as the entry name. It returns nil if no
+// project has local changes. If stashing a project fails, the changes
+// stashed in the other projects are restored.
+func stashProjects(jirix *jiri.X, localProjects project.Projects, name, message string, includeUntracked bool) (*stashEntry, error) {
+	states, err := project.GetProjectStates(jirix, localProjects, true)
+	if err != nil {
+		return nil, err
+	}
+	var keys project.ProjectKeys
+	for key, state := range states {
+		if state.HasUncommitted || (includeUntracked && state.HasUntracked) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Sort(keys)
+
+	gitMessage := "jiri stash " + name
+	if message != "" {
+		gitMessage += ": " + message
+	}
+	entry := &stashEntry{
+		Name:      name,
+		Message:   message,
+		Time:      time.Now().UTC(),
+		Untracked: includeUntracked,
+	}
+	for _, key := range keys {
+		local := localProjects[key]
+		relativePath, err := filepath.Rel(jirix.Root, local.Path)
+		if err != nil {
+			relativePath = local.Path
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+		rev, err := scm.StashPush(gitMessage, includeUntracked)
+		if err != nil {
+			restoreStashedProjects(jirix, localProjects, entry)
+			return nil, fmt.Errorf("failed to stash the changes of project %s(%s): %v", local.Name, relativePath, err)
+		}
+		if rev == "" {
+			continue
+		}
+		fmt.Fprintf(jirix.Stdout(), "Project %s(%s): stashed %s\n", local.Name, relativePath, shortRev(rev))
+		entry.Projects = append(entry.Projects, stashProject{
+			Key:      key.String(),
+			Name:     local.Name,
+			Path:     relativePath,
+			Revision: rev,
+		})
+	}
+	if len(entry.Projects) == 0 {
+		return nil, nil
+	}
+	entries, err := readStashes(jirix)
+	if err != nil {
+		restoreStashedProjects(jirix, localProjects, entry)
+		return nil, err
+	}
+	if err := writeStashes(jirix, append([]stashEntry{*entry}, entries...)); err != nil {
+		restoreStashedProjects(jirix, localProjects, entry)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// restoreStashedProjects pops the stash entries of the projects of entry
+// after a failure to save it.
+func restoreStashedProjects(jirix *jiri.X, localProjects project.Projects, entry *stashEntry) {
+	for _, p := range entry.Projects {
+		key, _ := project.ProjectKeyFromString(p.Key)
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(localProjects[key].Path))
+		if err := scm.StashRestore(p.Revision); err != nil {
+			jirix.Logger.Errorf("Failed to restore the changes of project %s(%s), they are in stash entry %s: %v\n", p.Name, p.Path, p.Revision, err)
+			continue
+		}
+		if err := scm.StashDrop(p.Revision); err != nil {
+			jirix.Logger.Warningf("Failed to drop stash entry %s of project %s(%s): %v\n", p.Revision, p.Name, p.Path, err)
+		}
+	}
+}
+
+func (c *stashCmd) runList(jirix *jiri.X) error {
+	entries, err := readStashes(jirix)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(jirix.Stdout(), "%s: %s", jirix.Color.Yellow("%s", entry.Name), entry.Time.Local().Format(time.RFC1123))
+		if entry.Message != "" {
+			fmt.Fprintf(jirix.Stdout(), " %s", entry.Message)
+		}
+		fmt.Fprintln(jirix.Stdout())
+		for _, p := range entry.Projects {
+			fmt.Fprintf(jirix.Stdout(), "  %s(%s)\n", p.Name, p.Path)
+		}
+	}
+	return nil
+}
+
+func (c *stashCmd) runPop(jirix *jiri.X, name string) error {
+	entries, err := readStashes(jirix)
+	if err != nil {
+		return err
+	}
+	i, err := findStash(entries, name)
+	if err != nil {
+		return err
+	}
+	entry := entries[i]
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	if err := popStash(jirix, localProjects, &entry); err != nil {
+		return err
+	}
+	fmt.Fprintf(jirix.Stdout(), "Restored the local changes of %d project(s) from %s\n", len(entry.Projects), entry.Name)
+	return nil
+}
+
+// stashConflictError is returned by popStash when the changes of some
+// projects conflict with their working tree.
+type stashConflictError struct {
+	name     string
+	projects []string
+}
+
+func (e *stashConflictError) Error() string {
+	return fmt.Sprintf("failed to restore the local changes of %s, they conflict in %s. The projects were left unchanged and the entry was kept", e.name, strings.Join(e.projects, ", "))
+}
+
+// popStash applies the stash entries of the projects of entry and drops
+// them if they all apply cleanly. Otherwise the projects are restored to
+// their previous state, the entry is kept and a *stashConflictError is
+// returned.
+func popStash(jirix *jiri.X, localProjects project.Projects, entry *stashEntry) error {
+	type stashedProject struct {
+		stashProject
+		scm *gitutil.Git
+		dir string
+		// created are the untracked files of the stash entry which
+		// do not exist in the project.
+		created []string
+	}
+	var projects []stashedProject
+	var problems []string
+	for _, p := range entry.Projects {
+		key, ok := project.ProjectKeyFromString(p.Key)
+		local, found := localProjects[key]
+		if !ok || !found {
+			problems = append(problems, fmt.Sprintf("project %s(%s) does not exist anymore", p.Name, p.Path))
+			continue
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+		revs, err := scm.StashList()
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(revs, p.Revision) {
+			problems = append(problems, fmt.Sprintf("stash entry %s of project %s(%s) does not exist anymore", shortRev(p.Revision), p.Name, p.Path))
+			continue
+		}
+		if dirty, err := scm.HasUncommittedChanges(); err != nil {
+			return err
+		} else if dirty {
+			problems = append(problems, fmt.Sprintf("project %s(%s) has uncommitted changes", p.Name, p.Path))
+			continue
+		}
+		untracked, err := scm.StashUntrackedFiles(p.Revision)
+		if err != nil {
+			return err
+		}
+		sp := stashedProject{stashProject: p, scm: scm, dir: local.Path}
+		for _, file := range untracked {
+			if _, err := os.Lstat(filepath.Join(local.Path, file)); os.IsNotExist(err) {
+				sp.created = append(sp.created, file)
+			}
+		}
+		projects = append(projects, sp)
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("cannot restore %s:\n  %s", entry.Name, strings.Join(problems, "\n  "))
+	}
+
+	var applied []stashedProject
+	var conflicts []string
+	for _, p := range projects {
+		applied = append(applied, p)
+		if err := p.scm.StashRestore(p.Revision); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s(%s)", p.Name, p.Path))
+			fmt.Fprintf(jirix.Stdout(), "Project %s(%s): ", p.Name, p.Path)
+			if files, _ := p.scm.UnmergedFiles(); len(files) != 0 {
+				fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("conflicts in %s\n", strings.Join(files, ", ")))
+			} else {
+				fmt.Fprintf(jirix.Stdout(), "%s", jirix.Color.Red("failed to apply stash entry %s: %s\n", shortRev(p.Revision), err))
+			}
+		}
+	}
+	if len(conflicts) != 0 {
+		for _, p := range applied {
+			if err := p.scm.Reset("HEAD"); err != nil {
+				jirix.Logger.Errorf("Failed to restore project %s(%s): %v\n", p.Name, p.Path, err)
+				continue
+			}
+			for _, file := range p.created {
+				if err := os.Remove(filepath.Join(p.dir, file)); err != nil && !os.IsNotExist(err) {
+					jirix.Logger.Errorf("Failed to remove %s from project %s(%s): %v\n", file, p.Name, p.Path, err)
+				}
+			}
+		}
+		return &stashConflictError{name: entry.Name, projects: conflicts}
+	}
+
+	for _, p := range projects {
+		if err := p.scm.StashDrop(p.Revision); err != nil {
+			jirix.Logger.Warningf("Failed to drop stash entry %s of project %s(%s): %v\n", p.Revision, p.Name, p.Path, err)
+		}
+	}
+	return removeStash(jirix, entry.Name)
+}
+
+func (c *stashCmd) runDrop(jirix *jiri.X, name string) error {
+	entries, err := readStashes(jirix)
+	if err != nil {
+		return err
+	}
+	i, err := findStash(entries, name)
+	if err != nil {
+		return err
+	}
+	entry := entries[i]
+	localProjects, err := project.LocalProjects(jirix, project.FastScan)
+	if err != nil {
+		return err
+	}
+	for _, p := range entry.Projects {
+		key, _ := project.ProjectKeyFromString(p.Key)
+		local, ok := localProjects[key]
+		if !ok {
+			jirix.Logger.Warningf("Project %s(%s) does not exist anymore\n", p.Name, p.Path)
+			continue
+		}
+		scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+		if err := scm.StashDrop(p.Revision); err != nil {
+			jirix.Logger.Warningf("Failed to drop stash entry %s of project %s(%s): %v\n", p.Revision, p.Name, p.Path, err)
+		}
+	}
+	if err := removeStash(jirix, entry.Name); err != nil {
+		return err
+	}
+	fmt.Fprintf(jirix.Stdout(), "Dropped %s\n", entry.Name)
+	return nil
+}
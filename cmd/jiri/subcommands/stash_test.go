@@ -0,0 +1,125 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subcommands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+func TestStash(t *testing.T) {
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout, Env: fake.X.Context.Env()})
+
+	readme := filepath.Join(localProjects[0].Path, "README")
+	untracked := filepath.Join(localProjects[1].Path, "untracked")
+	if err := os.WriteFile(readme, []byte("local change"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(untracked, []byte("untracked"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path), gitutil.UserNameOpt("John Doe"), gitutil.UserEmailOpt("john.doe@example.com"))
+	if err := git.Add("README"); err != nil {
+		t.Fatal(err)
+	}
+	checkContent := func(file, want string) {
+		t.Helper()
+		data, err := os.ReadFile(file)
+		if want == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("expected %s not to exist, got %v", file, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: got %q, want %q", file, data, want)
+		}
+	}
+
+	cmd := &stashCmd{includeUntracked: true, message: "wip"}
+	if err := cmd.run(fake.X, []string{"save"}); err != nil {
+		t.Fatal(err)
+	}
+	checkContent(readme, "initial readme")
+	checkContent(untracked, "")
+	entries, err := readStashes(fake.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "stash-1" || len(entries[0].Projects) != 2 {
+		t.Fatalf("unexpected stash entries %+v", entries)
+	}
+	stdout.Reset()
+	if err := cmd.run(fake.X, []string{"list"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"stash-1", "wip", localProjects[0].Name, localProjects[1].Name} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("list output does not contain %q:\n%s", want, stdout.String())
+		}
+	}
+
+	if err := cmd.run(fake.X, []string{"pop", "stash-1"}); err != nil {
+		t.Fatal(err)
+	}
+	checkContent(readme, "local change")
+	checkContent(untracked, "untracked")
+	if staged, err := git.StagedFiles(); err != nil || len(staged) != 1 || staged[0] != "README" {
+		t.Errorf("expected README to be staged again, got %v, %v", staged, err)
+	}
+	if entries, err := readStashes(fake.X); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no stash entries, got %+v, %v", entries, err)
+	}
+
+	// A conflict in one project leaves all the projects unchanged and keeps
+	// the entry.
+	if err := cmd.run(fake.X, []string{"save", "conflict"}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, git, "README", "conflicting change")
+	err = cmd.run(fake.X, []string{"pop"})
+	var conflictErr *stashConflictError
+	if !errors.As(err, &conflictErr) || len(conflictErr.projects) != 1 || !strings.HasPrefix(conflictErr.projects[0], localProjects[0].Name) {
+		t.Fatalf("expected a conflict in %s, got %v", localProjects[0].Name, err)
+	}
+	checkContent(readme, "conflicting change")
+	checkContent(untracked, "")
+	if dirty, err := git.HasUncommittedChanges(); err != nil || dirty {
+		t.Errorf("expected %s to be restored, got %v, %v", localProjects[0].Name, dirty, err)
+	}
+	if entries, err := readStashes(fake.X); err != nil || len(entries) != 1 {
+		t.Fatalf("expected the stash entry to be kept, got %+v, %v", entries, err)
+	}
+
+	if err := cmd.run(fake.X, []string{"drop", "conflict"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := readStashes(fake.X); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no stash entries, got %+v, %v", entries, err)
+	}
+	for _, localProject := range localProjects[:2] {
+		if revs, err := gitutil.New(fake.X, gitutil.RootDirOpt(localProject.Path)).StashList(); err != nil || len(revs) != 0 {
+			t.Errorf("expected the stash of %s to be empty, got %v, %v", localProject.Name, revs, err)
+		}
+	}
+	if err := cmd.run(fake.X, []string{"pop"}); err == nil {
+		t.Errorf("expected pop without entries to fail")
+	}
+}
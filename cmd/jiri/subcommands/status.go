@@ -6,16 +6,19 @@ package subcommands
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/google/subcommands"
 	"go.fuchsia.dev/jiri"
 	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
 )
 
 type statusCmd struct {
@@ -26,6 +29,7 @@ type statusCmd struct {
 	branch         string
 	commits        bool
 	deleted        bool
+	jsonOutput     bool
 	rebaseFailures uint32
 }
 
@@ -36,6 +40,49 @@ func (c *statusCmd) Usage() string {
 and prints it if there are some changes. It also shows status if the project is on
 a rev other then the one according to manifest(Named as JIRI_HEAD in git)
 
+With -json, the status of every project is printed as JSON instead, whether
+it has changes or not, including deleted projects:
+
+[
+	{
+		name: name,
+		path: relative-path,
+		remote: remote,
+		branch: current-branch, // empty if detached
+		revision: current-revision,
+		tracking: { // if the branch tracks another branch
+			name: tracking-branch,
+			revision: revision,
+			ahead: commits-not-in-tracking-branch,
+			behind: commits-not-in-branch
+		},
+		jiri_head: { // if JIRI_HEAD is known
+			revision: revision,
+			ahead: commits-not-in-JIRI_HEAD,
+			behind: commits-not-in-current-revision
+		},
+		on_manifest_revision: true|false,
+		uncommitted: [
+			{
+				status: xy-status-code, // of "git status -s"
+				path: path
+			},{...}...
+		],
+		untracked: [paths...],
+		local_config: {
+			ignore: true|false,
+			no_update: true|false,
+			no_rebase: true|false,
+			autostash: true|false
+		},
+		deleted: true|false, // if not in the manifest anymore
+		error: error
+	},{...}...
+]
+
+The -branch and -deleted flags filter the projects, the other flags are
+ignored.
+
 Usage:
   jiri status [flags]
 `
@@ -48,6 +95,7 @@ func (c *statusCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.branch, "branch", "", "Display all projects only on this branch along with their status.")
 	f.BoolVar(&c.deleted, "deleted", false, "List all deleted projects. Other flags would be ignored.")
 	f.BoolVar(&c.deleted, "d", false, "Same as -deleted.")
+	f.BoolVar(&c.jsonOutput, "json", false, "Print the status of all projects as JSON.")
 }
 
 func (c *statusCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
@@ -75,6 +123,9 @@ func (c *statusCmd) run(jirix *jiri.X, args []string) error {
 	if err != nil {
 		return err
 	}
+	if c.jsonOutput {
+		return c.writeJSON(jirix, localProjects, remoteProjects)
+	}
 	cwd := jirix.Cwd
 	if c.deleted {
 		for key, localProject := range localProjects {
@@ -231,3 +282,157 @@ func (c *statusCmd) getStatus(jirix *jiri.X, local project.Project, remote proje
 	}
 	return changes, headRev, extraCommits, nil
 }
+
+// StatusRevisionComparison compares the current revision of a project with
+// another ref.
+type StatusRevisionComparison struct {
+	Name     string `json:"name,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+}
+
+// StatusFile is a file with uncommitted changes.
+type StatusFile struct {
+	Status   string `json:"status"`
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"`
+}
+
+type StatusLocalConfig struct {
+	Ignore    bool `json:"ignore"`
+	NoUpdate  bool `json:"no_update"`
+	NoRebase  bool `json:"no_rebase"`
+	AutoStash bool `json:"autostash"`
+}
+
+// ProjectStatus is the status of a project printed by "jiri status -json".
+type ProjectStatus struct {
+	Name               string                    `json:"name"`
+	Path               string                    `json:"path"`
+	Remote             string                    `json:"remote"`
+	Branch             string                    `json:"branch"`
+	Revision           string                    `json:"revision"`
+	Tracking           *StatusRevisionComparison `json:"tracking,omitempty"`
+	JiriHead           *StatusRevisionComparison `json:"jiri_head,omitempty"`
+	OnManifestRevision bool                      `json:"on_manifest_revision"`
+	Uncommitted        []StatusFile              `json:"uncommitted"`
+	Untracked          []string                  `json:"untracked"`
+	LocalConfig        StatusLocalConfig         `json:"local_config"`
+	Deleted            bool                      `json:"deleted,omitempty"`
+	Error              string                    `json:"error,omitempty"`
+}
+
+// writeJSON prints the status of the local projects as JSON. The status of
+// each project is computed in parallel.
+func (c *statusCmd) writeJSON(jirix *jiri.X, localProjects, remoteProjects project.Projects) error {
+	states, err := project.GetProjectStates(jirix, localProjects, false)
+	if err != nil {
+		return err
+	}
+	var keys project.ProjectKeys
+	for key, localProject := range localProjects {
+		_, foundRemote := remoteProjects[key]
+		deleted := !foundRemote && !localProject.IsSubmodule
+		if c.deleted && !deleted {
+			continue
+		}
+		if c.branch != "" && c.branch != states[key].CurrentBranch.Name {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Sort(keys)
+
+	statuses := make([]ProjectStatus, len(keys))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, jirix.Jobs)
+	for i, key := range keys {
+		wg.Add(1)
+		limit <- struct{}{}
+		// jirix is not threadsafe, so we make a clone for each goroutine.
+		go func(jirix *jiri.X, i int, key project.ProjectKey) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			remoteProject, foundRemote := remoteProjects[key]
+			statuses[i] = projectStatus(jirix, states[key], remoteProject, foundRemote)
+		}(jirix.Clone(tool.ContextOpts{}), i, key)
+	}
+	wg.Wait()
+
+	e := json.NewEncoder(jirix.Stdout())
+	e.SetIndent("", " ")
+	return e.Encode(statuses)
+}
+
+// projectStatus returns the status of the project of state. remote is the
+// project in the manifest, if found.
+func projectStatus(jirix *jiri.X, state *project.ProjectState, remote project.Project, foundRemote bool) ProjectStatus {
+	local := state.Project
+	status := ProjectStatus{
+		Name:     local.Name,
+		Path:     local.Path,
+		Remote:   local.Remote,
+		Branch:   state.CurrentBranch.Name,
+		Revision: state.CurrentBranch.Revision,
+		Deleted:  !foundRemote && !local.IsSubmodule,
+		LocalConfig: StatusLocalConfig{
+			Ignore:    local.LocalConfig.Ignore,
+			NoUpdate:  local.LocalConfig.NoUpdate,
+			NoRebase:  local.LocalConfig.NoRebase,
+			AutoStash: local.LocalConfig.AutoStash,
+		},
+		Uncommitted: []StatusFile{},
+		Untracked:   []string{},
+	}
+	if rel, err := filepath.Rel(jirix.Root, local.Path); err == nil {
+		status.Path = rel
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(local.Path))
+	compare := func(name, rev string) (*StatusRevisionComparison, error) {
+		ahead, err := scm.CountCommits(status.Revision, rev)
+		if err != nil {
+			return nil, err
+		}
+		behind, err := scm.CountCommits(rev, status.Revision)
+		if err != nil {
+			return nil, err
+		}
+		return &StatusRevisionComparison{Name: name, Revision: rev, Ahead: ahead, Behind: behind}, nil
+	}
+
+	files, err := scm.PorcelainStatus()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	for _, file := range files {
+		if file.Code == "??" {
+			status.Untracked = append(status.Untracked, file.Path)
+		} else {
+			status.Uncommitted = append(status.Uncommitted, StatusFile{Status: file.Code, Path: file.Path, OrigPath: file.OrigPath})
+		}
+	}
+
+	if tracking := state.CurrentBranch.Tracking; tracking != nil {
+		if status.Tracking, err = compare(tracking.Name, tracking.Revision); err != nil {
+			status.Error = err.Error()
+			return status
+		}
+	}
+
+	headRev, err := scm.CurrentRevisionForRef("JIRI_HEAD")
+	if err != nil && foundRemote {
+		if headRev, err = project.GetHeadRevision(remote); err == nil {
+			headRev, err = scm.CurrentRevisionForRef(headRev)
+		}
+	}
+	if err == nil {
+		if status.JiriHead, err = compare("", headRev); err != nil {
+			status.Error = err.Error()
+			return status
+		}
+		status.OnManifestRevision = headRev == status.Revision
+	}
+	return status
+}
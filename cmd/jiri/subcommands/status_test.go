@@ -5,9 +5,11 @@
 package subcommands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -249,6 +251,117 @@ func TestStatusDeleted(t *testing.T) {
 	}
 }
 
+func TestStatusJSON(t *testing.T) {
+	t.Parallel()
+
+	fake := jiritest.NewFakeJiriRoot(t)
+	localProjects := createProjects(t, fake, 4)
+	_, _, latestCommitRevs, _ := createCommits(t, fake, localProjects)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete the last project.
+	manifest, err := fake.ReadRemoteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var projects []project.Project
+	for _, p := range manifest.Projects {
+		if p.Name != localProjects[3].Name {
+			projects = append(projects, p)
+		}
+	}
+	manifest.Projects = projects
+	if err := fake.WriteRemoteManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+
+	gitLocal0 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[0].Path))
+	if err := gitLocal0.Checkout("HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	newfile(t, localProjects[0].Path, "untracked1")
+	newfile(t, localProjects[0].Path, "untracked \"2\"")
+
+	gitLocal1 := gitutil.New(fake.X, gitutil.RootDirOpt(localProjects[1].Path))
+	if err := gitLocal1.Checkout("main"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, localProjects[1].Path, "extrafile", "extrafile")
+	newfile(t, localProjects[1].Path, "uncommitted.go")
+	if err := gitLocal1.Add("uncommitted.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(localProjects[1].Path, "extrafile"), filepath.Join(localProjects[1].Path, "extra file")); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"extrafile", "extra file"} {
+		if err := gitLocal1.Add(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := project.WriteLocalConfig(fake.X, localProjects[2], project.LocalConfig{NoUpdate: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := defaultStatusFlags()
+	cmd.jsonOutput = true
+	var statuses []ProjectStatus
+	if err := json.Unmarshal([]byte(executeStatus(t, fake, cmd)), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	// The manifest project is listed as well.
+	if len(statuses) != 5 {
+		t.Fatalf("expected 5 projects, got %+v", statuses)
+	}
+	byName := make(map[string]ProjectStatus)
+	for _, s := range statuses {
+		byName[s.Name] = s
+	}
+
+	s0 := byName[localProjects[0].Name]
+	if s0.Branch != "" || s0.OnManifestRevision || s0.JiriHead == nil || s0.JiriHead.Behind != 1 || s0.JiriHead.Ahead != 0 {
+		t.Errorf("unexpected status of detached project: %+v", s0)
+	}
+	if want := []string{"untracked \"2\"", "untracked1"}; s0.Path != "path-0" || !reflect.DeepEqual(s0.Untracked, want) || len(s0.Uncommitted) != 0 {
+		t.Errorf("unexpected files of project: %+v", s0)
+	}
+
+	s1 := byName[localProjects[1].Name]
+	if s1.Branch != "main" || s1.Tracking == nil || s1.Tracking.Name != "origin/main" || s1.Tracking.Ahead != 1 || s1.Tracking.Behind != 0 {
+		t.Errorf("unexpected tracking status: %+v %+v", s1, s1.Tracking)
+	}
+	if s1.OnManifestRevision || s1.JiriHead == nil || s1.JiriHead.Revision != latestCommitRevs[1] || s1.JiriHead.Ahead != 1 {
+		t.Errorf("unexpected JIRI_HEAD status: %+v %+v", s1, s1.JiriHead)
+	}
+	if want := []StatusFile{{Status: "R ", Path: "extra file", OrigPath: "extrafile"}, {Status: "A ", Path: "uncommitted.go"}}; !reflect.DeepEqual(s1.Uncommitted, want) {
+		t.Errorf("got uncommitted files %+v, want %+v", s1.Uncommitted, want)
+	}
+
+	s2 := byName[localProjects[2].Name]
+	if !s2.OnManifestRevision || !s2.LocalConfig.NoUpdate || s2.LocalConfig.Ignore || s2.Deleted {
+		t.Errorf("unexpected status: %+v", s2)
+	}
+
+	if s3 := byName[localProjects[3].Name]; !s3.Deleted {
+		t.Errorf("expected project to be deleted: %+v", s3)
+	}
+
+	cmd.deleted = true
+	statuses = nil
+	if err := json.Unmarshal([]byte(executeStatus(t, fake, cmd)), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != localProjects[3].Name {
+		t.Errorf("expected only the deleted project, got %+v", statuses)
+	}
+}
+
 func statusFlagsTest(t *testing.T, cmd *statusCmd) {
 	t.Parallel()
 
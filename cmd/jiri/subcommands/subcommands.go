@@ -44,13 +44,21 @@ func NewCommander(args []string) (*subcommands.Commander, error) {
 
 	cdr.Register(cdr.HelpCommand(), "")
 	cdr.Register(cdr.FlagsCommand(), "")
+	cdr.Register(&bisectCmd{cmdBase: b}, "")
 	cdr.Register(&branchCmd{cmdBase: b}, "")
+	cdr.Register(&clCmd{cmdBase: b}, "")
+	cdr.Register(&commitCmd{cmdBase: b}, "")
+	cdr.Register(&containsCmd{cmdBase: b}, "")
 	cdr.Register(&diffCmd{cmdBase: b}, "")
 	cdr.Register(&grepCmd{cmdBase: b}, "")
 	cdr.Register(&initCmd{cmdBase: b}, "")
+	cdr.Register(&logCmd{cmdBase: b}, "")
 	cdr.Register(&patchCmd{cmdBase: b}, "")
+	cdr.Register(&rebaseCmd{cmdBase: b}, "")
+	cdr.Register(&rollCmd{cmdBase: b}, "")
 	cdr.Register(&runpCmd{cmdBase: b}, "")
 	cdr.Register(&selfUpdateCmd{cmdBase: b}, "")
+	cdr.Register(&stashCmd{cmdBase: b}, "")
 	cdr.Register(&statusCmd{cmdBase: b}, "")
 	cdr.Register(&updateCmd{cmdBase: b}, "")
 	cdr.Register(&uploadCmd{cmdBase: b}, "")
@@ -62,6 +70,7 @@ func NewCommander(args []string) (*subcommands.Commander, error) {
 	cdr.Register(&fetchPkgsCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&genGitModuleCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&importCmd{cmdBase: b}, lowLevelGroup)
+	cdr.Register(&lockfileCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&manifestCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&overrideCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&packageCmd{cmdBase: b}, lowLevelGroup)
@@ -69,6 +78,7 @@ func NewCommander(args []string) (*subcommands.Commander, error) {
 	cdr.Register(&projectConfigCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&resolveCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&runHooksCmd{cmdBase: b}, lowLevelGroup)
+	cdr.Register(&sbomCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&snapshotCmd{cmdBase: b}, lowLevelGroup)
 	cdr.Register(&sourceManifestCmd{cmdBase: b}, lowLevelGroup)
 
@@ -264,6 +274,12 @@ attribute is ignored.
 * gerrithost (optional) - The url of the Gerrit host for the project.  If
 specified, then running "jiri cl upload" will upload a CL to this Gerrit host.
 
+* reviewhost (optional) - The kind of code review host of the project, "gerrit"
+by default. With reviewhost="github", "jiri upload" opens GitHub pull requests
+from the remote branch "jiri/<local branch>" and "jiri patch" fetches pull
+requests by number. The URL of the GitHub API can be given after a colon, e.g.
+"github:https://github.example.com/api/v3".
+
 * githooks (optional) - The path (relative to [root]) of a directory containing
 git hooks that will be installed in the projects .git/hooks directory during
 each update.
@@ -37,6 +37,7 @@ type updateCmd struct {
 	rebaseCurrent         bool
 	rebaseSubmodules      bool
 	rebaseTracked         bool
+	autoStash             bool
 	runHooks              bool
 	fetchPkgs             bool
 	overrideOptional      bool
@@ -57,6 +58,7 @@ func (c *updateCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.rebaseCurrent, "rebase-current", false, "Deprecated. Implies -rebase-tracked. Would be removed in future.")
 	f.BoolVar(&c.rebaseSubmodules, "rebase-submodules", false, "Rebase current tracked branches for submodules.")
 	f.BoolVar(&c.rebaseTracked, "rebase-tracked", false, "Rebase current tracked branches instead of fast-forwarding them.")
+	f.BoolVar(&c.autoStash, "autostash", false, "Stash the uncommitted changes and untracked files of the projects before updating them and re-apply them afterwards. Can also be enabled per project with 'jiri project-config -autostash'.")
 	f.BoolVar(&c.runHooks, "run-hooks", true, "Run hooks after updating sources.")
 	f.BoolVar(&c.fetchPkgs, "fetch-packages", true, "Use cipd to fetch packages.")
 	f.BoolVar(&c.overrideOptional, "override-optional", false, "Override existing optional attributes in the snapshot file with current jiri settings")
@@ -77,6 +79,14 @@ Usage:
   jiri update [flags] <file or url>
 
 <file or url> points to snapshot to checkout.
+
+Projects with uncommitted changes are not updated, unless -autostash is
+passed or enabled in their local config. Their changes are then stashed
+before the update and re-applied afterwards, staged changes being staged
+again unless the staged version conflicts with the update. If the changes
+conflict with the updated project, the project is left as updated, the
+changes are kept in its stash and the project is listed at the end of the
+update.
 `
 }
 
@@ -148,6 +158,7 @@ func (c *updateCmd) run(jirix *jiri.X, args []string) error {
 			RunHooks:              c.runHooks,
 			FetchPackages:         c.fetchPkgs,
 			RebaseSubmodules:      c.rebaseSubmodules,
+			AutoStash:             c.autoStash,
 			RunHookTimeout:        c.hookTimeout,
 			FetchPackagesTimeout:  c.fetchPkgsTimeout,
 			PackagesToSkip:        c.packagesToSkip,
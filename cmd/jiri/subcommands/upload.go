@@ -17,6 +17,7 @@ import (
 	"go.fuchsia.dev/jiri/gerrit"
 	"go.fuchsia.dev/jiri/gitutil"
 	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/reviewhost"
 )
 
 type uploadCmd struct {
@@ -48,13 +49,22 @@ func (e uploadError) Error() string {
 func (c *uploadCmd) Name() string     { return "upload" }
 func (c *uploadCmd) Synopsis() string { return "Upload a changelist for review" }
 func (c *uploadCmd) Usage() string {
-	return `Command "upload" uploads commits of a local branch to Gerrit.
+	return `Command "upload" uploads commits of a local branch for review, to Gerrit or
+as a GitHub pull request depending on the "reviewhost" attribute of the
+project. Pull requests are opened from a remote branch named after the local
+branch.
 
 Usage:
   jiri upload [flags] <ref>
 
 <ref> is the valid git ref to upload. It is optional and HEAD is used by
 default. This cannot be used with -multipart flag.
+
+With -multipart, the changes of all the projects on the current branch are
+uploaded. If the branch was created by "jiri branch -create", the projects of
+the branch which have no commits to upload are skipped. The GitHub pull
+requests uploaded together are linked to each other under the topic, or the
+branch name if there is no topic.
 `
 }
 
@@ -152,16 +162,35 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 		return err
 	}
 	if c.multipart {
+		set, err := branchSet(jirix, currentBranch)
+		if err != nil {
+			return err
+		}
 		for _, project := range localProjects {
 			scm := gitutil.New(jirix, gitutil.RootDirOpt(project.Path))
+			onBranch := false
 			if scm.IsOnBranch() {
 				branch, err := scm.CurrentBranchName()
 				if err != nil {
 					return err
 				}
-				if currentBranch == branch {
+				onBranch = currentBranch == branch
+			}
+			if !set[project.Key()] {
+				if onBranch {
 					projectsToProcess = append(projectsToProcess, project)
 				}
+				continue
+			}
+			if !onBranch {
+				jirix.Logger.Warningf("Project %s is not on branch %q, it will not be uploaded\n", project.Name, currentBranch)
+				continue
+			}
+			// Skip the projects of the branch set without changes.
+			if n, err := scm.CountCommits("HEAD", "@{upstream}"); err != nil {
+				return err
+			} else if n != 0 {
+				projectsToProcess = append(projectsToProcess, project)
 			}
 		}
 
@@ -174,6 +203,7 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 	type GerritPushOption struct {
 		Project      project.Project
 		CLOpts       gerrit.CLOpts
+		Host         reviewhost.Host
 		relativePath string
 	}
 	var gerritPushOptions []GerritPushOption
@@ -195,6 +225,14 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 				return fmt.Errorf("Project %s(%s) has uncommitted changes, please commit them or stash them. Cannot rebase before pushing.", project.Name, relativePath)
 			}
 		}
+		hostProject := project
+		if r, ok := remoteProjects[project.Key()]; ok {
+			hostProject = r
+		}
+		host, err := reviewhost.New(jirix, hostProject)
+		if err != nil {
+			return err
+		}
 		remoteBranch := c.remoteBranch
 		if remoteBranch == "" && currentBranch != "" {
 			remoteBranch, err = scm.RemoteBranchName()
@@ -227,7 +265,7 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 		if opts.Presubmit == gerrit.PresubmitTestType("") {
 			opts.Presubmit = gerrit.PresubmitTestTypeAll
 		}
-		gerritPushOptions = append(gerritPushOptions, GerritPushOption{project, opts, relativePath})
+		gerritPushOptions = append(gerritPushOptions, GerritPushOption{project, opts, host, relativePath})
 	}
 
 	// Rebase all projects before pushing
@@ -247,9 +285,11 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 		}
 	}
 
+	var changes []reviewhost.Change
 	for _, gerritPushOption := range gerritPushOptions {
 		fmt.Fprintf(jirix.Stdout(), "Pushing project %s(%s)\n", gerritPushOption.Project.Name, gerritPushOption.relativePath)
-		if err := gerrit.Push(jirix, gerritPushOption.Project.Path, gerritPushOption.CLOpts); err != nil {
+		opts := reviewhost.PushOptions{CLOpts: gerritPushOption.CLOpts, Branch: currentBranch}
+		if change, err := gerritPushOption.Host.Push(gerritPushOption.Project.Path, opts); err != nil {
 			if strings.Contains(err.Error(), "(no new changes)") {
 				if gitErr, ok := err.(gerrit.PushError); ok {
 					fmt.Fprintf(jirix.Stdout(), "%s", gitErr.Output)
@@ -260,9 +300,23 @@ func (c *uploadCmd) run(jirix *jiri.X, args []string) error {
 			} else {
 				return uploadError(err.Error())
 			}
+		} else if change != nil {
+			fmt.Fprintf(jirix.Stdout(), "%s\n", change.URL)
+			changes = append(changes, *change)
 		}
 		fmt.Fprintln(jirix.Stdout())
 	}
+	if c.multipart && len(changes) != 0 {
+		linkTopic := topic
+		if linkTopic == "" {
+			linkTopic = currentBranch
+		}
+		for _, gerritPushOption := range gerritPushOptions {
+			if err := gerritPushOption.Host.Link(linkTopic, changes); err != nil {
+				return uploadError(err.Error())
+			}
+		}
+	}
 	return nil
 }
 
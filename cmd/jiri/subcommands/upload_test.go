@@ -5,10 +5,15 @@
 package subcommands
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"go.fuchsia.dev/jiri"
@@ -262,6 +267,139 @@ func TestUploadMultipart(t *testing.T) {
 	assertUploadPushedFilesToRef(t, fake.X, gerritPath, expectedRef, []string{"file-10", "file-20"})
 }
 
+func TestUploadMultipartBranchSet(t *testing.T) {
+	t.Parallel()
+
+	fake, localProjects := setupUploadTest(t)
+
+	branch := "my-branch"
+	fake.X.Cwd = fake.X.Root
+	create := branchCmd{create: branch, projects: "project-[01]$"}
+	if err := create.run(fake.X, nil); err != nil {
+		t.Fatal(err)
+	}
+	git := gitutil.New(fake.X,
+		gitutil.RootDirOpt(localProjects[0].Path),
+		gitutil.UserNameOpt("John Doe"),
+		gitutil.UserEmailOpt("john.doe@example.com"))
+	commitFiles(t, git, []string{"file-10"})
+
+	cmd := defaultUploadFlags()
+	cmd.multipart = true
+	fake.X.Cwd = localProjects[0].Path
+	if err := cmd.run(fake.X, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	expectedRef := "refs/for/main"
+	assertUploadPushedFilesToRef(t, fake.X, fake.Projects[localProjects[0].Name], expectedRef, []string{"file-10"})
+
+	// The project of the branch set without commits is not uploaded.
+	remote := gitutil.New(fake.X, gitutil.RootDirOpt(fake.Projects[localProjects[1].Name]))
+	if _, err := remote.CurrentRevisionForRef(expectedRef); err == nil {
+		t.Errorf("expected nothing to be pushed to %s of %s", expectedRef, localProjects[1].Name)
+	}
+}
+
+func TestUploadMultipartGitHub(t *testing.T) {
+	var mu sync.Mutex
+	pulls := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		// There is a single pull request per repository, numbered 1.
+		repo, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/repos/"), "/pulls")
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/pulls"):
+			rw.Write([]byte("[]"))
+			return
+		case r.Method == http.MethodPost:
+			in["number"] = 1
+			in["state"] = "open"
+			in["base"] = map[string]any{"ref": in["base"]}
+			in["html_url"] = "https://github.com/" + repo + "/pull/1"
+			pulls[repo] = in
+		case r.Method == http.MethodPatch:
+			pulls[repo]["body"] = in["body"]
+		}
+		json.NewEncoder(rw).Encode(pulls[repo])
+	}))
+	defer server.Close()
+
+	localProjects, fake := setupUniverse(t)
+	m, err := fake.ReadRemoteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range m.Projects {
+		m.Projects[i].ReviewHost = "github:" + server.URL
+	}
+	if err := fake.WriteRemoteManifest(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	branch := "my-branch"
+	for i := 0; i < 2; i++ {
+		git := gitutil.New(fake.X,
+			gitutil.RootDirOpt(localProjects[i].Path),
+			gitutil.UserNameOpt("John Doe"),
+			gitutil.UserEmailOpt("john.doe@example.com"))
+		if err := git.CreateBranchWithUpstream(branch, "origin/main"); err != nil {
+			t.Fatal(err)
+		}
+		if err := git.Checkout(branch); err != nil {
+			t.Fatal(err)
+		}
+		commitFiles(t, git, []string{"file-1" + strconv.Itoa(i)})
+	}
+
+	cmd := defaultUploadFlags()
+	cmd.multipart = true
+	fake.X.Cwd = localProjects[1].Path
+	if err := cmd.run(fake.X, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	// The changes are pushed to a branch in the jiri/ namespace.
+	assertUploadPushedFilesToRef(t, fake.X, fake.Projects[localProjects[0].Name], "jiri/"+branch, []string{"file-10"})
+
+	if len(pulls) != 2 {
+		t.Fatalf("expected 2 pull requests, got %v", pulls)
+	}
+	for repo, pull := range pulls {
+		if pull["head"] != "jiri/"+branch || pull["base"].(map[string]any)["ref"] != "main" {
+			t.Errorf("unexpected pull request of %s: %v", repo, pull)
+		}
+		// Each pull request links to the other one.
+		body, _ := pull["body"].(string)
+		if !strings.Contains(body, "Topic: "+branch) || strings.Count(body, "https://github.com/") != 1 || strings.Contains(body, repo) {
+			t.Errorf("unexpected description of the pull request of %s:\n%s", repo, body)
+		}
+	}
+
+	// Uploading from a local branch named like the base branch is refused
+	// rather than overwriting the base branch of the remote.
+	git := gitutil.New(fake.X,
+		gitutil.RootDirOpt(localProjects[0].Path),
+		gitutil.UserNameOpt("John Doe"),
+		gitutil.UserEmailOpt("john.doe@example.com"))
+	if err := git.CreateBranchWithUpstream("main", "origin/main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Checkout("main"); err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, git, []string{"file-main"})
+	cmd = defaultUploadFlags()
+	fake.X.Cwd = localProjects[0].Path
+	if err := cmd.run(fake.X, []string{}); err == nil {
+		t.Fatal("expected uploading from branch main to fail")
+	}
+	assertUploadFilesNotPushedToRef(t, fake.X, fake.Projects[localProjects[0].Name], "main", []string{"file-main"})
+}
+
 func TestUploadMultipartWithBranchFlagSimple(t *testing.T) {
 	t.Parallel()
 
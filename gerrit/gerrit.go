@@ -34,6 +34,13 @@ var (
 	queryParameters = []string{"CURRENT_REVISION", "CURRENT_COMMIT", "CURRENT_FILES", "LABELS", "DETAILED_ACCOUNTS"}
 )
 
+// SubmittableOption is a query option which sets Change.Submittable.
+const SubmittableOption = "SUBMITTABLE"
+
+// AllRevisionsOption is a query option which adds every patchset of a change,
+// not only the current one, to Change.Revisions.
+const AllRevisionsOption = "ALL_REVISIONS"
+
 // Comment represents a single inline file comment.
 type Comment struct {
 	Line    int    `json:"line,omitempty"`
@@ -195,6 +202,11 @@ type Change struct {
 	Labels           map[string]map[string]any
 	Submitted        string
 
+	// Review state.
+	Status                   string
+	Submittable              bool
+	Unresolved_comment_count int
+
 	// Custom labels.
 	AutoSubmit    bool
 	MultiPart     *MultiPartCLInfo
@@ -202,6 +214,7 @@ type Change struct {
 }
 type Revisions map[string]Revision
 type Revision struct {
+	Number int `json:"_number"`
 	Fetch  `json:"fetch"`
 	Commit `json:"commit"`
 	Files  `json:"files"`
@@ -365,7 +378,10 @@ func makeRequest(method, url string, body io.Reader, cred *credentials) (*http.R
 // See the following links for more details about Gerrit search syntax:
 // - https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#list-changes
 // - https://gerrit-review.googlesource.com/Documentation/user-search.html
-func (g *Gerrit) Query(query string) (_ CLList, e error) {
+//
+// options are query options requested in addition to the default ones, such
+// as SubmittableOption.
+func (g *Gerrit) Query(query string, options ...string) (_ CLList, e error) {
 	u, err := url.Parse(g.host.String())
 	if err != nil {
 		return nil, err
@@ -381,6 +397,9 @@ func (g *Gerrit) Query(query string) (_ CLList, e error) {
 	for _, o := range queryParameters {
 		v.Add("o", o)
 	}
+	for _, o := range options {
+		v.Add("o", o)
+	}
 	u.RawQuery = v.Encode()
 	url := u.String()
 
@@ -402,9 +421,10 @@ func (g *Gerrit) ListChangesByCommit(commit string) (CLList, error) {
 	return g.Query(fmt.Sprintf("commit:%s", commit))
 }
 
-// GetChange returns a Change object for the given changeId number.
-func (g *Gerrit) GetChange(changeNumber int) (*Change, error) {
-	clList, err := g.Query(fmt.Sprintf("change:%d", changeNumber))
+// GetChange returns a Change object for the given changeId number, queried
+// with the given additional options.
+func (g *Gerrit) GetChange(changeNumber int, options ...string) (*Change, error) {
+	clList, err := g.Query(fmt.Sprintf("change:%d", changeNumber), options...)
 	if err != nil {
 		return nil, err
 	}
@@ -531,6 +551,68 @@ func (g *Gerrit) Submit(changeID string) (e error) {
 	return nil
 }
 
+// Abandon abandons the given change with an optional message.
+func (g *Gerrit) Abandon(changeID, message string) error {
+	return g.changeAction(changeID, "abandon", messageInput{message})
+}
+
+// Restore restores the given abandoned change with an optional message.
+func (g *Gerrit) Restore(changeID, message string) error {
+	return g.changeAction(changeID, "restore", messageInput{message})
+}
+
+// Rebase rebases the current patchset of the given change onto the tip of
+// its target branch, or of the change it depends on.
+func (g *Gerrit) Rebase(changeID string) error {
+	return g.changeAction(changeID, "rebase", struct{}{})
+}
+
+// AddReviewer adds the given account or group as a reviewer of the given
+// change.
+func (g *Gerrit) AddReviewer(changeID, reviewer string) error {
+	return g.changeAction(changeID, "reviewers", struct {
+		Reviewer string `json:"reviewer"`
+	}{reviewer})
+}
+
+type messageInput struct {
+	Message string `json:"message,omitempty"`
+}
+
+// changeAction posts input to the given action endpoint of a change.
+// https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html
+func (g *Gerrit) changeAction(changeID, action string, input any) (e error) {
+	cred, err := hostCredentials(g.jirix, g.host)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("Marshal(%#v) failed: %v", input, err)
+	}
+
+	url := fmt.Sprintf("%s/a/changes/%s/%s", g.host, changeID, action)
+	method, body := "POST", bytes.NewReader(data)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return fmt.Errorf("NewRequest(%q, %q, %v) failed: %v", method, url, body, err)
+	}
+	req.Header.Add("Content-Type", "application/json;charset=UTF-8")
+	req.SetBasicAuth(cred.username, cred.password)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Do(%v) failed: %v", req, err)
+	}
+	defer collect.Error(func() error { return res.Body.Close() }, &e)
+	if res.StatusCode != http.StatusOK {
+		// Gerrit explains why the action was refused, e.g. "change is
+		// merged" or "change is already up to date".
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s of change %s failed: %v %s", action, changeID, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
 // formatParams formats parameters of a change list.
 func formatParams(params []string, key string) []string {
 	var keyedParams []string
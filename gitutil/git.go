@@ -633,6 +633,32 @@ func (g *Git) LsRemote(args ...string) (string, error) {
 	return out[0], nil
 }
 
+// LsRemoteTags returns the commits the tags of a remote repository point
+// to, keyed by tag name. Annotated tags are peeled.
+func (g *Git) LsRemoteTags(remote string) (map[string]string, error) {
+	out, err := g.runOutput("ls-remote", "--tags", remote)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	peeled := make(map[string]bool)
+	for _, line := range out {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "refs/tags/") {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "refs/tags/")
+		if strings.HasSuffix(name, "^{}") {
+			name = strings.TrimSuffix(name, "^{}")
+			tags[name] = fields[0]
+			peeled[name] = true
+		} else if !peeled[name] {
+			tags[name] = fields[0]
+		}
+	}
+	return tags, nil
+}
+
 // CreateBranchWithUpstream creates a new branch and sets the upstream
 // repository to the given upstream.
 func (g *Git) CreateBranchWithUpstream(branch, upstream string) error {
@@ -933,6 +959,11 @@ func (g *Git) FilesWithUncommittedChanges() ([]string, error) {
 	return append(out, out2...), nil
 }
 
+// StagedFiles returns the files with changes in the staging area.
+func (g *Git) StagedFiles() ([]string, error) {
+	return g.runOutput("diff", "--cached", "--name-only", "--no-ext-diff")
+}
+
 // MergedBranches returns the list of all branches that were already merged.
 func (g *Git) MergedBranches(ref string) ([]string, error) {
 	branches, _, err := g.GetBranches("--merged", ref)
@@ -991,6 +1022,54 @@ func (g *Git) ListRemoteBranchesContainingRef(commit string) (map[string]bool, e
 	return m, nil
 }
 
+// RefsContainingCommit returns the full names of the refs matching any of
+// patterns which contain the given commit. Patterns are interpreted as by
+// "git for-each-ref".
+func (g *Git) RefsContainingCommit(commit string, patterns ...string) ([]string, error) {
+	args := append([]string{"for-each-ref", "--format", "%(refname)", "--contains", commit}, patterns...)
+	return g.runOutput(args...)
+}
+
+// IsAncestor returns whether commit is an ancestor of rev, or rev itself.
+func (g *Git) IsAncestor(commit, rev string) (bool, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"merge-base", "--is-ancestor", commit, rev}
+	if err := g.runGit(&stdout, &stderr, args...); err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, Error(stdout.String(), stderr.String(), err, g.rootDir, args...)
+	}
+	return true, nil
+}
+
+// CommitsWithChangeID returns the commits reachable from any ref whose
+// message has the given Change-Id footer.
+func (g *Git) CommitsWithChangeID(changeID string) ([]string, error) {
+	return g.runOutput("log", "--all", "--format=%H", "--grep", "^Change-Id: "+changeID+"$")
+}
+
+// CommitsWithPrefix returns the commits whose object names start with the
+// given hex prefix. Unlike revisions, refs whose names look like the prefix
+// are not matched.
+func (g *Git) CommitsWithPrefix(prefix string) ([]string, error) {
+	objects, err := g.runOutput("rev-parse", "--disambiguate="+prefix)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for _, object := range objects {
+		out, err := g.runOutput("cat-file", "-t", object)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) == 1 && out[0] == "commit" {
+			commits = append(commits, object)
+		}
+	}
+	return commits, nil
+}
+
 // ListBranchesContainingRef returns a slice of the local branches
 // which contains the given commit
 func (g *Git) ListBranchesContainingRef(commit string) (map[string]bool, error) {
@@ -1084,6 +1163,52 @@ func (g *Git) ShortStatus() (string, error) {
 	return strings.Join(out, "\n"), nil
 }
 
+// FileStatus is the status of a file as reported by "git status".
+type FileStatus struct {
+	// Code is the two letter status code, "??" for untracked files.
+	Code string
+	Path string
+	// OrigPath is the path a renamed or copied file is copied from.
+	OrigPath string
+}
+
+// PorcelainStatus returns the status of the changed and untracked files of
+// the working tree, as reported by "git status --porcelain -z".
+func (g *Git) PorcelainStatus() ([]FileStatus, error) {
+	args := []string{"status", "--porcelain", "-z"}
+	var stdout, stderr bytes.Buffer
+	if err := g.runGit(&stdout, &stderr, args...); err != nil {
+		return nil, Error(stdout.String(), stderr.String(), err, g.rootDir, args...)
+	}
+	return parsePorcelainStatus(stdout.String())
+}
+
+// parsePorcelainStatus parses the output of "git status --porcelain -z".
+func parsePorcelainStatus(out string) ([]FileStatus, error) {
+	var files []FileStatus
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if entry == "" {
+			continue
+		}
+		if len(entry) < 4 || entry[2] != ' ' {
+			return nil, fmt.Errorf("unexpected git status entry %q", entry)
+		}
+		file := FileStatus{Code: entry[:2], Path: entry[3:]}
+		// Renames and copies are followed by the path of their source.
+		if file.Code[0] == 'R' || file.Code[0] == 'C' {
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("missing source of git status entry %q", entry)
+			}
+			i++
+			file.OrigPath = fields[i]
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
 func (g *Git) CommitMsg(ref string) (string, error) {
 	out, err := g.runOutput("log", "-n", "1", "--format=format:%B", ref)
 	if err != nil {
@@ -1123,6 +1248,24 @@ func (g *Git) Log(branch, base, format string) ([][]string, error) {
 	return result, nil
 }
 
+// LogCommits returns the commits listed by "git log" with the given
+// arguments, such as revision ranges, --since or --author. Each commit is
+// returned as the lines of the specified format.
+func (g *Git) LogCommits(format string, args ...string) ([][]string, error) {
+	args = append([]string{"log", "-z", "--format=" + format}, args...)
+	var stdout, stderr bytes.Buffer
+	if err := g.runGit(&stdout, &stderr, args...); err != nil {
+		return nil, Error(stdout.String(), stderr.String(), err, g.rootDir, args...)
+	}
+	result := [][]string{}
+	for _, commit := range strings.Split(stdout.String(), "\x00") {
+		if commit = strings.TrimSpace(commit); commit != "" {
+			result = append(result, strings.Split(commit, "\n"))
+		}
+	}
+	return result, nil
+}
+
 // Merge merges all commits from <branch> to the current branch. If
 // <squash> is set, then all merged commits are squashed into a single
 // commit.
@@ -1206,10 +1349,13 @@ func (g *Git) Push(remote, branch string, opts ...PushOpt) error {
 	// TODO(youngseokyoon): consider making followTags option default to true, after verifying that
 	// it works well for the madb repository.
 	followTags := false
+	lease := ""
 	for _, opt := range opts {
 		switch typedOpt := opt.(type) {
 		case ForceOpt:
 			force = bool(typedOpt)
+		case ForceWithLeaseOpt:
+			lease = string(typedOpt)
 		case VerifyOpt:
 			verify = bool(typedOpt)
 		case FollowTagsOpt:
@@ -1218,6 +1364,8 @@ func (g *Git) Push(remote, branch string, opts ...PushOpt) error {
 	}
 	if force {
 		args = append(args, "--force")
+	} else if lease != "" {
+		args = append(args, "--force-with-lease="+lease)
 	}
 	if verify {
 		args = append(args, "--verify")
@@ -1235,16 +1383,22 @@ func (g *Git) Push(remote, branch string, opts ...PushOpt) error {
 func (g *Git) RebaseBranch(branch, upstream string, opts ...RebaseOpt) error {
 	args := []string{"rebase", "--keep-empty"}
 	rebaseMerges := false
+	onto := ""
 	for _, opt := range opts {
 		switch typedOpt := opt.(type) {
 		case RebaseMerges:
 			rebaseMerges = bool(typedOpt)
+		case OntoOpt:
+			onto = string(typedOpt)
 		}
 	}
 
 	if rebaseMerges {
 		args = append(args, "--rebase-merges")
 	}
+	if onto != "" {
+		args = append(args, "--onto", onto)
+	}
 	args = append(args, upstream)
 	if branch != "" {
 		args = append(args, branch)
@@ -1293,6 +1447,28 @@ func (g *Git) RebaseAbort() error {
 	return g.run("rebase", "--abort")
 }
 
+// IsRebaseInProgress returns whether a rebase is in progress.
+func (g *Git) IsRebaseInProgress() (bool, error) {
+	gitDir, err := g.AbsoluteGitDir()
+	if err != nil {
+		return false, err
+	}
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, dir)); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// RebaseContinue continues an in-progress rebase once the conflicts are
+// resolved, keeping the messages of the commits.
+func (g *Git) RebaseContinue() error {
+	return g.run("-c", "core.editor=true", "rebase", "--continue")
+}
+
 // Remove removes the given files.
 func (g *Git) Remove(fileNames ...string) error {
 	args := []string{"rm"}
@@ -1433,6 +1609,96 @@ func (g *Git) StashPop() error {
 	return g.run("stash", "pop")
 }
 
+// StashPush stashes the local changes with the given message, including the
+// untracked files if includeUntracked is set. It returns the revision of the
+// stash entry, or an empty string if there was nothing to stash.
+func (g *Git) StashPush(message string, includeUntracked bool) (string, error) {
+	oldSize, err := g.StashSize()
+	if err != nil {
+		return "", err
+	}
+	args := []string{"stash", "push"}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	if err := g.run(args...); err != nil {
+		return "", err
+	}
+	newSize, err := g.StashSize()
+	if err != nil {
+		return "", err
+	}
+	if newSize <= oldSize {
+		return "", nil
+	}
+	return g.CurrentRevisionForRef("stash@{0}")
+}
+
+// StashList returns the revisions of the stash entries, the most recent
+// first.
+func (g *Git) StashList() ([]string, error) {
+	return g.runOutput("stash", "list", "--format=%H")
+}
+
+// StashApply applies the stash entry with the given revision to the current
+// working tree, without removing it from the stash. If index is set, the
+// changes which were staged are staged again.
+func (g *Git) StashApply(rev string, index bool) error {
+	args := []string{"stash", "apply"}
+	if index {
+		args = append(args, "--index")
+	}
+	return g.run(append(args, rev)...)
+}
+
+// StashRestore applies the stash entry with the given revision to the
+// current working tree, without removing it from the stash. Staged changes
+// are staged again, unless the staged version of a file conflicts with the
+// working tree. "git stash apply --index" then fails without touching the
+// working tree, and the changes are applied unstaged.
+func (g *Git) StashRestore(rev string) error {
+	err := g.StashApply(rev, true)
+	if err != nil {
+		if files, _ := g.UnmergedFiles(); len(files) == 0 {
+			g.jirix.Logger.Debugf("Could not restore the staged changes of stash entry %s in %s: %s", rev, g.rootDir, err)
+			err = g.StashApply(rev, false)
+		}
+	}
+	return err
+}
+
+// StashDrop removes the stash entry with the given revision from the stash.
+func (g *Git) StashDrop(rev string) error {
+	revs, err := g.StashList()
+	if err != nil {
+		return err
+	}
+	for i, r := range revs {
+		if r == rev {
+			return g.run("stash", "drop", fmt.Sprintf("stash@{%d}", i))
+		}
+	}
+	return fmt.Errorf("stash entry %s not found", rev)
+}
+
+// StashUntrackedFiles returns the untracked files saved in the stash entry
+// with the given revision.
+func (g *Git) StashUntrackedFiles(rev string) ([]string, error) {
+	if _, err := g.runOutput("rev-parse", "--verify", "-q", rev+"^3"); err != nil {
+		// The entry does not include untracked files.
+		return nil, nil
+	}
+	return g.runOutput("ls-tree", "-r", "--name-only", rev+"^3")
+}
+
+// UnmergedFiles returns the files with unresolved conflicts.
+func (g *Git) UnmergedFiles() ([]string, error) {
+	return g.runOutput("diff", "--name-only", "--diff-filter=U")
+}
+
 // SubmoduleStatus returns the status of the modules for under the superproject.
 // If run under submodule directory, the directories of other submoudles will be
 // relative to the submodule rootDir.
@@ -48,6 +48,13 @@ type FollowTagsOpt bool
 
 func (FollowTagsOpt) pushOpt() {}
 
+// ForceWithLeaseOpt forces a push only if the remote ref has the expected
+// value, given as "<refname>:<expect>". An empty <expect> requires the
+// remote ref not to exist.
+type ForceWithLeaseOpt string
+
+func (ForceWithLeaseOpt) pushOpt() {}
+
 type ForceOpt bool
 
 func (ForceOpt) checkoutOpt()     {}
@@ -136,6 +143,11 @@ type RebaseMerges bool
 
 func (RebaseMerges) rebaseOpt() {}
 
+// OntoOpt rebases onto the given revision instead of the upstream.
+type OntoOpt string
+
+func (OntoOpt) rebaseOpt() {}
+
 type UpdateHeadOkOpt bool
 
 func (UpdateHeadOkOpt) fetchOpt() {}
@@ -0,0 +1,905 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/retry"
+)
+
+// packageStampFile is written into the directory of every package installed
+// by the http and oci sources. It records the installed digest so that
+// unchanged packages are not fetched again.
+const packageStampFile = ".jiri_package.json"
+
+type packageStamp struct {
+	PackageName string `json:"package"`
+	VersionTag  string `json:"version"`
+	Digest      string `json:"digest"`
+	// Files maps the slash separated path of each installed file to its
+	// digest.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// readPackageStamp returns the stamp of the package installed in dir, or nil
+// if there is none.
+func readPackageStamp(dir string) *packageStamp {
+	data, err := os.ReadFile(filepath.Join(dir, packageStampFile))
+	if err != nil {
+		return nil
+	}
+	var stamp packageStamp
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return nil
+	}
+	return &stamp
+}
+
+// installedDigest returns the digest of the package installed in dir, or an
+// empty string if there is none.
+func installedDigest(dir string) string {
+	if stamp := readPackageStamp(dir); stamp != nil {
+		return stamp.Digest
+	}
+	return ""
+}
+
+// hashTree returns the digests of the files in dir, keyed by their slash
+// separated path. Symlinks are recorded using their target.
+func hashTree(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case rel == packageStampFile:
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = "symlink:" + target
+		case info.Mode().IsRegular():
+			digest, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = digest
+		}
+		return nil
+	})
+	return files, err
+}
+
+// hashFile returns the sha256 digest of the content of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// installPackage replaces dir with a directory populated by fill and
+// records stamp in it.
+func installPackage(dir string, stamp packageStamp, fill func(tmpDir string) error) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), ".jiri-package-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.Chmod(tmpDir, 0755); err != nil {
+		return err
+	}
+	if err := fill(tmpDir); err != nil {
+		return err
+	}
+	if stamp.Files, err = hashTree(tmpDir); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stamp, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, packageStampFile), data, 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+// packageTarget is a package expanded for a single platform.
+type packageTarget struct {
+	name string
+	url  string
+	plat cipd.Platform
+}
+
+// expandTargets expands the name and url of Package p for each platform in
+// plats. Platforms which are not supported by the name are skipped.
+func (p *Package) expandTargets(plats []cipd.Platform) ([]packageTarget, error) {
+	if !cipd.MustExpand(p.Name) {
+		u, err := cipd.Expander{"version": p.Version}.Expand(p.URL)
+		if err != nil {
+			return nil, fmt.Errorf("expanding url of package %q failed: %v", p.Name, err)
+		}
+		return []packageTarget{{name: p.Name, url: u, plat: cipd.CipdPlatform}}, nil
+	}
+	var targets []packageTarget
+	for _, plat := range plats {
+		name, err := plat.Expander().Expand(p.Name)
+		if err == cipd.ErrSkipTemplate {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		expander := plat.Expander()
+		expander["version"] = p.Version
+		u, err := expander.Expand(p.URL)
+		if err == cipd.ErrSkipTemplate {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("expanding url of package %q failed: %v", p.Name, err)
+		}
+		targets = append(targets, packageTarget{name: name, url: u, plat: plat})
+	}
+	return targets, nil
+}
+
+// knownInstance returns the instance id of the instance called name of
+// Package p that is recorded in the lockfile or, for http packages, the
+// digest in the manifest.
+func (p *Package) knownInstance(name string) string {
+	for _, ins := range p.Instances {
+		if ins.Name == name {
+			return ins.ID
+		}
+	}
+	if p.Sha256 != "" {
+		return normalizeDigest(p.Sha256)
+	}
+	return ""
+}
+
+func normalizeDigest(digest string) string {
+	digest = strings.ToLower(digest)
+	if !strings.HasPrefix(digest, "sha256:") {
+		digest = "sha256:" + digest
+	}
+	return digest
+}
+
+func sha256Digest(data []byte) string {
+	hash := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+func newHTTPClient(fetchTimeout uint) *http.Client {
+	return &http.Client{Timeout: time.Duration(fetchTimeout) * time.Minute}
+}
+
+// httpStatusError is returned by openURL for requests that were answered
+// with a client error.
+type httpStatusError struct {
+	url    string
+	status string
+	code   int
+	header http.Header
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("fetching %q failed: got non-success response: %s", e.url, e.status)
+}
+
+// openURL opens rawurl for reading. Both http(s) and file URLs are
+// supported. Requests are retried on network and server errors.
+func openURL(jirix *jiri.X, client *http.Client, rawurl string, header http.Header) (io.ReadCloser, http.Header, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		f, err := os.Open(filepath.FromSlash(u.Path))
+		return f, nil, err
+	case "http", "https":
+	default:
+		return nil, nil, fmt.Errorf("unsupported url scheme in %q", rawurl)
+	}
+
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", cipd.UserAgent())
+	var resp *http.Response
+	var statusErr error
+	if err := retry.Function(jirix, func() error {
+		r, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if r.StatusCode >= 500 {
+			r.Body.Close()
+			return fmt.Errorf("fetching %q failed: got non-success response: %s", rawurl, r.Status)
+		}
+		if r.StatusCode >= 400 {
+			// Client errors will not go away by retrying.
+			r.Body.Close()
+			statusErr = &httpStatusError{url: rawurl, status: r.Status, code: r.StatusCode, header: r.Header}
+			return nil
+		}
+		resp = r
+		return nil
+	}, fmt.Sprintf("fetching %q", rawurl), retry.AttemptsOpt(3)); err != nil {
+		return nil, nil, err
+	}
+	if statusErr != nil {
+		return nil, nil, statusErr
+	}
+	return resp.Body, resp.Header, nil
+}
+
+// saveToTemp writes the content of body into a new temporary file and
+// returns its path together with the digest of the content.
+func saveToTemp(body io.ReadCloser) (string, string, error) {
+	defer body.Close()
+	f, err := os.CreateTemp("", "jiri-package-*")
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", "", err
+	}
+	return f.Name(), "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// httpSource fetches packages from tar, tar.gz or zip archives served over
+// http(s) or from the local filesystem. Archives are verified using the
+// digest recorded in the lockfile or in the "sha256" attribute.
+type httpSource struct{}
+
+func (httpSource) Resolve(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
+	// Archives are downloaded to be hashed, bound the time it takes like
+	// fetching them.
+	client := newHTTPClient(DefaultPackageTimeout)
+	pkgLocks := make(PackageLocks)
+	for _, pkg := range pkgs {
+		plats, err := pkg.GetPlatforms()
+		if err != nil {
+			return nil, err
+		}
+		targets, err := pkg.expandTargets(plats)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			body, _, err := openURL(jirix, client, t.url, nil)
+			if err != nil {
+				return nil, err
+			}
+			file, digest, err := saveToTemp(body)
+			if err != nil {
+				return nil, err
+			}
+			os.Remove(file)
+			if pkg.Sha256 != "" && normalizeDigest(pkg.Sha256) != digest {
+				return nil, fmt.Errorf("package %q: digest of %q is %s, expected %s", t.name, t.url, digest, normalizeDigest(pkg.Sha256))
+			}
+			pkgLock := PackageLock{
+				PackageName: t.name,
+				VersionTag:  pkg.Version,
+				InstanceID:  digest,
+				Source:      PackageSourceHTTP,
+				Digest:      digest,
+			}
+			pkgLocks[pkgLock.Key()] = pkgLock
+		}
+	}
+	return pkgLocks, nil
+}
+
+func (httpSource) Fetch(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error {
+	plats, err := fetchPlatforms(jirix)
+	if err != nil {
+		return err
+	}
+	client := newHTTPClient(fetchTimeout)
+	for _, pkg := range pkgs {
+		targets, err := pkg.fetchTargets(plats)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			jirix.Logger.Debugf("Package %q is not available on %v, skipped", pkg.Name, plats)
+		}
+		for _, t := range targets {
+			if err := fetchHTTPPackage(jirix, client, pkg, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fetchHTTPPackage(jirix *jiri.X, client *http.Client, pkg Package, t fetchTarget) error {
+	subdir := t.subdir
+	dir := filepath.Join(jirix.Root, subdir)
+	want := pkg.knownInstance(t.name)
+	if want == "" {
+		return fmt.Errorf("package %q has no digest to verify against, please run \"jiri resolve\" or set the \"sha256\" attribute", t.name)
+	}
+	if installedDigest(dir) == want {
+		jirix.Logger.Debugf("Package %q is up to date in %q", t.name, subdir)
+		return nil
+	}
+	jirix.Logger.Debugf("Fetching package %q from %q", t.name, t.url)
+	body, _, err := openURL(jirix, client, t.url, nil)
+	if err != nil {
+		return err
+	}
+	file, digest, err := saveToTemp(body)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	if digest != want {
+		return fmt.Errorf("package %q: digest of %q is %s, expected %s", t.name, t.url, digest, want)
+	}
+	stamp := packageStamp{PackageName: t.name, VersionTag: pkg.Version, Digest: digest}
+	if err := installPackage(dir, stamp, func(tmpDir string) error {
+		return extractArchive(file, tmpDir)
+	}); err != nil {
+		return fmt.Errorf("installing package %q failed: %v", t.name, err)
+	}
+	return nil
+}
+
+func (httpSource) Verify(jirix *jiri.X, pkgs Packages) ([]PackageStatus, error) {
+	return verifyStampedPackages(jirix, pkgs, PackageSourceHTTP)
+}
+
+// verifyStampedPackages compares the packages installed by the http and oci
+// sources with their stamps, reporting the status of the instance installed
+// for each of the fetch platforms.
+func verifyStampedPackages(jirix *jiri.X, pkgs Packages, source string) ([]PackageStatus, error) {
+	plats, err := fetchPlatforms(jirix)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []PackageStatus
+	for _, pkg := range pkgs {
+		targets, err := pkg.fetchTargets(plats)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			status := PackageStatus{
+				Name:     t.name,
+				Path:     t.subdir,
+				Source:   source,
+				LockedID: pkg.knownInstance(t.name),
+			}
+			dir := filepath.Join(jirix.Root, t.subdir)
+			if stamp := readPackageStamp(dir); stamp != nil {
+				status.InstalledID = stamp.Digest
+				current, err := hashTree(dir)
+				if err != nil {
+					return nil, err
+				}
+				for name, digest := range stamp.Files {
+					if d, ok := current[name]; !ok {
+						status.MissingFiles = append(status.MissingFiles, name)
+					} else if d != digest {
+						status.ModifiedFiles = append(status.ModifiedFiles, name)
+					}
+				}
+			}
+			statuses = append(statuses, status.finish())
+		}
+	}
+	return statuses, nil
+}
+
+// Media types understood by ociSource.
+const (
+	ociManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType           = "application/vnd.oci.image.index.v1+json"
+	dockerManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+type ociPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+}
+
+// ociManifest holds the fields shared by image manifests and image indexes.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociClient talks to the distribution API of an OCI registry. Anonymous
+// bearer tokens are requested when the registry asks for them.
+type ociClient struct {
+	jirix    *jiri.X
+	client   *http.Client
+	registry string
+	token    string
+}
+
+var ociChallengeParamRE = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+func (c *ociClient) get(repo, kind, ref string, accept ...string) (io.ReadCloser, http.Header, error) {
+	u := fmt.Sprintf("%s/v2/%s/%s/%s", strings.TrimRight(c.registry, "/"), repo, kind, ref)
+	header := make(http.Header)
+	if len(accept) != 0 {
+		header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	body, respHeader, err := openURL(c.jirix, c.client, u, header)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusUnauthorized && c.token == "" {
+		if err := c.fetchToken(statusErr.header.Get("WWW-Authenticate")); err != nil {
+			return nil, nil, err
+		}
+		header.Set("Authorization", "Bearer "+c.token)
+		return openURL(c.jirix, c.client, u, header)
+	}
+	return body, respHeader, err
+}
+
+func (c *ociClient) fetchToken(challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("unsupported authentication challenge %q from %q", challenge, c.registry)
+	}
+	params := make(map[string]string)
+	for _, m := range ociChallengeParamRE.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("authentication challenge %q from %q has no realm", challenge, c.registry)
+	}
+	query := make(url.Values)
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			query.Set(k, params[k])
+		}
+	}
+	body, _, err := openURL(c.jirix, c.client, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var resp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return fmt.Errorf("parsing token from %q failed: %v", params["realm"], err)
+	}
+	c.token = resp.Token
+	if c.token == "" {
+		c.token = resp.AccessToken
+	}
+	return nil
+}
+
+// resolveManifest returns the digest and content of the image manifest of
+// repo at ref. Image indexes are resolved to the manifest for plat.
+func (c *ociClient) resolveManifest(repo, ref string, plat cipd.Platform) (string, *ociManifest, error) {
+	body, header, err := c.get(repo, "manifests", ref, ociManifestMediaType, ociIndexMediaType, dockerManifestMediaType, dockerManifestListMediaType)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	digest := sha256Digest(data)
+	if strings.HasPrefix(ref, "sha256:") && digest != ref {
+		return "", nil, fmt.Errorf("manifest of %q has digest %s, expected %s", repo, digest, ref)
+	}
+	if d := header.Get("Docker-Content-Digest"); d != "" && d != digest {
+		return "", nil, fmt.Errorf("manifest of %q has digest %s, registry reported %s", repo, digest, d)
+	}
+	var m ociManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", nil, fmt.Errorf("parsing manifest of %q failed: %v", repo, err)
+	}
+	if len(m.Manifests) != 0 {
+		os := plat.OS
+		if os == "mac" {
+			os = "darwin"
+		}
+		for _, desc := range m.Manifests {
+			if desc.Platform != nil && desc.Platform.OS == os && desc.Platform.Architecture == plat.Arch {
+				return c.resolveManifest(repo, desc.Digest, plat)
+			}
+		}
+		return "", nil, fmt.Errorf("image index of %q at %q has no manifest for %v", repo, ref, plat)
+	}
+	return digest, &m, nil
+}
+
+// ociSource fetches packages stored as artifacts in an OCI registry. The
+// package name is the repository, the version is a tag or a digest and the
+// url is the registry. Layers are extracted when they are archives and
+// stored under their title annotation otherwise.
+type ociSource struct{}
+
+func (ociSource) Resolve(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
+	client := newHTTPClient(DefaultPackageTimeout)
+	pkgLocks := make(PackageLocks)
+	for _, pkg := range pkgs {
+		plats, err := pkg.GetPlatforms()
+		if err != nil {
+			return nil, err
+		}
+		targets, err := pkg.expandTargets(plats)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			c := &ociClient{jirix: jirix, client: client, registry: t.url}
+			digest, _, err := c.resolveManifest(t.name, pkg.Version, t.plat)
+			if err != nil {
+				return nil, err
+			}
+			pkgLock := PackageLock{
+				PackageName: t.name,
+				VersionTag:  pkg.Version,
+				InstanceID:  digest,
+				Source:      PackageSourceOCI,
+				Digest:      digest,
+			}
+			pkgLocks[pkgLock.Key()] = pkgLock
+		}
+	}
+	return pkgLocks, nil
+}
+
+func (ociSource) Verify(jirix *jiri.X, pkgs Packages) ([]PackageStatus, error) {
+	return verifyStampedPackages(jirix, pkgs, PackageSourceOCI)
+}
+
+func (ociSource) Fetch(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error {
+	plats, err := fetchPlatforms(jirix)
+	if err != nil {
+		return err
+	}
+	client := newHTTPClient(fetchTimeout)
+	for _, pkg := range pkgs {
+		targets, err := pkg.fetchTargets(plats)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			jirix.Logger.Debugf("Package %q is not available on %v, skipped", pkg.Name, plats)
+		}
+		for _, t := range targets {
+			if err := fetchOCIPackage(jirix, client, pkg, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fetchOCIPackage(jirix *jiri.X, client *http.Client, pkg Package, t fetchTarget) error {
+	subdir := t.subdir
+	dir := filepath.Join(jirix.Root, subdir)
+	ref := pkg.knownInstance(t.name)
+	if ref != "" && installedDigest(dir) == ref {
+		jirix.Logger.Debugf("Package %q is up to date in %q", t.name, subdir)
+		return nil
+	}
+	if ref == "" {
+		ref = pkg.Version
+	}
+	c := &ociClient{jirix: jirix, client: client, registry: t.url}
+	digest, m, err := c.resolveManifest(t.name, ref, t.plat)
+	if err != nil {
+		return err
+	}
+	if installedDigest(dir) == digest {
+		jirix.Logger.Debugf("Package %q is up to date in %q", t.name, subdir)
+		return nil
+	}
+	jirix.Logger.Debugf("Fetching package %q from %q", t.name, t.url)
+	files := make([]string, len(m.Layers))
+	for i, layer := range m.Layers {
+		body, _, err := c.get(t.name, "blobs", layer.Digest)
+		if err != nil {
+			return err
+		}
+		file, digest, err := saveToTemp(body)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file)
+		if digest != layer.Digest {
+			return fmt.Errorf("package %q: layer has digest %s, expected %s", t.name, digest, layer.Digest)
+		}
+		files[i] = file
+	}
+	stamp := packageStamp{PackageName: t.name, VersionTag: pkg.Version, Digest: digest}
+	if err := installPackage(dir, stamp, func(tmpDir string) error {
+		for i, layer := range m.Layers {
+			title := layer.Annotations[ociTitleAnnotation]
+			if title == "" || strings.Contains(layer.MediaType, "tar") {
+				if err := extractArchive(files[i], tmpDir); err != nil {
+					return err
+				}
+				continue
+			}
+			target, err := archiveTarget(tmpDir, title)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(files[i])
+			if err != nil {
+				return err
+			}
+			err = writeArchiveFile(target, f, 0644)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("installing package %q failed: %v", t.name, err)
+	}
+	return nil
+}
+
+// extractArchive extracts the tar, tar.gz or zip archive file into dest.
+func extractArchive(file, dest string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dest)
+	case bytes.Equal(magic, []byte("PK\x03\x04")):
+		return extractZip(file, dest)
+	default:
+		return extractTar(r, dest)
+	}
+}
+
+// archiveTarget returns the path of archive entry name under dest. It
+// rejects entries that would be written outside of dest, either directly or
+// through the symlinks extracted before them.
+func archiveTarget(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if !withinDir(dest, target) {
+		return "", fmt.Errorf("archive entry %q points outside of the package", name)
+	}
+	realDest, parent, err := realParent(dest, target)
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(realDest, parent) {
+		return "", fmt.Errorf("archive entry %q points outside of the package", name)
+	}
+	return target, nil
+}
+
+func withinDir(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// realParent returns dest and the parent directory of target with their
+// symlinks resolved. Only the existing part of the parent is resolved, the
+// rest of it is created as plain directories during the extraction.
+func realParent(dest, target string) (string, string, error) {
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return "", "", err
+	}
+	dir, rest := filepath.Dir(target), ""
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return realDest, filepath.Join(resolved, rest), nil
+		}
+		if !os.IsNotExist(err) || dir == dest {
+			return "", "", err
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = filepath.Dir(dir)
+	}
+}
+
+func writeArchiveFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Replace a symlink extracted earlier rather than writing through it.
+	if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeArchiveSymlink(dest, target, link string) error {
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("archive symlink %q points outside of the package", link)
+	}
+	// The link is relative to the directory it really is created in.
+	realDest, parent, err := realParent(dest, target)
+	if err != nil {
+		return err
+	}
+	if !withinDir(realDest, filepath.Join(parent, link)) {
+		return fmt.Errorf("archive symlink %q points outside of the package", link)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(link, target)
+}
+
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := archiveTarget(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeArchiveSymlink(dest, target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := archiveTarget(dest, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(file, dest string) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		target, err := archiveTarget(dest, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		if mode&os.ModeSymlink != 0 {
+			var link []byte
+			if link, err = io.ReadAll(rc); err == nil {
+				err = writeArchiveSymlink(dest, target, string(link))
+			}
+		} else {
+			err = writeArchiveFile(target, rc, mode.Perm())
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"golang.org/x/sync/errgroup"
+)
+
+// autoStashMessage is the message of the stash entries created by
+// "jiri update -autostash".
+const autoStashMessage = "jiri update autostash"
+
+// autoStash is the stash entry holding the local changes of a project
+// during an update.
+type autoStash struct {
+	project Project
+	// revision is the revision of the stash entry.
+	revision string
+}
+
+// autoStashProjects stashes the uncommitted changes and untracked files of
+// the projects which are going to be checked out or rebased by ops, if
+// autostash is set or enabled in their local config, so that they can be
+// updated.
+func autoStashProjects(jirix *jiri.X, ops operations, autostash bool) ([]autoStash, error) {
+	var mu sync.Mutex
+	var stashes []autoStash
+	var eg errgroup.Group
+	limit := make(chan struct{}, jirix.Jobs)
+	for _, op := range ops {
+		switch op.Kind() {
+		case updateOpKind, moveOpKind, changeRemoteOpKind:
+		default:
+			continue
+		}
+		project := op.Project()
+		if !autostash && !project.LocalConfig.AutoStash {
+			continue
+		}
+		if project.LocalConfig.Ignore || project.LocalConfig.NoUpdate {
+			continue
+		}
+		source := op.Source()
+		limit <- struct{}{}
+		eg.Go(func() error {
+			defer func() { <-limit }()
+			scm := gitutil.New(jirix, gitutil.RootDirOpt(source))
+			uncommitted, err := scm.HasUncommittedChanges()
+			if err != nil {
+				return fmt.Errorf("Cannot get uncommitted changes for project %q: %s", project.Name, err)
+			}
+			untracked, err := scm.HasUntrackedFiles()
+			if err != nil {
+				return fmt.Errorf("Cannot get untracked files for project %q: %s", project.Name, err)
+			}
+			if !uncommitted && !untracked {
+				return nil
+			}
+			rev, err := scm.StashPush(autoStashMessage, true)
+			if err != nil || rev == "" {
+				return err
+			}
+			jirix.Logger.Debugf("Stashed the local changes of project %s(%s) in %s", project.Name, source, rev)
+			mu.Lock()
+			stashes = append(stashes, autoStash{project: project, revision: rev})
+			mu.Unlock()
+			return nil
+		})
+	}
+	err := eg.Wait()
+	sort.Slice(stashes, func(i, j int) bool { return stashes[i].project.Path < stashes[j].project.Path })
+	return stashes, err
+}
+
+// restoreAutoStashes re-applies the changes stashed by autoStashProjects
+// once the projects are updated. If the changes of a project conflict with
+// its new revision, the project is left clean, its stash entry is kept and
+// the project is listed in the summary logged at the end.
+func restoreAutoStashes(jirix *jiri.X, stashes []autoStash) {
+	var mu sync.Mutex
+	var conflicts []string
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, jirix.Jobs)
+	for _, stash := range stashes {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(stash autoStash) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			if msg := restoreAutoStash(jirix, stash); msg != "" {
+				mu.Lock()
+				conflicts = append(conflicts, msg)
+				mu.Unlock()
+			}
+		}(stash)
+	}
+	wg.Wait()
+	if len(conflicts) == 0 {
+		return
+	}
+	sort.Strings(conflicts)
+	msg := "The local changes of the following projects conflict with their update and were kept in their stash.\n"
+	msg += "Apply them manually with the given commands:\n  " + strings.Join(conflicts, "\n  ") + "\n\n"
+	jirix.Logger.Errorf("%s", msg)
+}
+
+// restoreAutoStash re-applies the changes of stash and drops it. It returns
+// a description of the project if they could not be applied.
+func restoreAutoStash(jirix *jiri.X, stash autoStash) string {
+	project := stash.project
+	relativePath, err := filepath.Rel(jirix.Cwd, project.Path)
+	if err != nil {
+		relativePath = project.Path
+	}
+	scm := gitutil.New(jirix, gitutil.RootDirOpt(project.Path))
+	untracked, err := scm.StashUntrackedFiles(stash.revision)
+	if err != nil {
+		jirix.IncrementFailures()
+		return fmt.Sprintf("%s(%s): %s", project.Name, relativePath, jirix.Color.Yellow("git -C %q stash apply %s", relativePath, stash.revision))
+	}
+	// The untracked files of the stash entry which do not exist in the
+	// updated project, to remove if the changes cannot be applied.
+	var created []string
+	for _, file := range untracked {
+		if _, err := os.Lstat(filepath.Join(project.Path, file)); os.IsNotExist(err) {
+			created = append(created, file)
+		}
+	}
+	if err := scm.StashRestore(stash.revision); err != nil {
+		jirix.IncrementFailures()
+		if files, _ := scm.UnmergedFiles(); len(files) != 0 {
+			jirix.Logger.Debugf("Project %s(%s) has conflicts in %s", project.Name, relativePath, strings.Join(files, ", "))
+		}
+		// Leave the project as updated.
+		if err := scm.Reset("HEAD"); err != nil {
+			jirix.Logger.Errorf("Failed to reset project %s(%s) after a conflict: %s\n\n", project.Name, relativePath, err)
+		}
+		for _, file := range created {
+			if err := os.Remove(filepath.Join(project.Path, file)); err != nil && !os.IsNotExist(err) {
+				jirix.Logger.Errorf("Failed to remove %s from project %s(%s): %s\n\n", file, project.Name, relativePath, err)
+			}
+		}
+		return fmt.Sprintf("%s(%s): %s", project.Name, relativePath, jirix.Color.Yellow("git -C %q stash apply %s", relativePath, stash.revision))
+	}
+	if err := scm.StashDrop(stash.revision); err != nil {
+		jirix.Logger.Warningf("Failed to drop stash entry %s of project %s(%s): %s\n\n", stash.revision, project.Name, relativePath, err)
+	}
+	return ""
+}
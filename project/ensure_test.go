@@ -0,0 +1,87 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/jiritest/xtest"
+)
+
+func TestGenerateEnsureFileForPlatforms(t *testing.T) {
+	jirix := xtest.NewX(t)
+	other := cipd.Platform{OS: "fakeos", Arch: "arm64"}
+	tool := Package{
+		Name:      "test/tool/${platform}",
+		Version:   "version:1",
+		Path:      "prebuilt/tool/{{.OS}}-{{.Arch}}",
+		Platforms: cipd.CipdPlatform.String() + "," + other.String(),
+		Instances: []PackageInstance{{Name: "test/tool/" + other.String(), ID: "tool-id"}},
+	}
+	pkgs := Packages{}
+	for _, pkg := range []Package{
+		tool,
+		{
+			// The instance for other would be installed to the same
+			// directory as the one for the current platform.
+			Name:      "test/shared/${platform}",
+			Version:   "version:1",
+			Path:      "prebuilt/shared",
+			Platforms: cipd.CipdPlatform.String() + "," + other.String(),
+		},
+		{
+			Name:      "test/unsupported/${platform}",
+			Version:   "version:1",
+			Path:      "prebuilt/unsupported/{{.OS}}",
+			Platforms: cipd.CipdPlatform.String(),
+		},
+	} {
+		pkgs[pkg.Key()] = pkg
+	}
+
+	jirix.LockfileEnabled = true
+	path, err := generateEnsureFile(jirix, pkgs, true, "", []cipd.Platform{cipd.CipdPlatform, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if want := "@Subdir prebuilt/tool/fakeos-arm64\ntest/tool/fakeos-arm64 version:1\n"; !strings.Contains(content, want) {
+		t.Errorf("ensure file does not contain %q:\n%s", want, content)
+	}
+	if strings.Contains(content, "test/shared/fakeos-arm64") || strings.Contains(content, "prebuilt/unsupported/fakeos") {
+		t.Errorf("ensure file contains unexpected declarations:\n%s", content)
+	}
+
+	// Platforms missing from the lockfile are reported.
+	tool.Instances = nil
+	pkgs[tool.Key()] = tool
+	if _, err := generateEnsureFile(jirix, pkgs, true, "", []cipd.Platform{cipd.CipdPlatform, other}); err == nil || !strings.Contains(err.Error(), "jiri resolve") {
+		t.Errorf("expected missing lock error, got %v", err)
+	}
+
+	// With snapshots, locked instances are used directly.
+	tool.Instances = []PackageInstance{{Name: "test/tool/" + other.String(), ID: "tool-id"}}
+	pkgs[tool.Key()] = tool
+	jirix.UsingSnapshot = true
+	path, err = generateEnsureFile(jirix, pkgs, true, "", []cipd.Platform{cipd.CipdPlatform, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	if data, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if want := "test/tool/fakeos-arm64 tool-id\n"; !strings.Contains(string(data), want) {
+		t.Errorf("ensure file does not contain %q:\n%s", want, data)
+	}
+}
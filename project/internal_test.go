@@ -6,3 +6,9 @@ package project
 
 // InternalWriteMetadata exports writeMetadata for tests.
 var InternalWriteMetadata = writeMetadata
+
+// InternalCheckPackagePaths exports checkPackagePaths for tests.
+var InternalCheckPackagePaths = checkPackagePaths
+
+// InternalExtractArchive exports extractArchive for tests.
+var InternalExtractArchive = extractArchive
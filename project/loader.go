@@ -435,7 +435,7 @@ func (ld *loader) parseLockData(jirix *jiri.X, data []byte) error {
 
 	for k, v := range projectLocks {
 		if projLock, ok := ld.ProjectLocks[k]; ok {
-			if projLock != v && !jirix.UsingImportOverride {
+			if !projLock.LockEqual(v) && !jirix.UsingImportOverride {
 				return fmt.Errorf("conflicting project lock entries %+v with %+v", projLock, v)
 			}
 		} else {
@@ -677,6 +677,9 @@ func (ld *loader) load(jirix *jiri.X, root, repoPath, file, ref string, parentIm
 		pkg.Attributes = pkg.ComputedAttributes.String()
 		// Record manifest location.
 		pkg.ManifestPath = f
+		if err := pkg.validate(); err != nil {
+			return fmt.Errorf("invalid package %q in manifest %s: %v", pkg.Name, file, err)
+		}
 		key := pkg.Key()
 		if val, ok := ld.Packages[key]; ok {
 			// Package with same remote url and local path already exists in manifest.
@@ -0,0 +1,274 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+)
+
+// LatestLockFileVersion is the newest version of the lockfile format.
+//
+// Version 1 lockfiles are a json list of project and package locks. Version
+// 2 lockfiles are a json object which records the version of the format and
+// lists project and package locks separately. Package locks of version 2
+// are keyed by their package name, path and platform and all locks record
+// the manifest that requested them.
+const LatestLockFileVersion = 2
+
+// lockFileV2 is the json representation of version 2 lockfiles.
+type lockFileV2 struct {
+	Version  int           `json:"version"`
+	Projects []ProjectLock `json:"projects"`
+	Packages []PackageLock `json:"packages"`
+}
+
+// LockFileVersion returns the version of the lockfile format used by
+// jsonData.
+func LockFileVersion(jsonData []byte) (int, error) {
+	data := bytes.TrimSpace(jsonData)
+	if len(data) == 0 || data[0] == '[' {
+		return 1, nil
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version < 2 || header.Version > LatestLockFileVersion {
+		return 0, fmt.Errorf("unsupported lockfile version %d", header.Version)
+	}
+	return header.Version, nil
+}
+
+func unmarshalLockFile(jsonData []byte) (ProjectLocks, PackageLocks, error) {
+	var file lockFileV2
+	if err := json.Unmarshal(jsonData, &file); err != nil {
+		return nil, nil, err
+	}
+	projectLocks := make(ProjectLocks)
+	for _, v := range file.Projects {
+		if e, ok := projectLocks[v.Key()]; ok && !e.LockEqual(v) {
+			return nil, nil, fmt.Errorf("project %q has more than 1 revision lock %q, %q", v.Remote, e.Revision, v.Revision)
+		}
+		projectLocks[v.Key()] = v
+	}
+	pkgLocks := make(PackageLocks)
+	for _, v := range file.Packages {
+		if e, ok := pkgLocks[v.Key()]; ok && !e.LockEqual(v) {
+			return nil, nil, fmt.Errorf("package %q has more than 1 version lock at path %q for platform %q: %q, %q", v.PackageName, v.LocalPath, v.Platform, e.InstanceID, v.InstanceID)
+		}
+		pkgLocks[v.Key()] = v
+	}
+	return projectLocks, pkgLocks, nil
+}
+
+func marshalLockFileV2(projectLocks ProjectLocks, pkgLocks PackageLocks) ([]byte, error) {
+	file := lockFileV2{
+		Version:  2,
+		Projects: sortedProjectLocks(projectLocks),
+		Packages: sortedPackageLocks(pkgLocks),
+	}
+	return json.MarshalIndent(&file, "", "    ")
+}
+
+func sortedProjectLocks(projectLocks ProjectLocks) []ProjectLock {
+	ret := make([]ProjectLock, 0, len(projectLocks))
+	for _, v := range projectLocks {
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Remote == ret[j].Remote {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Remote < ret[j].Remote
+	})
+	return ret
+}
+
+func sortedPackageLocks(pkgLocks PackageLocks) []PackageLock {
+	ret := make([]PackageLock, 0, len(pkgLocks))
+	for _, v := range pkgLocks {
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].PackageName != ret[j].PackageName {
+			return ret[i].PackageName < ret[j].PackageName
+		}
+		if ret[i].LocalPath != ret[j].LocalPath {
+			return ret[i].LocalPath < ret[j].LocalPath
+		}
+		if ret[i].Platform != ret[j].Platform {
+			return ret[i].Platform < ret[j].Platform
+		}
+		return ret[i].VersionTag < ret[j].VersionTag
+	})
+	return ret
+}
+
+// v1 returns p without the fields version 1 lockfiles do not record.
+func (p ProjectLock) v1() ProjectLock {
+	return ProjectLock{Remote: p.Remote, Name: p.Name, Revision: p.Revision}
+}
+
+// v1 returns p without the fields version 1 lockfiles do not record.
+func (p PackageLock) v1() PackageLock {
+	p.Platform = ""
+	p.Manifest = ""
+	p.Ref = ""
+	p.ResolvedAt = ""
+	return p
+}
+
+// lockTarget is an expanded name of a package together with the platform
+// recorded in its lock, which is empty if the package name does not use
+// platform templates.
+type lockTarget struct {
+	name     string
+	platform string
+}
+
+// lockTargets returns the lock targets of Package p for the platforms it
+// supports.
+func (p *Package) lockTargets() ([]lockTarget, error) {
+	if !cipd.MustExpand(p.Name) {
+		return []lockTarget{{name: p.Name}}, nil
+	}
+	plats, err := p.GetPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	var ret []lockTarget
+	for _, plat := range plats {
+		names, err := cipd.Expand(p.Name, []cipd.Platform{plat})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			ret = append(ret, lockTarget{name: name, platform: plat.String()})
+		}
+	}
+	return ret, nil
+}
+
+// manifestRelPath returns the path of manifest file relative to the jiri
+// root, if it is inside of it.
+func manifestRelPath(jirix *jiri.X, file string) string {
+	if file == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(jirix.Root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(file)
+}
+
+// lockProjectsV2 records the manifest of each project in projectLocks and
+// keeps the time of existing locks that did not change.
+func lockProjectsV2(jirix *jiri.X, projects Projects, projectLocks, existing ProjectLocks, now string) {
+	for _, p := range projects {
+		key := ProjectLockKey(p.Key())
+		lock, ok := projectLocks[key]
+		if !ok {
+			continue
+		}
+		lock.Manifest = manifestRelPath(jirix, p.ManifestPath)
+		if e, ok := existing[key]; ok && e.LockEqual(lock) {
+			if lock.Ref == "" {
+				lock.Ref = e.Ref
+			}
+			lock.ResolvedAt = e.ResolvedAt
+		}
+		if lock.ResolvedAt == "" {
+			lock.ResolvedAt = now
+		}
+		projectLocks[key] = lock
+	}
+}
+
+// lockPackagesV2 returns the locks of pkgs for every platform they support,
+// recording their path, platform and manifest, using the instances in
+// resolved.
+func lockPackagesV2(jirix *jiri.X, pkgs Packages, resolved PackageLocks, now string) (PackageLocks, error) {
+	ret := make(PackageLocks)
+	for _, pkg := range pkgs {
+		targets, err := pkg.lockTargets()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			lock, ok := resolved.find(t.name, pkg.Version, pkg.Path, t.platform)
+			if !ok {
+				jirix.Logger.Debugf("Package %q is not resolved", t.name)
+				continue
+			}
+			lock.LocalPath = pkg.Path
+			lock.Platform = t.platform
+			lock.Manifest = manifestRelPath(jirix, pkg.ManifestPath)
+			if lock.ResolvedAt == "" {
+				lock.ResolvedAt = now
+			}
+			ret[lock.Key()] = lock
+		}
+	}
+	return ret, nil
+}
+
+// resolveConstrainedPackageLocks resolves the instances of pkgs, resolving
+// their version constraints first. The locks of constrained packages are
+// keyed by their constraints.
+func resolveConstrainedPackageLocks(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
+	tags, err := resolveConstrainedPackages(jirix, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	concretePkgs := make(Packages)
+	for k, v := range pkgs {
+		if tag, ok := tags[k]; ok {
+			v.Version = tag
+		}
+		concretePkgs[k] = v
+	}
+	pkgLocks, err := resolvePackageLocks(jirix, concretePkgs)
+	if err != nil {
+		return nil, err
+	}
+	return lockConstraints(pkgs, tags, pkgLocks)
+}
+
+// LockPackages resolves pkgs and returns their locks as "jiri resolve"
+// records them in a lockfile of the given version. With recordTime, version
+// 2 locks record the time they were resolved at.
+func LockPackages(jirix *jiri.X, pkgs Packages, version int, recordTime bool) (PackageLocks, error) {
+	pkgLocks, err := resolveConstrainedPackageLocks(jirix, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	if version < 2 {
+		return pkgLocks, nil
+	}
+	now := ""
+	if recordTime {
+		now = time.Now().UTC().Format(time.RFC3339)
+	}
+	return lockPackagesV2(jirix, pkgs, pkgLocks, now)
+}
+
+// resolveTime returns the time recorded in new version 2 locks.
+func resolveTime(resolveConfig ResolveConfig) string {
+	if !resolveConfig.RecordResolveTime() {
+		return ""
+	}
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,205 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.fuchsia.dev/jiri"
+)
+
+// ProjectLockMismatch is a project lock whose recorded revision differs from
+// the computed one.
+type ProjectLockMismatch struct {
+	Computed ProjectLock
+	Recorded ProjectLock
+}
+
+// PackageLockMismatch is a package lock whose recorded instance differs from
+// the computed one.
+type PackageLockMismatch struct {
+	Computed PackageLock
+	Recorded PackageLock
+}
+
+// LockDiff describes how the locks recorded in lockfiles differ from the
+// locks computed from manifests. Missing locks are computed but not
+// recorded, extra locks are recorded but no longer computed.
+type LockDiff struct {
+	MissingProjects    []ProjectLock
+	ExtraProjects      []ProjectLock
+	MismatchedProjects []ProjectLockMismatch
+	MissingPackages    []PackageLock
+	ExtraPackages      []PackageLock
+	MismatchedPackages []PackageLockMismatch
+}
+
+// Empty returns true if the recorded locks match the computed ones.
+func (d LockDiff) Empty() bool {
+	return len(d.MissingProjects) == 0 && len(d.ExtraProjects) == 0 && len(d.MismatchedProjects) == 0 &&
+		len(d.MissingPackages) == 0 && len(d.ExtraPackages) == 0 && len(d.MismatchedPackages) == 0
+}
+
+// DiffProjectLocks adds the differences between computed and recorded
+// project locks to d.
+func (d *LockDiff) DiffProjectLocks(computed, recorded ProjectLocks) {
+	for _, c := range sortedProjectLocks(computed) {
+		r, ok := recorded[c.Key()]
+		if !ok {
+			d.MissingProjects = append(d.MissingProjects, c)
+		} else if !c.LockEqual(r) {
+			d.MismatchedProjects = append(d.MismatchedProjects, ProjectLockMismatch{Computed: c, Recorded: r})
+		}
+	}
+	for _, r := range sortedProjectLocks(recorded) {
+		if _, ok := computed[r.Key()]; !ok {
+			d.ExtraProjects = append(d.ExtraProjects, r)
+		}
+	}
+}
+
+// DiffPackageLocks adds the differences between computed and recorded
+// package locks to d. Locks without a path or a platform, as recorded by
+// version 1 lockfiles, match locks with any.
+func (d *LockDiff) DiffPackageLocks(computed, recorded PackageLocks) {
+	type nameVersion struct {
+		name, version string
+	}
+	byVersion := make(map[nameVersion][]PackageLock)
+	for _, r := range sortedPackageLocks(recorded) {
+		k := nameVersion{r.PackageName, r.VersionTag}
+		byVersion[k] = append(byVersion[k], r)
+	}
+	matches := func(a, b PackageLock) bool {
+		return (a.LocalPath == b.LocalPath || a.LocalPath == "" || b.LocalPath == "") &&
+			(a.Platform == b.Platform || a.Platform == "" || b.Platform == "")
+	}
+	used := make(map[PackageLockKey]bool)
+	for _, c := range sortedPackageLocks(computed) {
+		found := false
+		for _, r := range byVersion[nameVersion{c.PackageName, c.VersionTag}] {
+			if !matches(c, r) {
+				continue
+			}
+			found = true
+			used[r.Key()] = true
+			if !c.LockEqual(r) {
+				d.MismatchedPackages = append(d.MismatchedPackages, PackageLockMismatch{Computed: c, Recorded: r})
+			}
+			break
+		}
+		if !found {
+			d.MissingPackages = append(d.MissingPackages, c)
+		}
+	}
+	for _, r := range sortedPackageLocks(recorded) {
+		if !used[r.Key()] {
+			d.ExtraPackages = append(d.ExtraPackages, r)
+		}
+	}
+}
+
+// checkedLockFiles returns the existing lockfiles to check the locks of
+// manifestFiles against: the one at lockFilePath and the ones next to each
+// manifest.
+func checkedLockFiles(jirix *jiri.X, manifestFiles []string, lockFilePath string) ([]string, error) {
+	candidates := []string{lockFilePath}
+	if jirix.LockfileName != "" {
+		for _, m := range manifestFiles {
+			candidates = append(candidates, filepath.Join(filepath.Dir(m), jirix.LockfileName))
+		}
+	}
+	var ret []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		abs, err := filepath.Abs(c)
+		if err != nil {
+			return nil, err
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		if _, err := os.Stat(abs); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+// LockFileDiff is the LockDiff of a single lockfile.
+type LockFileDiff struct {
+	LockFile string
+	LockDiff
+}
+
+// CheckJiriLockFiles computes the locks GenerateJiriLockFile would write for
+// manifestFiles, without writing anything, and compares them with the locks
+// recorded in the lockfile at resolveConfig.LockFilePath() and in each of the
+// lockfiles next to the manifests. It returns the differences of every
+// checked lockfile.
+func CheckJiriLockFiles(jirix *jiri.X, manifestFiles []string, resolveConfig ResolveConfig) ([]LockFileDiff, error) {
+	lockFiles, err := checkedLockFiles(jirix, manifestFiles, resolveConfig.LockFilePath())
+	if err != nil {
+		return nil, err
+	}
+	if len(lockFiles) == 0 {
+		return nil, fmt.Errorf("no lockfile found for manifests %v", manifestFiles)
+	}
+	var diffs []LockFileDiff
+	for _, lockFile := range lockFiles {
+		diff, err := checkJiriLockFile(jirix, manifestFiles, resolveConfig, lockFile)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, LockFileDiff{LockFile: lockFile, LockDiff: diff})
+	}
+	return diffs, nil
+}
+
+// checkJiriLockFile compares the locks recorded in lockFile with the locks
+// GenerateJiriLockFile would write to it for manifestFiles.
+func checkJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig ResolveConfig, lockFile string) (LockDiff, error) {
+	data, err := os.ReadFile(lockFile)
+	if err != nil {
+		return LockDiff{}, err
+	}
+	recordedProjects, recordedPkgs, err := UnmarshalLockEntries(data)
+	if err != nil {
+		return LockDiff{}, fmt.Errorf("failed to parse lockfile %q: %v", lockFile, err)
+	}
+
+	// Resolving partially updates the existing locks, so hand over copies.
+	eProjectLocks := make(ProjectLocks)
+	for k, v := range recordedProjects {
+		eProjectLocks[k] = v
+	}
+	ePkgLocks := make(PackageLocks)
+	for k, v := range recordedPkgs {
+		ePkgLocks[k] = v
+	}
+	projectLocks, pkgLocks, err := resolveManifestLocks(jirix, manifestFiles, resolveConfig, resolveConfig.FullResolve(), eProjectLocks, ePkgLocks)
+	if err != nil {
+		return LockDiff{}, err
+	}
+
+	var diff LockDiff
+	if resolveConfig.EnableProjectLock() {
+		diff.DiffProjectLocks(projectLocks, recordedProjects)
+	}
+	if resolveConfig.EnablePackageLock() {
+		diff.DiffPackageLocks(pkgLocks, recordedPkgs)
+	}
+	return diff, nil
+}
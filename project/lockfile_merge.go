@@ -0,0 +1,186 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProjectLockConflict is a project lock that both sides of a merge changed
+// differently. A nil lock means the side did not contain the lock.
+type ProjectLockConflict struct {
+	Key    ProjectLockKey
+	Base   *ProjectLock
+	Ours   *ProjectLock
+	Theirs *ProjectLock
+}
+
+// PackageLockConflict is a package that both sides of a merge changed
+// differently. Locks of a package are grouped by name, local path and
+// platform, so the versions each side pins are compared as a whole. An empty
+// side means it did not contain the package.
+type PackageLockConflict struct {
+	Key    PackageLockKey
+	Base   []PackageLock
+	Ours   []PackageLock
+	Theirs []PackageLock
+}
+
+func (c ProjectLockConflict) String() string {
+	rev := func(l *ProjectLock) string {
+		if l == nil {
+			return "<deleted>"
+		}
+		return l.Revision
+	}
+	return fmt.Sprintf("project %q(remote: %s): base %s, ours %s, theirs %s", c.Key.name, c.Key.remote, rev(c.Base), rev(c.Ours), rev(c.Theirs))
+}
+
+func (c PackageLockConflict) String() string {
+	ids := func(locks []PackageLock) string {
+		if len(locks) == 0 {
+			return "<deleted>"
+		}
+		var s []string
+		for _, l := range locks {
+			s = append(s, fmt.Sprintf("%s(%s)", l.VersionTag, l.InstanceID))
+		}
+		return strings.Join(s, ",")
+	}
+	s := fmt.Sprintf("package %q", c.Key.packageName)
+	if c.Key.localPath != "" {
+		s += fmt.Sprintf(" at %q", c.Key.localPath)
+	}
+	if c.Key.platform != "" {
+		s += fmt.Sprintf(" for %q", c.Key.platform)
+	}
+	return fmt.Sprintf("%s: base %s, ours %s, theirs %s", s, ids(c.Base), ids(c.Ours), ids(c.Theirs))
+}
+
+// MergeProjectLocks does a three-way merge of project locks. Locks changed
+// on one side only are taken from that side. Locks changed on both sides are
+// conflicts unless both pin the same revision; ours is kept for them, or
+// theirs if ours deleted the lock.
+func MergeProjectLocks(base, ours, theirs ProjectLocks) (ProjectLocks, []ProjectLockConflict) {
+	keys := make(map[ProjectLockKey]bool)
+	for _, locks := range []ProjectLocks{base, ours, theirs} {
+		for k := range locks {
+			keys[k] = true
+		}
+	}
+	lookup := func(locks ProjectLocks, k ProjectLockKey) *ProjectLock {
+		if v, ok := locks[k]; ok {
+			return &v
+		}
+		return nil
+	}
+	same := func(a, b *ProjectLock) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+
+	merged := make(ProjectLocks)
+	var conflicts []ProjectLockConflict
+	for k := range keys {
+		b, o, t := lookup(base, k), lookup(ours, k), lookup(theirs, k)
+		var result *ProjectLock
+		switch {
+		case same(o, t), same(t, b):
+			result = o
+		case same(o, b):
+			result = t
+		case o != nil && t != nil && o.LockEqual(*t):
+			result = o
+		default:
+			conflicts = append(conflicts, ProjectLockConflict{Key: k, Base: b, Ours: o, Theirs: t})
+			if result = o; result == nil {
+				result = t
+			}
+		}
+		if result != nil {
+			merged[k] = *result
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key.String() < conflicts[j].Key.String()
+	})
+	return merged, conflicts
+}
+
+// MergePackageLocks does a three-way merge of package locks, following the
+// rules of MergeProjectLocks for instances instead of revisions. Locks are
+// merged per package name, local path and platform, so that both sides
+// changing the version of a package differently is a conflict.
+func MergePackageLocks(base, ours, theirs PackageLocks) (PackageLocks, []PackageLockConflict) {
+	// group returns the locks of each package, sorted by version.
+	group := func(locks PackageLocks) map[PackageLockKey][]PackageLock {
+		groups := make(map[PackageLockKey][]PackageLock)
+		for k, v := range locks {
+			k.versionTag = ""
+			groups[k] = append(groups[k], v)
+		}
+		for _, v := range groups {
+			sort.Slice(v, func(i, j int) bool { return v[i].VersionTag < v[j].VersionTag })
+		}
+		return groups
+	}
+	baseGroups, ourGroups, theirGroups := group(base), group(ours), group(theirs)
+	keys := make(map[PackageLockKey]bool)
+	for _, groups := range []map[PackageLockKey][]PackageLock{baseGroups, ourGroups, theirGroups} {
+		for k := range groups {
+			keys[k] = true
+		}
+	}
+	same := func(a, b []PackageLock) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	lockEqual := func(a, b []PackageLock) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !a[i].LockEqual(b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	merged := make(PackageLocks)
+	var conflicts []PackageLockConflict
+	for k := range keys {
+		b, o, t := baseGroups[k], ourGroups[k], theirGroups[k]
+		var result []PackageLock
+		switch {
+		case same(o, t), same(t, b):
+			result = o
+		case same(o, b):
+			result = t
+		case len(o) != 0 && lockEqual(o, t):
+			result = o
+		default:
+			conflicts = append(conflicts, PackageLockConflict{Key: k, Base: b, Ours: o, Theirs: t})
+			if result = o; len(result) == 0 {
+				result = t
+			}
+		}
+		for _, v := range result {
+			merged[v.Key()] = v
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].String() < conflicts[j].String()
+	})
+	return merged, conflicts
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.fuchsia.dev/jiri/jiritest/xtest"
+	"go.fuchsia.dev/jiri/project"
+)
+
+func TestMarshalAndUnmarshalLockFileV2(t *testing.T) {
+	t.Parallel()
+
+	projectLock := project.ProjectLock{
+		Remote:     "https://example.com/foo",
+		Name:       "foo",
+		Revision:   "aaa",
+		Manifest:   "manifest/foo",
+		Ref:        "refs/heads/main",
+		ResolvedAt: "2026-01-02T03:04:05Z",
+	}
+	// The same package and version, locked to different instances at two
+	// paths.
+	pkgLock0 := project.PackageLock{
+		PackageName: "fuchsia/tool/linux-amd64",
+		LocalPath:   "prebuilt/a",
+		VersionTag:  "version:1",
+		InstanceID:  "id-a",
+		Platform:    "linux-amd64",
+		Manifest:    "manifest/foo",
+	}
+	pkgLock1 := pkgLock0
+	pkgLock1.LocalPath = "prebuilt/b"
+	pkgLock1.InstanceID = "id-b"
+	projectLocks := project.ProjectLocks{projectLock.Key(): projectLock}
+	pkgLocks := project.PackageLocks{pkgLock0.Key(): pkgLock0, pkgLock1.Key(): pkgLock1}
+
+	data, err := project.MarshalLockFile(2, projectLocks, pkgLocks)
+	if err != nil {
+		t.Fatalf("marshalling lockfile failed: %v", err)
+	}
+	if version, err := project.LockFileVersion(data); err != nil || version != 2 {
+		t.Errorf("LockFileVersion() = %d, %v, want 2", version, err)
+	}
+	gotProjectLocks, gotPkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatalf("unmarshalling lockfile failed: %v", err)
+	}
+	if !reflect.DeepEqual(gotProjectLocks, projectLocks) {
+		t.Errorf("unexpected project locks, got %v, want %v", gotProjectLocks, projectLocks)
+	}
+	if !reflect.DeepEqual(gotPkgLocks, pkgLocks) {
+		t.Errorf("unexpected package locks, got %v, want %v", gotPkgLocks, pkgLocks)
+	}
+
+	// Version 1 does not record the new fields.
+	data, err = project.MarshalLockEntries(projectLocks, pkgLocks)
+	if err != nil {
+		t.Fatalf("marshalling lockfile failed: %v", err)
+	}
+	if version, err := project.LockFileVersion(data); err != nil || version != 1 {
+		t.Errorf("LockFileVersion() = %d, %v, want 1", version, err)
+	}
+	for _, field := range []string{"platform", "manifest", "ref", "resolved_at"} {
+		if strings.Contains(string(data), `"`+field+`"`) {
+			t.Errorf("version 1 lockfile contains field %q:\n%s", field, data)
+		}
+	}
+
+	// Conflicting locks of a package at the same path are rejected.
+	data = []byte(`{
+    "version": 2,
+    "projects": [],
+    "packages": [
+        {"package": "fuchsia/tool", "path": "prebuilt/a", "version": "version:1", "instance_id": "id-a"},
+        {"package": "fuchsia/tool", "path": "prebuilt/a", "version": "version:1", "instance_id": "id-b"}
+    ]
+}`)
+	if _, _, err := project.UnmarshalLockEntries(data); err == nil {
+		t.Errorf("expected conflicting package locks to be rejected")
+	}
+	if _, err := project.LockFileVersion([]byte(`{"version": 3}`)); err == nil {
+		t.Errorf("expected unknown lockfile version to be rejected")
+	}
+}
+
+func TestEnforceLocksByPath(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+	jirix.LockfileEnabled = true
+	jirix.LockfileName = "jiri.lock"
+
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest")
+	m := project.Manifest{Packages: []project.Package{
+		{Name: "fuchsia/tool", Version: "version:1", Path: "prebuilt/a"},
+		{Name: "fuchsia/tool", Version: "version:1", Path: "prebuilt/b"},
+	}}
+	if err := m.ToFile(jirix, manifestPath); err != nil {
+		t.Fatal(err)
+	}
+	lockData := `{
+    "version": 2,
+    "projects": [],
+    "packages": [
+        {"package": "fuchsia/tool", "path": "prebuilt/a", "version": "version:1", "instance_id": "id-a"},
+        {"package": "fuchsia/tool", "path": "prebuilt/b", "version": "version:1", "instance_id": "id-b"}
+    ]
+}`
+	if err := os.WriteFile(filepath.Join(dir, jirix.LockfileName), []byte(lockData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, pkgs, err := project.LoadManifestFile(jirix, manifestPath, nil, nil)
+	if err != nil {
+		t.Fatalf("loading manifest failed: %v", err)
+	}
+	for _, pkg := range pkgs {
+		want := []project.PackageInstance{{Name: "fuchsia/tool", ID: "id-" + filepath.Base(pkg.Path)}}
+		if !reflect.DeepEqual(pkg.Instances, want) {
+			t.Errorf("package at %q has instances %v, want %v", pkg.Path, pkg.Instances, want)
+		}
+	}
+}
+
+func TestResolveLockFileV2(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	archive := makeTarGz(t, map[string]string{"tool": "tool"})
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest")
+	lockPath := filepath.Join(dir, "jiri.lock")
+	m := project.Manifest{Packages: []project.Package{{
+		Name:      "test/tool/${platform}",
+		Version:   "1.0",
+		Path:      "prebuilt/tool",
+		Platforms: "linux-amd64,mac-amd64",
+		Source:    project.PackageSourceHTTP,
+		URL:       server.URL + "/${platform}.tar.gz",
+	}}}
+	if err := m.ToFile(jirix, manifestPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 1 is written by default.
+	if err := project.GenerateJiriLockFile(jirix, []string{manifestPath}, fakeResolveConfig{lockFilePath: lockPath}); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := project.LockFileVersion(data); err != nil || version != 1 {
+		t.Fatalf("LockFileVersion() = %d, %v, want 1", version, err)
+	}
+
+	// Migrating reuses the existing locks.
+	before := atomic.LoadInt32(&hits)
+	jirix.LockfileVersion = project.LatestLockFileVersion
+	if err := project.GenerateJiriLockFile(jirix, []string{manifestPath}, fakeResolveConfig{lockFilePath: lockPath, partial: true, recordTime: true}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if after := atomic.LoadInt32(&hits); after != before {
+		t.Errorf("packages were resolved again during migration")
+	}
+	if data, err = os.ReadFile(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := project.LockFileVersion(data); err != nil || version != 2 {
+		t.Fatalf("LockFileVersion() = %d, %v, want 2", version, err)
+	}
+	_, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgLocks) != 2 {
+		t.Fatalf("expected 2 package locks, got %v", pkgLocks)
+	}
+	for _, lock := range pkgLocks {
+		if lock.LocalPath != "prebuilt/tool" ||
+			lock.PackageName != "test/tool/"+lock.Platform ||
+			lock.Manifest != filepath.ToSlash(manifestPath) ||
+			lock.ResolvedAt == "" ||
+			lock.Digest != digestOf(archive) {
+			t.Errorf("unexpected lock %+v", lock)
+		}
+	}
+}
+
+func TestDiffPackageLocks(t *testing.T) {
+	t.Parallel()
+
+	// Version 1 locks without path or platform match version 2 locks.
+	recorded := project.PackageLocks{}
+	for _, l := range []project.PackageLock{
+		{PackageName: "fuchsia/tool/linux-amd64", VersionTag: "version:1", InstanceID: "id-1"},
+		{PackageName: "fuchsia/other", VersionTag: "version:1", InstanceID: "id-2"},
+		{PackageName: "fuchsia/stale", VersionTag: "version:1", InstanceID: "id-3"},
+	} {
+		recorded[l.Key()] = l
+	}
+	computed := project.PackageLocks{}
+	tool := project.PackageLock{PackageName: "fuchsia/tool/linux-amd64", VersionTag: "version:1", InstanceID: "id-1", LocalPath: "prebuilt/tool", Platform: "linux-amd64"}
+	other := project.PackageLock{PackageName: "fuchsia/other", VersionTag: "version:1", InstanceID: "id-4", LocalPath: "prebuilt/other"}
+	added := project.PackageLock{PackageName: "fuchsia/new", VersionTag: "version:1", InstanceID: "id-5"}
+	for _, l := range []project.PackageLock{tool, other, added} {
+		computed[l.Key()] = l
+	}
+
+	var diff project.LockDiff
+	diff.DiffPackageLocks(computed, recorded)
+	if !reflect.DeepEqual(diff.MissingPackages, []project.PackageLock{added}) {
+		t.Errorf("unexpected missing packages %+v", diff.MissingPackages)
+	}
+	if len(diff.ExtraPackages) != 1 || diff.ExtraPackages[0].PackageName != "fuchsia/stale" {
+		t.Errorf("unexpected extra packages %+v", diff.ExtraPackages)
+	}
+	if len(diff.MismatchedPackages) != 1 || diff.MismatchedPackages[0].Computed != other || diff.MismatchedPackages[0].Recorded.InstanceID != "id-2" {
+		t.Errorf("unexpected mismatched packages %+v", diff.MismatchedPackages)
+	}
+	if diff.Empty() {
+		t.Errorf("expected diff not to be empty")
+	}
+}
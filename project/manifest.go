@@ -377,10 +377,11 @@ func (i *LocalImport) validate() error {
 }
 
 type LocalConfig struct {
-	Ignore   bool     `xml:"ignore"`
-	NoUpdate bool     `xml:"no-update"`
-	NoRebase bool     `xml:"no-rebase"`
-	XMLName  struct{} `xml:"config"`
+	Ignore    bool     `xml:"ignore"`
+	NoUpdate  bool     `xml:"no-update"`
+	NoRebase  bool     `xml:"no-rebase"`
+	AutoStash bool     `xml:"autostash"`
+	XMLName   struct{} `xml:"config"`
 }
 
 // Reads localConfig from given reader. Returns incorrect bytes
@@ -510,6 +511,24 @@ type Package struct {
 	// be appended.
 	Attributes string `xml:"attributes,attr,omitempty"`
 
+	// Source selects the backend used to resolve and fetch this package.
+	// An empty value means "cipd". See PackageSource for details.
+	Source string `xml:"source,attr,omitempty"`
+
+	// URL stores the location of packages that are not fetched from cipd.
+	// For "http" packages it points to an archive, for "oci" packages it
+	// points to the registry. ${platform}, ${os}, ${arch} and ${version}
+	// templates are expanded.
+	URL string `xml:"url,attr,omitempty"`
+
+	// Sha256 optionally pins the digest of the archive of a "http"
+	// package that does not use platform templates.
+	Sha256 string `xml:"sha256,attr,omitempty"`
+
+	// License is the SPDX license expression of the package. It is
+	// recorded in the SBOMs generated by "jiri sbom".
+	License string `xml:"license,attr,omitempty"`
+
 	// Instances store the known instance ids for this package.
 	// It is mainly used by snapshot file.
 	Instances []PackageInstance `xml:"instance"`
@@ -571,7 +590,8 @@ func (p *Packages) FilterACL(jirix *jiri.X) (Packages, bool, error) {
 	hasInternal := false
 	for _, pkg := range *p {
 		pkg.Name = strings.TrimRight(pkg.Name, "/")
-		if pkg.Internal {
+		// Access control only applies to cipd packages.
+		if pkg.Internal && pkg.GetSource() == PackageSourceCIPD {
 			hasInternal = true
 			pkgACLMap[pkg.Name] = false
 		}
@@ -650,6 +670,30 @@ func (p *Package) GetPath() (string, error) {
 	return p.Path, nil
 }
 
+// expandedPath returns the path returned by GetPath with its templates
+// filled in for the current platform.
+func (p *Package) expandedPath() (string, error) {
+	return p.expandedPathFor(cipd.CipdPlatform)
+}
+
+// expandedPathFor returns the path returned by GetPath with its templates
+// filled in for platform plat.
+func (p *Package) expandedPathFor(plat cipd.Platform) (string, error) {
+	subdir, err := p.GetPath()
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New("pack").Parse(subdir)
+	if err != nil {
+		return "", fmt.Errorf("parsing package path %q failed", subdir)
+	}
+	var subdirBuf bytes.Buffer
+	// subdir is using fuchsia platform format instead of
+	// using cipd platform format
+	tmpl.Execute(&subdirBuf, cipd.FuchsiaPlatform(plat))
+	return subdirBuf.String(), nil
+}
+
 // GetPlatforms returns the platforms information of
 // this Package struct.
 func (p *Package) GetPlatforms() ([]cipd.Platform, error) {
@@ -736,22 +780,17 @@ func (ld *loader) enforceLocks(jirix *jiri.X) error {
 			usedPkgLocks[k] = false
 		}
 		for _, v := range ld.Packages {
-			plats, err := v.GetPlatforms()
+			targets, err := v.lockTargets()
 			if err != nil {
 				return err
 			}
-			pkgs, err := cipd.Expand(v.Name, plats)
-			if err != nil {
-				return err
-			}
-			for _, pkg := range pkgs {
-				if pkgLock, ok := ld.PackageLocks[MakePackageLockKey(pkg, v.Version)]; ok {
-					if pkgLock.VersionTag != v.Version && !jirix.IgnoreLockConflicts {
-						// Package version conflicts detected. Treated it as an error.
-						s := fmt.Sprintf("package %q has conflicting version in manifest and jiri.lock: %s:%s", v.Name, v.Version, pkgLock.VersionTag)
-						jirix.Logger.Debugf("%s", s)
-						err = errors.New(s)
-					}
+			seen := make(map[string]bool)
+			for _, t := range targets {
+				if seen[t.name] {
+					continue
+				}
+				seen[t.name] = true
+				if pkgLock, ok := ld.PackageLocks.find(t.name, v.Version, v.Path, t.platform); ok {
 					ins := PackageInstance{
 						Name: pkgLock.PackageName,
 						ID:   pkgLock.InstanceID,
@@ -760,12 +799,9 @@ func (ld *loader) enforceLocks(jirix *jiri.X) error {
 					ld.Packages[v.Key()] = v
 					usedPkgLocks[pkgLock.Key()] = true
 				} else {
-					jirix.Logger.Debugf("Package %q is not found in jiri.lock", pkg)
+					jirix.Logger.Debugf("Package %q is not found in jiri.lock", t.name)
 				}
 			}
-			if err != nil {
-				return err
-			}
 		}
 		for k, v := range usedPkgLocks {
 			if !v {
@@ -795,6 +831,9 @@ func LoadManifestFile(jirix *jiri.X, file string, localProjects Projects, localM
 	if err := ld.Load(jirix, "", "", file, "", "", nil, localManifestProjects); err != nil {
 		return nil, nil, nil, err
 	}
+	if err := checkPackagePaths(jirix, ld.Projects, ld.Packages); err != nil {
+		return nil, nil, nil, err
+	}
 	jirix.AddCleanupFunc(ld.cleanup)
 	if jirix.LockfileEnabled {
 		if err := ld.enforceLocks(jirix); err != nil {
@@ -817,6 +856,9 @@ func LoadUpdatedManifest(jirix *jiri.X, localProjects Projects, localManifestPro
 	if err := ld.Load(jirix, "", "", jirix.JiriManifestFile(), "", "", nil, localManifestProjects); err != nil {
 		return nil, nil, nil, err
 	}
+	if err := checkPackagePaths(jirix, ld.Projects, ld.Packages); err != nil {
+		return nil, nil, nil, err
+	}
 	jirix.AddCleanupFunc(ld.cleanup)
 	if jirix.LockfileEnabled {
 		if err := ld.enforceLocks(jirix); err != nil {
@@ -831,38 +873,25 @@ func LoadUpdatedManifest(jirix *jiri.X, localProjects Projects, localManifestPro
 }
 
 // resolvePackageLocks resolves instance ids using versions described in given
-// pkgs using cipd.
+// pkgs using the package source of each package.
 func resolvePackageLocks(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
-	jirix.TimerPush("resolve instance id for cipd packages")
+	jirix.TimerPush("resolve instance id for packages")
 	defer jirix.TimerPop()
 
-	pkgs, _, err := pkgs.FilterACL(jirix)
-	if err != nil {
-		return nil, err
-	}
-
-	ensureFilePath, err := generateEnsureFile(jirix, pkgs, false, "")
+	pkgsBySource, err := pkgs.bySource()
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(ensureFilePath)
-
-	pkgInstances, err := cipd.Resolve(jirix, ensureFilePath)
-	if err != nil {
-		return nil, err
-	}
-	// TODO: Remove this boilerplate once we have a better package
-	// layout that doesn't cause import cycles
 	pkgLocks := make(PackageLocks)
-	for _, val := range pkgInstances {
-		pkgLock := PackageLock{
-			PackageName: val.PackageName,
-			VersionTag:  val.VersionTag,
-			InstanceID:  val.InstanceID,
+	for _, name := range sortedSourceNames(pkgsBySource) {
+		locks, err := packageSources[name].Resolve(jirix, pkgsBySource[name])
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range locks {
+			pkgLocks[k] = v
 		}
-		pkgLocks[pkgLock.Key()] = pkgLock
 	}
-
 	return pkgLocks, nil
 }
 
@@ -870,7 +899,7 @@ func resolvePackageLocks(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
 func resolveProjectLocks(projects Projects) (ProjectLocks, error) {
 	projectLocks := make(ProjectLocks)
 	for _, v := range projects {
-		projectLock := ProjectLock{v.Remote, v.Name, v.Revision}
+		projectLock := ProjectLock{Remote: v.Remote, Name: v.Name, Revision: v.Revision}
 		projectLocks[projectLock.Key()] = projectLock
 	}
 	return projectLocks, nil
@@ -878,9 +907,10 @@ func resolveProjectLocks(projects Projects) (ProjectLocks, error) {
 
 // CipdSnapshot generates a snapshot of the cipd ensure and version file
 func CreateCipdSnapshot(jirix *jiri.X, pkgs Packages, file string) error {
+	pkgs = pkgs.filterSource(PackageSourceCIPD)
 	ensureSnapshotFilePath := file + ".ensure"
 	versionSnapshotFilePath := file + ".version"
-	ensureFilePath, err := generateEnsureFile(jirix, pkgs, false, filepath.Base(versionSnapshotFilePath))
+	ensureFilePath, err := generateEnsureFile(jirix, pkgs, false, filepath.Base(versionSnapshotFilePath), nil)
 	if err != nil {
 		return err
 	}
@@ -902,10 +932,10 @@ func CreateCipdSnapshot(jirix *jiri.X, pkgs Packages, file string) error {
 	return nil
 }
 
-// FetchPackages fetches prebuilt packages described in given pkgs using cipd.
-// Parameter fetchTimeout is in minutes.
+// FetchPackages fetches prebuilt packages described in given pkgs using the
+// package source of each package. Parameter fetchTimeout is in minutes.
 func FetchPackages(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error {
-	jirix.TimerPush("fetch cipd packages")
+	jirix.TimerPush("fetch packages")
 	defer jirix.TimerPop()
 
 	pkgsWAccess, hasInternalPkgs, err := pkgs.FilterACL(jirix)
@@ -913,22 +943,19 @@ func FetchPackages(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error {
 		return err
 	}
 
-	ensureFilePath, err := generateEnsureFile(jirix, pkgsWAccess, !jirix.LockfileEnabled || jirix.UsingSnapshot, "")
+	pkgsBySource, err := pkgsWAccess.bySource()
 	if err != nil {
 		return err
 	}
-	defer os.Remove(ensureFilePath)
-
-	if jirix.LockfileEnabled && !jirix.UsingSnapshot {
-		versionFilePath, err := generateVersionFile(jirix, ensureFilePath, pkgsWAccess)
-		if err != nil {
+	if _, ok := pkgsBySource[PackageSourceCIPD]; !ok {
+		// Still run cipd so that the cipd packages removed from the
+		// manifest are cleaned up.
+		pkgsBySource[PackageSourceCIPD] = make(Packages)
+	}
+	for _, name := range sortedSourceNames(pkgsBySource) {
+		if err := packageSources[name].Fetch(jirix, pkgsBySource[name], fetchTimeout); err != nil {
 			return err
 		}
-		defer os.Remove(versionFilePath)
-	}
-
-	if err := cipd.Ensure(jirix, ensureFilePath, jirix.Root, fetchTimeout); err != nil {
-		return err
 	}
 
 	if hasInternalPkgs {
@@ -1027,7 +1054,7 @@ func writePackageJSON(jirix *jiri.X, access bool) error {
 	return os.WriteFile(filepath.Join(jirix.RootMetaDir(), jirix.PrebuiltJSON), jsonData, 0644)
 }
 
-func generateEnsureFile(jirix *jiri.X, pkgs Packages, ignoreCryptoCheck bool, versionFilePath string) (string, error) {
+func generateEnsureFile(jirix *jiri.X, pkgs Packages, ignoreCryptoCheck bool, versionFilePath string, plats []cipd.Platform) (string, error) {
 	ensureFile, err := os.CreateTemp("", "jiri*.ensure")
 	if err != nil {
 		return "", fmt.Errorf("not able to create tmp file: %v", err)
@@ -1073,7 +1100,9 @@ func generateEnsureFile(jirix *jiri.X, pkgs Packages, ignoreCryptoCheck bool, ve
 		}
 		ensureFileBuf.WriteString("$ResolvedVersions " + versionFileName + "\n")
 	}
-	if jirix.CipdParanoidMode {
+	if jirix.CipdCheckIntegrity {
+		ensureFileBuf.WriteString("$ParanoidMode CheckIntegrity\n")
+	} else if jirix.CipdParanoidMode {
 		ensureFileBuf.WriteString("$ParanoidMode CheckPresence\n")
 	}
 	ensureFileBuf.WriteString("\n")
@@ -1086,6 +1115,25 @@ func generateEnsureFile(jirix *jiri.X, pkgs Packages, ignoreCryptoCheck bool, ve
 			return "", err
 		}
 		cipdDecls = append(cipdDecls, cipdDecl)
+		if len(plats) < 2 {
+			continue
+		}
+		// Install the instances for the other platforms in plats to
+		// their own directories.
+		targets, err := pkg.fetchTargets(plats)
+		if err != nil {
+			return "", err
+		}
+		for _, t := range targets {
+			if t.plat == cipd.CipdPlatform {
+				continue
+			}
+			cipdDecl, err := pkg.platformCipdDecl(jirix, t)
+			if err != nil {
+				return "", err
+			}
+			cipdDecls = append(cipdDecls, cipdDecl)
+		}
 	}
 	sort.Strings(cipdDecls)
 	for _, cipdDecl := range cipdDecls {
@@ -1106,19 +1154,10 @@ func generateEnsureFile(jirix *jiri.X, pkgs Packages, ignoreCryptoCheck bool, ve
 func (p *Package) cipdDecl(jirix *jiri.X) (string, error) {
 	var buf bytes.Buffer
 	// Write "@Subdir" line to cipd declaration
-	subdir, err := p.GetPath()
+	subdir, err := p.expandedPath()
 	if err != nil {
 		return "", err
 	}
-	tmpl, err := template.New("pack").Parse(subdir)
-	if err != nil {
-		return "", fmt.Errorf("parsing package path %q failed", subdir)
-	}
-	var subdirBuf bytes.Buffer
-	// subdir is using fuchsia platform format instead of
-	// using cipd platform format
-	tmpl.Execute(&subdirBuf, cipd.FuchsiaPlatform(cipd.CipdPlatform))
-	subdir = subdirBuf.String()
 	buf.WriteString(fmt.Sprintf("@Subdir %s\n", subdir))
 	// Write package version line to cipd declaration
 	plats, err := p.GetPlatforms()
@@ -1157,6 +1196,19 @@ func (p *Package) cipdDecl(jirix *jiri.X) (string, error) {
 	return buf.String(), nil
 }
 
+// platformCipdDecl returns the cipd declaration of fetch target t of
+// Package p, which is for another platform than the current one.
+func (p *Package) platformCipdDecl(jirix *jiri.X, t fetchTarget) (string, error) {
+	version := p.Version
+	id := p.knownInstance(t.name)
+	if jirix.UsingSnapshot && id != "" {
+		version = id
+	} else if jirix.LockfileEnabled && !jirix.UsingSnapshot && id == "" {
+		return "", fmt.Errorf("package %q has no locked instance for platform %s, please run \"jiri resolve\"", t.name, t.plat)
+	}
+	return fmt.Sprintf("@Subdir %s\n%s %s\n", t.subdir, t.name, version), nil
+}
+
 func generateVersionFile(jirix *jiri.X, ensureFile string, pkgs Packages) (string, error) {
 	versionFileName := ensureFile[:len(ensureFile)-len(".ensure")] + ".version"
 
@@ -0,0 +1,312 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+)
+
+// Names of the supported package sources, as used by the "source"
+// attribute of the <package> tag.
+const (
+	PackageSourceCIPD = "cipd"
+	PackageSourceHTTP = "http"
+	PackageSourceOCI  = "oci"
+)
+
+// PackageSource is implemented by every backend that is able to resolve
+// and fetch <package> entries.
+type PackageSource interface {
+	// Resolve returns the locks of pkgs for all of the platforms they
+	// support.
+	Resolve(jirix *jiri.X, pkgs Packages) (PackageLocks, error)
+
+	// Fetch installs pkgs for the current platform and the platforms in
+	// jirix.FetchPlatforms under jirix.Root.
+	// If a package has known instances, the fetched content must match
+	// them. Parameter fetchTimeout is in minutes.
+	Fetch(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error
+
+	// Verify compares the installed instances and files of pkgs for the
+	// current platform with the expected ones.
+	Verify(jirix *jiri.X, pkgs Packages) ([]PackageStatus, error)
+}
+
+var packageSources = map[string]PackageSource{
+	PackageSourceCIPD: cipdSource{},
+	PackageSourceHTTP: httpSource{},
+	PackageSourceOCI:  ociSource{},
+}
+
+// GetSource returns the name of the package source of Package p.
+func (p *Package) GetSource() string {
+	if p.Source == "" {
+		return PackageSourceCIPD
+	}
+	return p.Source
+}
+
+func (p *Package) validate() error {
+	switch p.GetSource() {
+	case PackageSourceCIPD:
+		if p.URL != "" || p.Sha256 != "" {
+			return fmt.Errorf("attributes \"url\" and \"sha256\" are not supported by cipd packages")
+		}
+	case PackageSourceHTTP, PackageSourceOCI:
+		if p.URL == "" {
+			return fmt.Errorf("attribute \"url\" is required by %s packages", p.Source)
+		}
+		if p.Sha256 != "" && cipd.MustExpand(p.Name) {
+			return fmt.Errorf("attribute \"sha256\" cannot be used with package name templates, use a lockfile instead")
+		}
+		if p.Sha256 != "" && p.Source == PackageSourceOCI {
+			return fmt.Errorf("attribute \"sha256\" is not supported by oci packages, use a digest as version instead")
+		}
+		// The path is removed and recreated when the package is fetched.
+		if p.Path == "" {
+			return fmt.Errorf("attribute \"path\" is required by %s packages", p.Source)
+		}
+		if path := filepath.Clean(p.Path); filepath.IsAbs(path) || path == "." || path == ".." || strings.HasPrefix(path, "../") {
+			return fmt.Errorf("path %q of %s packages must be a subdirectory of the jiri root", p.Path, p.Source)
+		}
+	default:
+		return fmt.Errorf("unknown package source %q", p.Source)
+	}
+	if isVersionConstraint(p.Version) {
+		if p.GetSource() != PackageSourceCIPD {
+			return fmt.Errorf("version constraints are only supported by cipd packages")
+		}
+		if _, err := parseSemverConstraint(strings.TrimPrefix(p.Version, semverPrefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPackagePaths returns an error if the path of an http or oci package,
+// which the package owns exclusively, overlaps the jiri root metadata, a
+// project or another package.
+func checkPackagePaths(jirix *jiri.X, projects Projects, pkgs Packages) error {
+	type owner struct {
+		path      string
+		desc      string
+		exclusive bool
+	}
+	owners := []owner{{path: jiri.RootMetaDir, desc: "the jiri root metadata"}}
+	for _, p := range projects {
+		path, err := filepath.Rel(jirix.Root, p.Path)
+		if err != nil {
+			path = p.Path
+		}
+		owners = append(owners, owner{path: path, desc: fmt.Sprintf("project %q", p.Name)})
+	}
+	for _, pkg := range pkgs {
+		plats, err := pkg.GetPlatforms()
+		if err != nil {
+			return err
+		}
+		if !containsPlatform(plats, cipd.CipdPlatform) {
+			plats = append(plats, cipd.CipdPlatform)
+		}
+		seen := make(map[string]bool)
+		for _, plat := range plats {
+			path, err := pkg.expandedPathFor(plat)
+			if err != nil {
+				return err
+			}
+			if !seen[path] {
+				seen[path] = true
+				owners = append(owners, owner{path: path, desc: fmt.Sprintf("package %q", pkg.Name), exclusive: pkg.GetSource() != PackageSourceCIPD})
+			}
+		}
+	}
+	overlap := func(a, b string) bool {
+		return a == "." || b == "." || a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+	}
+	for i, a := range owners {
+		if !a.exclusive {
+			continue
+		}
+		for j, b := range owners {
+			if i != j && overlap(filepath.Clean(a.path), filepath.Clean(b.path)) {
+				return fmt.Errorf("path %q of %s overlaps path %q of %s", a.path, a.desc, b.path, b.desc)
+			}
+		}
+	}
+	return nil
+}
+
+// bySource groups pkgs by the name of their package source.
+func (p Packages) bySource() (map[string]Packages, error) {
+	ret := make(map[string]Packages)
+	for k, v := range p {
+		name := v.GetSource()
+		if _, ok := packageSources[name]; !ok {
+			return nil, fmt.Errorf("package %q uses unknown package source %q", v.Name, name)
+		}
+		if _, ok := ret[name]; !ok {
+			ret[name] = make(Packages)
+		}
+		ret[name][k] = v
+	}
+	return ret, nil
+}
+
+// filterSource returns the packages in p that are fetched from the
+// package source name.
+func (p Packages) filterSource(name string) Packages {
+	ret := make(Packages)
+	for k, v := range p {
+		if v.GetSource() == name {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func sortedSourceNames(pkgsBySource map[string]Packages) []string {
+	names := make([]string, 0, len(pkgsBySource))
+	for k := range pkgsBySource {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetchPlatforms returns the platforms packages are fetched for: the current
+// platform followed by the ones set in jirix.FetchPlatforms.
+func fetchPlatforms(jirix *jiri.X) ([]cipd.Platform, error) {
+	plats := []cipd.Platform{cipd.CipdPlatform}
+	for _, s := range strings.Split(jirix.FetchPlatforms, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		plat, err := cipd.NewPlatform(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fetch platform: %v", err)
+		}
+		if !containsPlatform(plats, plat) {
+			plats = append(plats, plat)
+		}
+	}
+	return plats, nil
+}
+
+func containsPlatform(plats []cipd.Platform, plat cipd.Platform) bool {
+	for _, p := range plats {
+		if p == plat {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchTarget is a packageTarget together with the directory it is
+// installed to.
+type fetchTarget struct {
+	packageTarget
+	subdir string
+}
+
+// fetchTargets returns the instances of Package p to install for plats, the
+// first of which is the current platform. Other platforms are only used if
+// p supports them, and an instance is skipped if an earlier one is already
+// installed to its directory.
+func (p *Package) fetchTargets(plats []cipd.Platform) ([]fetchTarget, error) {
+	supported, err := p.GetPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	var ret []fetchTarget
+	seen := make(map[string]bool)
+	for i, plat := range plats {
+		if i > 0 && !containsPlatform(supported, plat) {
+			continue
+		}
+		targets, err := p.expandTargets([]cipd.Platform{plat})
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		subdir, err := p.expandedPathFor(plat)
+		if err != nil {
+			return nil, err
+		}
+		if seen[subdir] {
+			continue
+		}
+		seen[subdir] = true
+		t := targets[0]
+		t.plat = plat
+		ret = append(ret, fetchTarget{packageTarget: t, subdir: subdir})
+	}
+	return ret, nil
+}
+
+// cipdSource resolves and fetches packages using the cipd client.
+type cipdSource struct{}
+
+func (cipdSource) Resolve(jirix *jiri.X, pkgs Packages) (PackageLocks, error) {
+	pkgs, _, err := pkgs.FilterACL(jirix)
+	if err != nil {
+		return nil, err
+	}
+
+	ensureFilePath, err := generateEnsureFile(jirix, pkgs, false, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(ensureFilePath)
+
+	pkgInstances, err := cipd.Resolve(jirix, ensureFilePath)
+	if err != nil {
+		return nil, err
+	}
+	// TODO: Remove this boilerplate once we have a better package
+	// layout that doesn't cause import cycles
+	pkgLocks := make(PackageLocks)
+	for _, val := range pkgInstances {
+		pkgLock := PackageLock{
+			PackageName: val.PackageName,
+			VersionTag:  val.VersionTag,
+			InstanceID:  val.InstanceID,
+		}
+		pkgLocks[pkgLock.Key()] = pkgLock
+	}
+
+	return pkgLocks, nil
+}
+
+func (cipdSource) Fetch(jirix *jiri.X, pkgs Packages, fetchTimeout uint) error {
+	plats, err := fetchPlatforms(jirix)
+	if err != nil {
+		return err
+	}
+	ensureFilePath, err := generateEnsureFile(jirix, pkgs, !jirix.LockfileEnabled || jirix.UsingSnapshot, "", plats)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(ensureFilePath)
+
+	if jirix.LockfileEnabled && !jirix.UsingSnapshot {
+		versionFilePath, err := generateVersionFile(jirix, ensureFilePath, pkgs)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(versionFilePath)
+	}
+
+	return cipd.Ensure(jirix, ensureFilePath, jirix.Root, fetchTimeout)
+}
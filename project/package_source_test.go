@@ -0,0 +1,469 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/jiritest/xtest"
+	"go.fuchsia.dev/jiri/project"
+)
+
+type fakeResolveConfig struct {
+	lockFilePath string
+	partial      bool
+	recordTime   bool
+	projectLock  bool
+}
+
+func (c fakeResolveConfig) AllowFloatingRefs() bool         { return false }
+func (c fakeResolveConfig) LockFilePath() string            { return c.lockFilePath }
+func (c fakeResolveConfig) LocalManifestProjects() []string { return nil }
+func (c fakeResolveConfig) EnablePackageLock() bool         { return true }
+func (c fakeResolveConfig) EnableProjectLock() bool         { return c.projectLock }
+func (c fakeResolveConfig) HostnameAllowList() []string     { return nil }
+func (c fakeResolveConfig) FullResolve() bool               { return !c.partial }
+func (c fakeResolveConfig) RecordResolveTime() bool         { return c.recordTime }
+
+func makeTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func digestOf(data []byte) string {
+	hash := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// resolvePackages writes pkgs into a manifest and resolves it into a
+// lockfile using project.GenerateJiriLockFile.
+func resolvePackages(t *testing.T, jirix *jiri.X, pkgs ...project.Package) project.PackageLocks {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest")
+	lockPath := filepath.Join(dir, "jiri.lock")
+	m := project.Manifest{Packages: pkgs}
+	if err := m.ToFile(jirix, manifestPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := project.GenerateJiriLockFile(jirix, []string{manifestPath}, fakeResolveConfig{lockFilePath: lockPath}); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pkgLocks, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkgLocks
+}
+
+func readPackageFile(t *testing.T, root, path string) string {
+	data, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHTTPPackageSource(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	archive := makeTarGz(t, map[string]string{"bin/tool": "tool v1"})
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tool-1.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	pkg := project.Package{
+		Name:    "test/tool",
+		Version: "1.0",
+		Path:    "prebuilt/tool",
+		Source:  project.PackageSourceHTTP,
+		URL:     server.URL + "/tool-${version}.tar.gz",
+	}
+
+	// Resolve records the digest of the archive.
+	pkgLocks := resolvePackages(t, jirix, pkg)
+	lock, ok := pkgLocks[project.MakePackageLockKey("test/tool", "1.0")]
+	if !ok {
+		t.Fatalf("lock for package test/tool not found in %v", pkgLocks)
+	}
+	if lock.Digest != digestOf(archive) || lock.InstanceID != lock.Digest || lock.Source != project.PackageSourceHTTP {
+		t.Fatalf("unexpected lock %+v, want digest %s", lock, digestOf(archive))
+	}
+
+	// Fetching without a digest is not allowed.
+	if err := project.FetchPackages(jirix, project.Packages{pkg.Key(): pkg}, project.DefaultPackageTimeout); err == nil {
+		t.Fatalf("expected fetching package without digest to fail")
+	}
+
+	pkg.Instances = []project.PackageInstance{{Name: lock.PackageName, ID: lock.InstanceID}}
+	pkgs := project.Packages{pkg.Key(): pkg}
+	if err := project.FetchPackages(jirix, pkgs, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if got, want := readPackageFile(t, jirix.Root, "prebuilt/tool/bin/tool"), "tool v1"; got != want {
+		t.Errorf("unexpected package content %q, want %q", got, want)
+	}
+
+	// Fetching an installed package again is a no-op.
+	before := atomic.LoadInt32(&hits)
+	if err := project.FetchPackages(jirix, pkgs, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if after := atomic.LoadInt32(&hits); after != before {
+		t.Errorf("installed package was fetched again")
+	}
+
+	// Mismatching digests are rejected and keep the installed package.
+	pkg.Instances = nil
+	pkg.Sha256 = strings.Repeat("0", 64)
+	if err := project.FetchPackages(jirix, project.Packages{pkg.Key(): pkg}, project.DefaultPackageTimeout); err == nil {
+		t.Fatalf("expected fetching package with mismatching digest to fail")
+	}
+	if got, want := readPackageFile(t, jirix.Root, "prebuilt/tool/bin/tool"), "tool v1"; got != want {
+		t.Errorf("unexpected package content %q, want %q", got, want)
+	}
+}
+
+func TestOCIPackageSource(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	layer := makeTarGz(t, map[string]string{"lib/data": "layer content"})
+	config := []byte("{}")
+	raw := []byte("raw file")
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]any{
+			"mediaType": "application/vnd.oci.empty.v1+json",
+			"digest":    digestOf(config),
+			"size":      len(config),
+		},
+		"layers": []map[string]any{
+			{
+				"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+				"digest":    digestOf(layer),
+				"size":      len(layer),
+			},
+			{
+				"mediaType":   "application/octet-stream",
+				"digest":      digestOf(raw),
+				"size":        len(raw),
+				"annotations": map[string]string{"org.opencontainers.image.title": "README"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs := map[string][]byte{
+		digestOf(layer): layer,
+		digestOf(raw):   raw,
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token": "secret"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:test/tool:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/test/tool/manifests/v1", r.URL.Path == "/v2/test/tool/manifests/"+digestOf(manifest):
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digestOf(manifest))
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/test/tool/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/test/tool/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pkg := project.Package{
+		Name:    "test/tool",
+		Version: "v1",
+		Path:    "prebuilt/oci",
+		Source:  project.PackageSourceOCI,
+		URL:     server.URL,
+	}
+	pkgLocks := resolvePackages(t, jirix, pkg)
+	lock, ok := pkgLocks[project.MakePackageLockKey("test/tool", "v1")]
+	if !ok {
+		t.Fatalf("lock for package test/tool not found in %v", pkgLocks)
+	}
+	if lock.Digest != digestOf(manifest) {
+		t.Fatalf("unexpected lock %+v, want digest %s", lock, digestOf(manifest))
+	}
+
+	pkg.Instances = []project.PackageInstance{{Name: lock.PackageName, ID: lock.InstanceID}}
+	if err := project.FetchPackages(jirix, project.Packages{pkg.Key(): pkg}, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if got, want := readPackageFile(t, jirix.Root, "prebuilt/oci/lib/data"), "layer content"; got != want {
+		t.Errorf("unexpected package content %q, want %q", got, want)
+	}
+	if got, want := readPackageFile(t, jirix.Root, "prebuilt/oci/README"), "raw file"; got != want {
+		t.Errorf("unexpected package content %q, want %q", got, want)
+	}
+}
+
+func TestFetchPackagesForPlatforms(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	archives := make(map[string][]byte)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archive, ok := archives[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	plats := []cipd.Platform{cipd.CipdPlatform, {OS: "linux", Arch: "arm64"}, {OS: "mac", Arch: "amd64"}}
+	pkg := project.Package{
+		Name:      "test/tool/${platform}",
+		Version:   "1.0",
+		Path:      "prebuilt/tool/{{.OS}}-{{.Arch}}",
+		Platforms: "linux-amd64,linux-arm64,mac-amd64",
+		Source:    project.PackageSourceHTTP,
+		URL:       server.URL + "/${platform}.tar.gz",
+	}
+	for _, plat := range plats {
+		archive := makeTarGz(t, map[string]string{"tool": "tool for " + plat.String()})
+		archives[plat.String()+".tar.gz"] = archive
+		pkg.Instances = append(pkg.Instances, project.PackageInstance{Name: "test/tool/" + plat.String(), ID: digestOf(archive)})
+	}
+	pkgs := project.Packages{pkg.Key(): pkg}
+
+	// windows-amd64 is not supported by the package and is skipped.
+	jirix.FetchPlatforms = "linux-arm64,mac-amd64,windows-amd64"
+	if err := project.FetchPackages(jirix, pkgs, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	for _, plat := range plats {
+		fuchsiaPlat := cipd.FuchsiaPlatform(plat)
+		path := fmt.Sprintf("prebuilt/tool/%s-%s/tool", fuchsiaPlat.OS, fuchsiaPlat.Arch)
+		if got, want := readPackageFile(t, jirix.Root, path), "tool for "+plat.String(); got != want {
+			t.Errorf("unexpected content %q in %s, want %q", got, path, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(jirix.Root, "prebuilt", "tool", "windows-x64")); !os.IsNotExist(err) {
+		t.Errorf("package for unsupported platform was installed")
+	}
+
+	// Fetching again is a no-op.
+	before := atomic.LoadInt32(&hits)
+	if err := project.FetchPackages(jirix, pkgs, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if after := atomic.LoadInt32(&hits); after != before {
+		t.Errorf("installed packages were fetched again")
+	}
+
+	// The instances of every platform are verified and repaired.
+	modified := fmt.Sprintf("prebuilt/tool/%s-%s", cipd.FuchsiaPlatform(plats[1]).OS, cipd.FuchsiaPlatform(plats[1]).Arch)
+	if err := os.WriteFile(filepath.Join(jirix.Root, modified, "tool"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := project.VerifyPackages(jirix, pkgs)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if len(statuses) != len(plats) {
+		t.Fatalf("expected a status per platform, got %+v", statuses)
+	}
+	for _, s := range statuses {
+		if s.Path == modified {
+			if s.State != project.PackageStateFilesChanged || s.Name != "test/tool/"+plats[1].String() {
+				t.Errorf("unexpected status %+v of the modified package", s)
+			}
+		} else if !s.OK() {
+			t.Errorf("unexpected status %+v", s)
+		}
+	}
+	if err := project.RepairPackages(jirix, pkgs, statuses, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("repair failed: %v", err)
+	}
+	if got, want := readPackageFile(t, jirix.Root, modified+"/tool"), "tool for "+plats[1].String(); got != want {
+		t.Errorf("unexpected content %q in %s after repair, want %q", got, modified, want)
+	}
+}
+
+func TestCheckPackagePaths(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	projects := project.Projects{}
+	p := project.Project{Name: "tools", Path: filepath.Join(jirix.Root, "tools"), Remote: "https://example.com/tools"}
+	projects[p.Key()] = p
+	cipdPkg := project.Package{Name: "fuchsia/tool", Version: "latest", Path: "prebuilt"}
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{"prebuilt-http/tool", ""},
+		{"tools/bin", `project "tools"`},
+		{"tools-bin", ""},
+		{"prebuilt", `package "fuchsia/tool"`},
+		{"prebuilt/http", `package "fuchsia/tool"`},
+		{".jiri_root/tool", "jiri root"},
+	}
+	for _, test := range tests {
+		pkg := project.Package{Name: "http/tool", Version: "1.0", Path: test.path, Source: project.PackageSourceHTTP, URL: "https://example.com/tool.tar.gz"}
+		pkgs := project.Packages{cipdPkg.Key(): cipdPkg, pkg.Key(): pkg}
+		err := project.InternalCheckPackagePaths(jirix, projects, pkgs)
+		if test.wantErr == "" && err != nil {
+			t.Errorf("path %q: unexpected error %v", test.path, err)
+		} else if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("path %q: got error %v, want it to mention %s", test.path, err, test.wantErr)
+		}
+	}
+}
+
+func TestExtractArchiveSymlinks(t *testing.T) {
+	type entry struct {
+		name, link, content string
+	}
+	extract := func(entries ...entry) (string, error) {
+		root := t.TempDir()
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, e := range entries {
+			hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+			if e.link != "" {
+				hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.link, Typeflag: tar.TypeSymlink}
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(e.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(root, "pkg.tar")
+		if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dest := filepath.Join(root, "pkg")
+		if err := os.Mkdir(dest, 0755); err != nil {
+			t.Fatal(err)
+		}
+		return root, project.InternalExtractArchive(file, dest)
+	}
+
+	// Symlinks within the package are followed.
+	root, err := extract(entry{name: "a/y", content: "y"}, entry{name: "lib", link: "a"}, entry{name: "lib/z", content: "z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "pkg", "a", "z")); err != nil || string(data) != "z" {
+		t.Errorf("expected lib/z to be written through the symlink, got %q, %v", data, err)
+	}
+
+	// A chain of symlinks that are each lexically within the package but
+	// really point above it is rejected.
+	root, err = extract(
+		entry{name: "a/b/l", link: ".."},
+		entry{name: "a/b/l/m", link: "../.."},
+		entry{name: "a/m/x", content: "evil"})
+	if err == nil || !strings.Contains(err.Error(), "outside of the package") {
+		t.Errorf("expected the escaping symlink to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "x")); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written outside of the package, got %v", err)
+	}
+}
+
+func TestResolvePackageVersionAtSeveralPaths(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(makeTarGz(t, map[string]string{"bin/tool": r.URL.Path}))
+	}))
+	defer server.Close()
+
+	pkg := func(version, path string) project.Package {
+		return project.Package{
+			Name:    "test/tool",
+			Version: version,
+			Path:    path,
+			Source:  project.PackageSourceHTTP,
+			URL:     server.URL + "/tool-${version}.tar.gz",
+		}
+	}
+	// Version 1.0 is installed to two paths, the lockfile records one lock
+	// per path.
+	pkgLocks := resolvePackages(t, jirix, pkg("1.0", "a"), pkg("1.0", "b"), pkg("2.0", "c"))
+	got := map[string]string{}
+	for _, lock := range pkgLocks {
+		got[lock.LocalPath] = lock.VersionTag
+	}
+	if want := map[string]string{"a": "1.0", "b": "1.0", "c": "2.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got locks by path %v, want %v", got, want)
+	}
+}
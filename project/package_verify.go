@@ -0,0 +1,152 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+)
+
+// States of an installed package, as reported in PackageStatus.State.
+const (
+	PackageStateOK               = "ok"
+	PackageStateNotInstalled     = "not installed"
+	PackageStateInstanceMismatch = "instance mismatch"
+	PackageStateFilesChanged     = "files changed"
+)
+
+// PackageStatus describes how an installed package compares with the
+// instance that jiri expects for it.
+type PackageStatus struct {
+	Name          string   `json:"name"`
+	Path          string   `json:"path"`
+	Source        string   `json:"source"`
+	State         string   `json:"state"`
+	LockedID      string   `json:"locked_instance_id,omitempty"`
+	InstalledID   string   `json:"installed_instance_id,omitempty"`
+	MissingFiles  []string `json:"missing_files,omitempty"`
+	ModifiedFiles []string `json:"modified_files,omitempty"`
+}
+
+// OK returns true if the package is installed and unmodified.
+func (s PackageStatus) OK() bool {
+	return s.State == PackageStateOK
+}
+
+// finish sorts the file lists of s and computes its state.
+func (s PackageStatus) finish() PackageStatus {
+	sort.Strings(s.MissingFiles)
+	sort.Strings(s.ModifiedFiles)
+	switch {
+	case s.InstalledID == "":
+		s.State = PackageStateNotInstalled
+	case s.LockedID != "" && s.LockedID != s.InstalledID:
+		s.State = PackageStateInstanceMismatch
+	case len(s.MissingFiles) != 0 || len(s.ModifiedFiles) != 0:
+		s.State = PackageStateFilesChanged
+	default:
+		s.State = PackageStateOK
+	}
+	return s
+}
+
+func (cipdSource) Verify(jirix *jiri.X, pkgs Packages) ([]PackageStatus, error) {
+	deployed, err := cipd.Deployed(jirix.Root)
+	if err != nil {
+		return nil, err
+	}
+	deployedByKey := make(map[string]cipd.DeployedPackage)
+	for _, d := range deployed {
+		deployedByKey[cipdDeploymentKey(d.PackageName, d.Subdir)] = d
+	}
+
+	var statuses []PackageStatus
+	for _, pkg := range pkgs {
+		names, err := cipd.Expand(pkg.Name, []cipd.Platform{cipd.CipdPlatform})
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			continue
+		}
+		subdir, err := pkg.expandedPath()
+		if err != nil {
+			return nil, err
+		}
+		status := PackageStatus{
+			Name:     names[0],
+			Path:     subdir,
+			Source:   PackageSourceCIPD,
+			LockedID: pkg.knownInstance(names[0]),
+		}
+		if d, ok := deployedByKey[cipdDeploymentKey(names[0], subdir)]; ok {
+			status.InstalledID = d.InstanceID
+			status.MissingFiles, status.ModifiedFiles = d.Check(jirix.Root)
+		}
+		statuses = append(statuses, status.finish())
+	}
+	return statuses, nil
+}
+
+func cipdDeploymentKey(name, subdir string) string {
+	subdir = path.Clean(filepath.ToSlash(subdir))
+	if subdir == "." {
+		subdir = ""
+	}
+	return name + "\x00" + subdir
+}
+
+// VerifyPackages compares the installed instances and files of pkgs with the
+// expected ones, for the current platform and, for the packages not
+// installed by cipd, the other fetch platforms too. The returned statuses are
+// sorted by path and name.
+func VerifyPackages(jirix *jiri.X, pkgs Packages) ([]PackageStatus, error) {
+	pkgsBySource, err := pkgs.bySource()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []PackageStatus
+	for _, name := range sortedSourceNames(pkgsBySource) {
+		s, err := packageSources[name].Verify(jirix, pkgsBySource[name])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s...)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Path != statuses[j].Path {
+			return statuses[i].Path < statuses[j].Path
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+// RepairPackages re-installs the packages with a broken status. Parameter
+// pkgs must contain all of the packages that should be installed, as
+// fetching removes cipd packages that it does not know about.
+func RepairPackages(jirix *jiri.X, pkgs Packages, statuses []PackageStatus, fetchTimeout uint) error {
+	for _, s := range statuses {
+		if s.OK() {
+			continue
+		}
+		if s.Source == PackageSourceCIPD {
+			// Let cipd check the hashes of every deployed file.
+			jirix.CipdCheckIntegrity = true
+			continue
+		}
+		// Without a stamp the package is always installed again.
+		stamp := filepath.Join(jirix.Root, filepath.FromSlash(s.Path), packageStampFile)
+		if err := os.Remove(stamp); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return FetchPackages(jirix, pkgs, fetchTimeout)
+}
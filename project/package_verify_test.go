@@ -0,0 +1,165 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.fuchsia.dev/jiri/jiritest/xtest"
+	"go.fuchsia.dev/jiri/project"
+)
+
+func TestVerifyHTTPPackage(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	archive := makeTarGz(t, map[string]string{"bin/tool": "tool v1", "README": "readme"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	pkg := project.Package{
+		Name:    "test/tool",
+		Version: "1.0",
+		Path:    "prebuilt/tool",
+		Source:  project.PackageSourceHTTP,
+		URL:     server.URL + "/tool.tar.gz",
+		Instances: []project.PackageInstance{
+			{Name: "test/tool", ID: digestOf(archive)},
+		},
+	}
+	pkgs := project.Packages{pkg.Key(): pkg}
+
+	verify := func() project.PackageStatus {
+		statuses, err := project.VerifyPackages(jirix, pkgs)
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		if len(statuses) != 1 {
+			t.Fatalf("expected one status, got %+v", statuses)
+		}
+		return statuses[0]
+	}
+
+	if s := verify(); s.State != project.PackageStateNotInstalled {
+		t.Errorf("unexpected state %q before fetch", s.State)
+	}
+	if err := project.FetchPackages(jirix, pkgs, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if s := verify(); !s.OK() || s.InstalledID != digestOf(archive) {
+		t.Errorf("unexpected status %+v after fetch", s)
+	}
+
+	dir := filepath.Join(jirix.Root, "prebuilt", "tool")
+	if err := os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "README")); err != nil {
+		t.Fatal(err)
+	}
+	s := verify()
+	if s.State != project.PackageStateFilesChanged ||
+		!reflect.DeepEqual(s.ModifiedFiles, []string{"bin/tool"}) ||
+		!reflect.DeepEqual(s.MissingFiles, []string{"README"}) {
+		t.Errorf("unexpected status %+v after modification", s)
+	}
+
+	if err := project.RepairPackages(jirix, pkgs, []project.PackageStatus{s}, project.DefaultPackageTimeout); err != nil {
+		t.Fatalf("repair failed: %v", err)
+	}
+	if s := verify(); !s.OK() {
+		t.Errorf("unexpected status %+v after repair", s)
+	}
+	if got, want := readPackageFile(t, jirix.Root, "prebuilt/tool/bin/tool"), "tool v1"; got != want {
+		t.Errorf("unexpected package content %q, want %q", got, want)
+	}
+}
+
+func TestVerifyCipdPackage(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	// Lay out the site metadata the cipd client writes when deploying a
+	// package.
+	writeFile := func(path, content string) {
+		path = filepath.Join(jirix.Root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readmeHash := fmt.Sprintf("%x", sha256.Sum256([]byte("readme")))
+	writeFile(".cipd/pkgs/0/description.json", `{"subdir": "prebuilt/tool", "package_name": "test/tool"}`)
+	writeFile(".cipd/pkgs/0/_current.txt", "installed-id")
+	writeFile(".cipd/pkgs/0/installed-id/.cipdpkg/manifest.json", `{
+  "files": [
+    {"name": "bin/tool", "size": 7},
+    {"name": "bin/link", "symlink": "tool"},
+    {"name": "README", "size": 6, "hash": "`+readmeHash+`"},
+    {"name": ".cipdpkg/manifest.json", "size": 100}
+  ]
+}`)
+	writeFile("prebuilt/tool/bin/tool", "tool v1")
+	writeFile("prebuilt/tool/README", "readme")
+	if err := os.Symlink("tool", filepath.Join(jirix.Root, "prebuilt", "tool", "bin", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := project.Package{
+		Name:      "test/tool",
+		Version:   "version:1",
+		Path:      "prebuilt/tool",
+		Instances: []project.PackageInstance{{Name: "test/tool", ID: "installed-id"}},
+	}
+	other := project.Package{Name: "test/other", Version: "version:1", Path: "prebuilt/other"}
+	pkgs := project.Packages{pkg.Key(): pkg, other.Key(): other}
+
+	statuses, err := project.VerifyPackages(jirix, pkgs)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	want := []project.PackageStatus{
+		{Name: "test/other", Path: "prebuilt/other", Source: "cipd", State: project.PackageStateNotInstalled},
+		{Name: "test/tool", Path: "prebuilt/tool", Source: "cipd", State: project.PackageStateOK, LockedID: "installed-id", InstalledID: "installed-id"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("unexpected statuses\ngot:  %+v\nwant: %+v", statuses, want)
+	}
+
+	// An edit which keeps the size is detected using the recorded hash.
+	writeFile("prebuilt/tool/README", "README")
+	if err := os.Remove(filepath.Join(jirix.Root, "prebuilt", "tool", "bin", "link")); err != nil {
+		t.Fatal(err)
+	}
+	pkg.Instances[0].ID = "locked-id"
+	statuses, err = project.VerifyPackages(jirix, project.Packages{pkg.Key(): pkg})
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	want = []project.PackageStatus{{
+		Name:          "test/tool",
+		Path:          "prebuilt/tool",
+		Source:        "cipd",
+		State:         project.PackageStateInstanceMismatch,
+		LockedID:      "locked-id",
+		InstalledID:   "installed-id",
+		MissingFiles:  []string{"bin/link"},
+		ModifiedFiles: []string{"README"},
+	}}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("unexpected statuses\ngot:  %+v\nwant: %+v", statuses, want)
+	}
+}
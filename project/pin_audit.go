@@ -0,0 +1,196 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/retry"
+)
+
+// OrphanedPin is a pinned project revision which is not reachable from the
+// remote branch of the project nor from any allowed ref.
+type OrphanedPin struct {
+	Name         string `json:"name"`
+	Remote       string `json:"remote"`
+	RemoteBranch string `json:"remote_branch"`
+	Revision     string `json:"revision"`
+	// Source is the manifest or lockfile that pins the revision.
+	Source string `json:"source"`
+	// Missing is true if the revision could not be fetched from the
+	// branches of the remote at all.
+	Missing bool `json:"missing,omitempty"`
+}
+
+func (o OrphanedPin) String() string {
+	reason := fmt.Sprintf("not reachable from %q or an allowed ref", o.RemoteBranch)
+	if o.Missing {
+		reason = "not found on any branch of the remote"
+	}
+	return fmt.Sprintf("%s(remote: %s) at %s pinned by %s: %s", o.Name, o.Remote, o.Revision, o.Source, reason)
+}
+
+// pinnedRevision is a revision a project is pinned to by source.
+type pinnedRevision struct {
+	revision, source string
+}
+
+// collectPins returns the revisions projects are pinned to by the manifests
+// and by the project locks in lockFiles, keyed by project.
+func collectPins(projects Projects, lockFiles []string) (map[ProjectKey][]pinnedRevision, error) {
+	pins := make(map[ProjectKey][]pinnedRevision)
+	add := func(key ProjectKey, pin pinnedRevision) {
+		for _, p := range pins[key] {
+			if p.revision == pin.revision {
+				return
+			}
+		}
+		pins[key] = append(pins[key], pin)
+	}
+	for key, p := range projects {
+		if p.Revision != "" && p.Revision != "HEAD" {
+			add(key, pinnedRevision{p.Revision, p.ManifestPath})
+		}
+	}
+	for _, lockFile := range lockFiles {
+		data, err := os.ReadFile(lockFile)
+		if err != nil {
+			return nil, err
+		}
+		projectLocks, _, err := UnmarshalLockEntries(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse lockfile %q: %v", lockFile, err)
+		}
+		for _, lock := range sortedProjectLocks(projectLocks) {
+			// Locks of projects which are no longer in the manifests
+			// are reported by "jiri resolve -check".
+			if _, ok := projects[ProjectKey(lock.Key())]; ok {
+				add(ProjectKey(lock.Key()), pinnedRevision{lock.Revision, lockFile})
+			}
+		}
+	}
+	return pins, nil
+}
+
+// AuditPins checks that every revision the projects in manifestFiles are
+// pinned to, either in the manifests or in the lockfile at lockFilePath and
+// the lockfiles next to the manifests, is reachable from the remote branch
+// of its project or from a ref matching one of allowedRefs, such as
+// "refs/heads/releases/*" or "refs/tags/". The check is done in the git
+// cache, which is fetched first. It returns the orphaned pins.
+func AuditPins(jirix *jiri.X, manifestFiles []string, localManifestProjects []string, lockFilePath string, allowedRefs []string) ([]OrphanedPin, error) {
+	if jirix.Cache == "" {
+		return nil, fmt.Errorf("auditing pins requires a git cache, run \"jiri init -cache=<dir>\" to set one")
+	}
+	projects, _, err := loadManifestFiles(jirix, manifestFiles, localManifestProjects)
+	if err != nil {
+		return nil, err
+	}
+	lockFiles, err := checkedLockFiles(jirix, manifestFiles, lockFilePath)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := collectPins(projects, lockFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	type auditJob struct {
+		project Project
+		dir     string
+		pins    []pinnedRevision
+	}
+	var jobs []auditJob
+	cacheMutexes := make(map[string]*sync.Mutex)
+	for key, projectPins := range pins {
+		project := projects[key]
+		if err := project.fillDefaults(); err != nil {
+			return nil, err
+		}
+		dir, err := project.CacheDirPath(jirix)
+		if err != nil {
+			return nil, err
+		}
+		if cacheMutexes[dir] == nil {
+			cacheMutexes[dir] = &sync.Mutex{}
+		}
+		jobs = append(jobs, auditJob{project, dir, projectPins})
+	}
+
+	var orphans []OrphanedPin
+	var mu sync.Mutex
+	errs := make(chan error, len(jobs))
+	var wg sync.WaitGroup
+	fetchLimit := make(chan struct{}, jirix.Jobs)
+	for _, job := range jobs {
+		wg.Add(1)
+		fetchLimit <- struct{}{}
+		go func(job auditJob, cacheMutex *sync.Mutex) {
+			defer func() { <-fetchLimit }()
+			defer wg.Done()
+			cacheMutex.Lock()
+			defer cacheMutex.Unlock()
+			project := job.project
+			remote := rewriteRemote(jirix, project.Remote)
+			if err := updateOrCreateCache(jirix, job.dir, remote, project.RemoteBranch, job.pins[0].revision, 0, false); err != nil {
+				errs <- err
+				return
+			}
+			// The cache is only fetched if a revision is missing, so fetch
+			// again to prune deleted branches and to get the tags allowed
+			// refs may match.
+			scm := gitutil.New(jirix, gitutil.RootDirOpt(job.dir))
+			refspec := "+refs/heads/*:refs/heads/*"
+			if err := retry.Function(jirix, func() error {
+				return scm.FetchRefspec("origin", refspec, gitutil.TagsOpt(true), gitutil.PruneOpt(true), gitutil.UpdateHeadOkOpt(true))
+			}, fmt.Sprintf("Fetching for %s:%s", job.dir, refspec),
+				retry.AttemptsOpt(jirix.Attempts)); err != nil {
+				errs <- err
+				return
+			}
+			patterns := append([]string{"refs/heads/" + project.RemoteBranch}, allowedRefs...)
+			for _, pin := range job.pins {
+				orphan := OrphanedPin{
+					Name:         project.Name,
+					Remote:       project.Remote,
+					RemoteBranch: project.RemoteBranch,
+					Revision:     pin.revision,
+					Source:       pin.source,
+				}
+				if !scm.IsRevAvailable(jirix, remote, pin.revision) {
+					orphan.Missing = true
+				} else if refs, err := scm.RefsContainingCommit(pin.revision, patterns...); err != nil {
+					errs <- err
+					return
+				} else if len(refs) != 0 {
+					continue
+				}
+				mu.Lock()
+				orphans = append(orphans, orphan)
+				mu.Unlock()
+			}
+		}(job, cacheMutexes[job.dir])
+	}
+	wg.Wait()
+	close(errs)
+	if err := errFromChannel(errs); err != nil {
+		return nil, err
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Name != orphans[j].Name {
+			return orphans[i].Name < orphans[j].Name
+		}
+		if orphans[i].Revision != orphans[j].Revision {
+			return orphans[i].Revision < orphans[j].Revision
+		}
+		return orphans[i].Source < orphans[j].Source
+	})
+	return orphans, nil
+}
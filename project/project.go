@@ -67,12 +67,22 @@ type Project struct {
 	// update".  If Revision is set, RemoteBranch will be ignored.  If Revision
 	// is not set, "HEAD" is used as the default.
 	Revision string `xml:"revision,attr,omitempty"`
+	// Track is a constraint selecting the tag the project follows when
+	// Revision is not set, e.g. "tag:v1.*" for the newest tag matching a
+	// pattern or "semver:^1.2" for the highest matching semantic version.
+	// It is resolved to a revision by "jiri resolve", or by "jiri update"
+	// if the project is not locked.
+	Track string `xml:"track,attr,omitempty"`
 	// HistoryDepth is the depth flag passed to git clone and git fetch
 	// commands. It is used to limit downloading large histories for large
 	// projects.
 	HistoryDepth int `xml:"historydepth,attr,omitempty"`
 	// GerritHost is the gerrit host where project CLs will be sent.
 	GerritHost string `xml:"gerrithost,attr,omitempty"`
+	// ReviewHost selects the code review host of the project: "gerrit", the
+	// default, or "github" to review changes as GitHub pull requests. An API
+	// URL may follow after a colon, e.g. "github:https://github.example.com/api/v3".
+	ReviewHost string `xml:"reviewhost,attr,omitempty"`
 	// GitHooks is a directory containing git hooks that will be installed for
 	// this project.
 	GitHooks string `xml:"githooks,attr,omitempty"`
@@ -97,6 +107,10 @@ type Project struct {
 	// this project is successfully fetched.
 	Flag string `xml:"flag,attr,omitempty"`
 
+	// License is the SPDX license expression of the project, e.g.
+	// "Apache-2.0". It is recorded in the SBOMs generated by "jiri sbom".
+	License string `xml:"license,attr,omitempty"`
+
 	XMLName struct{} `xml:"project"`
 
 	// This is used to store computed key. This is useful when remote and
@@ -285,6 +299,11 @@ func (p *Project) validate() error {
 	if strings.Contains(p.Name, KeySeparator) {
 		return fmt.Errorf("bad project: name cannot contain %q: %+v", KeySeparator, *p)
 	}
+	if p.Track != "" {
+		if _, err := parseTrack(p.Track); err != nil {
+			return fmt.Errorf("bad project %q: %v", p.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -298,18 +317,27 @@ func (p *Project) update(other *Project) {
 	if other.Revision != "" {
 		p.Revision = other.Revision
 	}
+	if other.Track != "" {
+		p.Track = other.Track
+	}
 	if other.HistoryDepth != 0 {
 		p.HistoryDepth = other.HistoryDepth
 	}
 	if other.GerritHost != "" {
 		p.GerritHost = other.GerritHost
 	}
+	if other.ReviewHost != "" {
+		p.ReviewHost = other.ReviewHost
+	}
 	if other.GitHooks != "" {
 		p.GitHooks = other.GitHooks
 	}
 	if other.Flag != "" {
 		p.Flag = other.Flag
 	}
+	if other.License != "" {
+		p.License = other.License
+	}
 }
 
 // WriteProjectFlags write flag files into project directory using in "flag"
@@ -422,6 +450,10 @@ type ProjectLock struct {
 	Remote   string `json:"repository_url"`
 	Name     string `json:"name"`
 	Revision string `json:"revision"`
+	// The fields below are only recorded in version 2 lockfiles.
+	Manifest   string `json:"manifest,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	ResolvedAt string `json:"resolved_at,omitempty"`
 }
 
 // ProjectLockKey defines the key used in ProjectLocks type
@@ -441,18 +473,35 @@ func (p ProjectLock) Key() ProjectLockKey {
 	return ProjectLockKey{name: p.Name, remote: p.Remote}
 }
 
+// LockEqual determines whether current ProjectLock pins the same project to
+// the same revision as ProjectLock other.
+func (p ProjectLock) LockEqual(other ProjectLock) bool {
+	return p.Remote == other.Remote && p.Name == other.Name && p.Revision == other.Revision
+}
+
 // PackageLock describes locked version information for a jiri managed package.
 type PackageLock struct {
 	PackageName string `json:"package"`
 	LocalPath   string `json:"path,omitempty"`
 	VersionTag  string `json:"version"`
 	InstanceID  string `json:"instance_id"`
-}
-
-// PackageLockKey defines the key used in PackageLocks type
+	Source      string `json:"source,omitempty"`
+	Digest      string `json:"digest,omitempty"`
+	// The fields below are only recorded in version 2 lockfiles. Platform
+	// is only set for packages whose names use platform templates.
+	Platform   string `json:"platform,omitempty"`
+	Manifest   string `json:"manifest,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	ResolvedAt string `json:"resolved_at,omitempty"`
+}
+
+// PackageLockKey defines the key used in PackageLocks type. Locks read from
+// version 1 lockfiles usually have neither a local path nor a platform.
 type PackageLockKey struct {
 	packageName string
 	versionTag  string
+	localPath   string
+	platform    string
 }
 
 func MakePackageLockKey(packageName string, versionTag string) PackageLockKey {
@@ -463,7 +512,29 @@ func MakePackageLockKey(packageName string, versionTag string) PackageLockKey {
 type PackageLocks map[PackageLockKey]PackageLock
 
 func (p PackageLock) Key() PackageLockKey {
-	return MakePackageLockKey(p.PackageName, p.VersionTag)
+	return PackageLockKey{
+		packageName: p.PackageName,
+		versionTag:  p.VersionTag,
+		localPath:   p.LocalPath,
+		platform:    p.Platform,
+	}
+}
+
+// find returns the lock of package name at version for the package
+// installed to localPath on platform. Locks without a local path or a
+// platform match any.
+func (l PackageLocks) find(name, version, localPath, platform string) (PackageLock, bool) {
+	for _, k := range []PackageLockKey{
+		{name, version, localPath, platform},
+		{name, version, localPath, ""},
+		{name, version, "", platform},
+		{name, version, "", ""},
+	} {
+		if lock, ok := l[k]; ok {
+			return lock, true
+		}
+	}
+	return PackageLock{}, false
 }
 
 // LockEqual determines whether current PackageLock has same version and
@@ -471,7 +542,8 @@ func (p PackageLock) Key() PackageLockKey {
 func (p PackageLock) LockEqual(other PackageLock) bool {
 	return (p.PackageName == other.PackageName &&
 		p.VersionTag == other.VersionTag &&
-		p.InstanceID == other.InstanceID)
+		p.InstanceID == other.InstanceID &&
+		p.Digest == other.Digest)
 }
 
 // ResolveConfig interface provides the configuration
@@ -484,11 +556,17 @@ type ResolveConfig interface {
 	EnableProjectLock() bool
 	HostnameAllowList() []string
 	FullResolve() bool
+	RecordResolveTime() bool
 }
 
 // UnmarshalLockEntries unmarshals project locks and package locks from
 // jsonData.
 func UnmarshalLockEntries(jsonData []byte) (ProjectLocks, PackageLocks, error) {
+	if version, err := LockFileVersion(jsonData); err != nil {
+		return nil, nil, err
+	} else if version != 1 {
+		return unmarshalLockFile(jsonData)
+	}
 	projectLocks := make(ProjectLocks)
 	pkgLocks := make(PackageLocks)
 	var entries []map[string]string
@@ -502,15 +580,16 @@ func UnmarshalLockEntries(jsonData []byte) (ProjectLocks, PackageLocks, error) {
 				VersionTag:  entry["version"],
 				InstanceID:  entry["instance_id"],
 				LocalPath:   entry["path"],
+				Source:      entry["source"],
+				Digest:      entry["digest"],
 			}
 			if v, ok := pkgLocks[pkgLock.Key()]; ok {
 				// HACK: allow the same package to be pinned to the same version
 				// at two different paths by only checking equality of the
 				// InstanceID instead of the entire structs.
 				//
-				// A better way to do this would be to include the `LocalPath`
-				// in the inputs to the PackageLock key, but that would require
-				// adding `path` fields to all existing lockfiles.
+				// Version 2 lockfiles record the path of every entry, which
+				// avoids this.
 				if v.InstanceID != pkgLock.InstanceID {
 					return nil, nil, fmt.Errorf("package %q has more than 1 version lock %q, %q", pkgName, v.InstanceID, pkgLock.InstanceID)
 				}
@@ -523,7 +602,7 @@ func UnmarshalLockEntries(jsonData []byte) (ProjectLocks, PackageLocks, error) {
 				Revision: entry["revision"],
 			}
 			if v, ok := projectLocks[projectLock.Key()]; ok {
-				if v != projectLock {
+				if !v.LockEqual(projectLock) {
 					return nil, nil, fmt.Errorf("package %q has more than 1 revision lock %q, %q", repoURL, v.Revision, projectLock.Revision)
 				}
 			}
@@ -535,47 +614,44 @@ func UnmarshalLockEntries(jsonData []byte) (ProjectLocks, PackageLocks, error) {
 }
 
 // MarshalLockEntries marshals project locks and package locks into
-// json format data.
+// json format data, using version 1 of the lockfile format.
 func MarshalLockEntries(projectLocks ProjectLocks, pkgLocks PackageLocks) ([]byte, error) {
-	entries := make([]any, len(projectLocks)+len(pkgLocks))
-	projEntries := make([]ProjectLock, len(projectLocks))
-	pkgEntries := make([]PackageLock, len(pkgLocks))
+	return MarshalLockFile(1, projectLocks, pkgLocks)
+}
 
-	i := 0
-	for _, v := range projectLocks {
-		projEntries[i] = v
-		i++
+// MarshalLockFile marshals project locks and package locks into json
+// format data, using the given version of the lockfile format.
+func MarshalLockFile(version int, projectLocks ProjectLocks, pkgLocks PackageLocks) ([]byte, error) {
+	switch version {
+	case 0, 1:
+	case 2:
+		return marshalLockFileV2(projectLocks, pkgLocks)
+	default:
+		return nil, fmt.Errorf("unsupported lockfile version %d", version)
 	}
-	sort.Slice(projEntries, func(i, j int) bool {
-		if projEntries[i].Remote == projEntries[j].Remote {
-			return projEntries[i].Name < projEntries[j].Name
-		}
-		return projEntries[i].Remote < projEntries[j].Remote
-	})
 
-	i = 0
-	for _, v := range pkgLocks {
-		pkgEntries[i] = v
-		i++
+	projEntries := sortedProjectLocks(projectLocks)
+	for i := range projEntries {
+		projEntries[i] = projEntries[i].v1()
 	}
-	sort.Slice(pkgEntries, func(i, j int) bool {
-		if pkgEntries[i].PackageName != pkgEntries[j].PackageName {
-			return pkgEntries[i].PackageName < pkgEntries[j].PackageName
-		}
-		if pkgEntries[i].LocalPath != pkgEntries[j].LocalPath {
-			return pkgEntries[i].LocalPath < pkgEntries[j].LocalPath
+	var pkgEntries []PackageLock
+	seen := make(map[PackageLockKey]bool)
+	for _, v := range sortedPackageLocks(pkgLocks) {
+		// Locks of version 2 may only differ in fields which version 1
+		// does not record.
+		v = v.v1()
+		if !seen[v.Key()] {
+			seen[v.Key()] = true
+			pkgEntries = append(pkgEntries, v)
 		}
-		return pkgEntries[i].VersionTag < pkgEntries[j].VersionTag
-	})
+	}
 
-	i = 0
+	entries := make([]any, 0, len(projEntries)+len(pkgEntries))
 	for _, v := range projEntries {
-		entries[i] = v
-		i++
+		entries = append(entries, v)
 	}
 	for _, v := range pkgEntries {
-		entries[i] = v
-		i++
+		entries = append(entries, v)
 	}
 
 	jsonData, err := json.MarshalIndent(&entries, "", "    ")
@@ -1188,7 +1264,7 @@ func loadManifestFiles(jirix *jiri.X, manifestFiles []string, localManifestProje
 }
 
 func writeLockFile(jirix *jiri.X, lockfilePath string, projectLocks ProjectLocks, pkgLocks PackageLocks) error {
-	data, err := MarshalLockEntries(projectLocks, pkgLocks)
+	data, err := MarshalLockFile(jirix.LockfileVersion, projectLocks, pkgLocks)
 	if err != nil {
 		return err
 	}
@@ -1332,7 +1408,32 @@ func getChangedLocksPkgs(ePkgLocks PackageLocks, pkgs Packages) (PackageLocks, P
 func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig ResolveConfig) error {
 	jirix.Logger.Debugf("Generate jiri lockfile for manifests %v to %q", manifestFiles, resolveConfig.LockFilePath())
 
-	resolveLocks := func(jirix *jiri.X, manifestFiles []string, resolveFully bool, ePkgLocks PackageLocks) (projectLocks ProjectLocks, pkgLocks PackageLocks, err error) {
+	resolveFully := false
+	var eProjectLocks ProjectLocks
+	var ePkgLocks PackageLocks
+	// Read existing lockfile.
+	jsonData, err := os.ReadFile(resolveConfig.LockFilePath())
+	if err == nil {
+		eProjectLocks, ePkgLocks, err = UnmarshalLockEntries(jsonData)
+	}
+	if err != nil {
+		resolveFully = true
+	}
+	resolveFully = resolveFully || resolveConfig.FullResolve()
+
+	projectLocks, pkgLocks, err := resolveManifestLocks(jirix, manifestFiles, resolveConfig, resolveFully, eProjectLocks, ePkgLocks)
+	if err != nil {
+		return err
+	}
+	return writeLockFile(jirix, resolveConfig.LockFilePath(), projectLocks, pkgLocks)
+}
+
+// resolveManifestLocks returns the locks of the projects and packages in
+// manifestFiles. Unless resolveFully is set, packages whose locks exist in
+// ePkgLocks are not resolved again.
+func resolveManifestLocks(jirix *jiri.X, manifestFiles []string, resolveConfig ResolveConfig, resolveFully bool, eProjectLocks ProjectLocks, ePkgLocks PackageLocks) (ProjectLocks, PackageLocks, error) {
+	now := resolveTime(resolveConfig)
+	resolveLocks := func(jirix *jiri.X, manifestFiles []string, resolveFully bool, eProjectLocks ProjectLocks, ePkgLocks PackageLocks) (projectLocks ProjectLocks, pkgLocks PackageLocks, err error) {
 		projects, pkgs, err := loadManifestFiles(jirix, manifestFiles, resolveConfig.LocalManifestProjects())
 		if err != nil {
 			return nil, nil, err
@@ -1344,9 +1445,21 @@ func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig R
 		if resolveConfig.EnableProjectLock() {
 			// For project locks, there is no differences between
 			// full or partial resolve.
+			refs, err := resolveTrackedProjects(jirix, projects, eProjectLocks)
+			if err != nil {
+				return nil, nil, err
+			}
 			projectLocks, err = resolveProjectLocks(projects)
 			if err != nil {
-				return
+				return nil, nil, err
+			}
+			for k, ref := range refs {
+				lock := projectLocks[ProjectLockKey(k)]
+				lock.Ref = ref
+				projectLocks[lock.Key()] = lock
+			}
+			if jirix.LockfileVersion >= 2 {
+				lockProjectsV2(jirix, projects, projectLocks, eProjectLocks, now)
 			}
 		}
 		if resolveConfig.EnablePackageLock() {
@@ -1361,10 +1474,30 @@ func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig R
 					resolveFully = true
 				}
 			}
+			if !resolveFully {
+				// Version constraints are resolved again on every run.
+				for k, v := range pkgs {
+					if !isVersionConstraint(v.Version) {
+						continue
+					}
+					pkgsToProcess[k] = v
+					targets, err := v.lockTargets()
+					if err != nil {
+						return nil, nil, err
+					}
+					for _, t := range targets {
+						for lk, lock := range ePkgLocks {
+							if lock.PackageName == t.name && lock.VersionTag == v.Version {
+								locksToProcess[lk] = lock
+							}
+						}
+					}
+				}
+			}
 			if !resolveConfig.AllowFloatingRefs() {
 				pkgsForRefCheck := make(map[cipd.PackageInstance]bool)
 				pkgsPlatformMap := make(map[cipd.PackageInstance][]cipd.Platform)
-				for _, v := range pkgsToProcess {
+				for _, v := range pkgsToProcess.filterSource(PackageSourceCIPD) {
 					pkgInstance := cipd.PackageInstance{
 						PackageName: v.Name,
 						VersionTag:  v.Version,
@@ -1402,9 +1535,8 @@ func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig R
 					delete(pkgsWithMultiVersionsMap, k)
 				}
 			}
-			pkgLocks, err = resolvePackageLocks(jirix, pkgsToProcess)
-			if err != nil {
-				return
+			if pkgLocks, err = resolveConstrainedPackageLocks(jirix, pkgsToProcess); err != nil {
+				return nil, nil, err
 			}
 			// Merge with existing locks.
 			if !resolveFully {
@@ -1415,6 +1547,10 @@ func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig R
 					pkgLocks[k] = v
 				}
 			}
+			if jirix.LockfileVersion >= 2 {
+				pkgLocks, err = lockPackagesV2(jirix, pkgs, pkgLocks, now)
+				return
+			}
 			// sort the keys of pkgs to avoid nondeterministic output.
 			pkgKeys := make([]PackageKey, 0)
 			for k := range pkgs {
@@ -1423,50 +1559,38 @@ func GenerateJiriLockFile(jirix *jiri.X, manifestFiles []string, resolveConfig R
 			sort.Slice(pkgKeys, func(i, j int) bool {
 				return pkgKeys[i].Less(pkgKeys[j])
 			})
+			// The same version may be installed to several paths, so the
+			// locks without a path are only removed once all of them have
+			// been given a lock of their own.
+			var pathless []PackageLockKey
 			for _, k := range pkgKeys {
 				v := pkgs[k]
 				if _, ok := pkgsWithMultiVersionsMap[v.Name]; ok {
-					plats, err := v.GetPlatforms()
+					targets, err := v.lockTargets()
 					if err != nil {
 						return nil, nil, err
 					}
-					expandedNames, err := cipd.Expand(v.Name, plats)
-					if err != nil {
-						return nil, nil, err
-					}
-					for _, expandedName := range expandedNames {
-						lockKey := MakePackageLockKey(expandedName, v.Version)
-						lockEntry, ok := pkgLocks[lockKey]
+					for _, t := range targets {
+						lockEntry, ok := pkgLocks.find(t.name, v.Version, v.Path, t.platform)
 						if !ok {
-							jirix.Logger.Errorf("lock key not found in pkgLocks: %v, package: %+v", lockKey, v)
-							return nil, nil, err
+							return nil, nil, fmt.Errorf("lock of package %q version %q not found", t.name, v.Version)
+						}
+						if lockEntry.LocalPath != v.Path {
+							pathless = append(pathless, lockEntry.Key())
 						}
 						lockEntry.LocalPath = v.Path
-						pkgLocks[lockKey] = lockEntry
+						pkgLocks[lockEntry.Key()] = lockEntry
 					}
 				}
 			}
+			for _, k := range pathless {
+				delete(pkgLocks, k)
+			}
 		}
 		return
 	}
 
-	resolveFully := false
-	var ePkgLocks PackageLocks
-	// Read existing lockfile.
-	jsonData, err := os.ReadFile(resolveConfig.LockFilePath())
-	if err == nil {
-		_, ePkgLocks, err = UnmarshalLockEntries(jsonData)
-	}
-	if err != nil {
-		resolveFully = true
-	}
-	resolveFully = resolveFully || resolveConfig.FullResolve()
-
-	projectLocks, pkgLocks, err := resolveLocks(jirix, manifestFiles, resolveFully, ePkgLocks)
-	if err != nil {
-		return err
-	}
-	return writeLockFile(jirix, resolveConfig.LockFilePath(), projectLocks, pkgLocks)
+	return resolveLocks(jirix, manifestFiles, resolveFully, eProjectLocks, ePkgLocks)
 }
 
 type UpdateUniverseParams struct {
@@ -1477,6 +1601,7 @@ type UpdateUniverseParams struct {
 	RunHooks              bool
 	FetchPackages         bool
 	RebaseSubmodules      bool
+	AutoStash             bool
 	RunHookTimeout        uint
 	FetchPackagesTimeout  uint
 	PackagesToSkip        []string
@@ -1514,6 +1639,9 @@ func UpdateUniverse(jirix *jiri.X, params UpdateUniverseParams) (e error) {
 		if err != nil {
 			return err
 		}
+		if err := resolveUnlockedConstraints(jirix, remoteProjects, pkgs); err != nil {
+			return err
+		}
 
 		// Actually update the projects.
 		return updateProjects(jirix, localProjects, remoteProjects, hooks, pkgs, false /*snapshot*/, params)
@@ -2033,7 +2161,7 @@ func syncProjectMaster(jirix *jiri.X, project Project, state ProjectState, rebas
 				msg += "\n" + item
 			}
 		}
-		msg += "\nCommit or discard the changes and try again.\n\n"
+		msg += "\nCommit or discard the changes and try again, or update with -autostash.\n\n"
 		jirix.Logger.Errorf("%s", msg)
 		jirix.IncrementFailures()
 		return nil
@@ -2613,21 +2741,37 @@ func updateProjects(jirix *jiri.X, localProjects, remoteProjects Projects, hooks
 		return err
 	}
 
-	batchOps := append(operations(nil), ops...)
-	for len(batchOps) > 0 {
-		batch := operations{batchOps[0]}
-		opType := fmt.Sprintf("%T", batchOps[0])
-		batchOps = batchOps[1:]
-		for len(batchOps) > 0 && opType == fmt.Sprintf("%T", batchOps[0]) {
-			if err := batchOps[0].Test(jirix); err != nil {
+	// Stash the local changes which would prevent updating the projects,
+	// and re-apply them once the projects are updated, even if the update
+	// fails.
+	stashes, err := autoStashProjects(jirix, ops, params.AutoStash)
+	if err != nil {
+		restoreAutoStashes(jirix, stashes)
+		return err
+	}
+	runOps := func() error {
+		batchOps := append(operations(nil), ops...)
+		for len(batchOps) > 0 {
+			batch := operations{batchOps[0]}
+			opType := fmt.Sprintf("%T", batchOps[0])
+			batchOps = batchOps[1:]
+			for len(batchOps) > 0 && opType == fmt.Sprintf("%T", batchOps[0]) {
+				if err := batchOps[0].Test(jirix); err != nil {
+					return err
+				}
+				batch = append(batch, batchOps[0])
+				batchOps = batchOps[1:]
+			}
+			if err := runBatch(jirix, params.GC, batch); err != nil {
 				return err
 			}
-			batch = append(batch, batchOps[0])
-			batchOps = batchOps[1:]
-		}
-		if err := runBatch(jirix, params.GC, batch); err != nil {
-			return err
 		}
+		return nil
+	}
+	err = runOps()
+	restoreAutoStashes(jirix, stashes)
+	if err != nil {
+		return err
 	}
 
 	// Set project to assume-unchanged to index in tree to avoid unpredictable submodule changes.
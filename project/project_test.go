@@ -808,6 +808,99 @@ func TestBranchUpdateWhenNoRebase(t *testing.T) {
 	}
 }
 
+func TestUpdateAutoStash(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fake := setupUniverse(t)
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	update := func(autostash bool) {
+		t.Helper()
+		if err := project.UpdateUniverse(fake.X, project.UpdateUniverseParams{
+			AutoStash:            autostash,
+			RunHookTimeout:       project.DefaultHookTimeout,
+			FetchPackagesTimeout: project.DefaultPackageTimeout,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkContent := func(file, want string) {
+		t.Helper()
+		data, err := os.ReadFile(file)
+		if want == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("expected %s not to exist, got %v", file, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: got %q, want %q", file, data, want)
+		}
+	}
+	checkUpdated := func(p project.Project) {
+		t.Helper()
+		remoteRev, err := gitutil.New(fake.X, gitutil.RootDirOpt(fake.Projects[p.Name])).CurrentRevision()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if localRev, err := gitutil.New(fake.X, gitutil.RootDirOpt(p.Path)).CurrentRevision(); err != nil {
+			t.Fatal(err)
+		} else if localRev != remoteRev {
+			t.Errorf("project %s: got revision %s, want %s", p.Name, localRev, remoteRev)
+		}
+	}
+
+	p := localProjects[1]
+	readme := writeUncommitedFile(t, p.Path, "README", "local change")
+	untracked := writeUncommitedFile(t, p.Path, "untracked", "untracked")
+	writeFile(t, fake.X, fake.Projects[p.Name], "file", "remote change")
+	update(true)
+	checkUpdated(p)
+	checkContent(readme, "local change")
+	checkContent(untracked, "untracked")
+	scm := gitutil.New(fake.X, gitutil.RootDirOpt(p.Path))
+	if revs, err := scm.StashList(); err != nil || len(revs) != 0 {
+		t.Errorf("expected the stash to be dropped, got %v, %v", revs, err)
+	}
+
+	// Conflicting changes are kept in the stash.
+	failures := fake.X.Failures()
+	writeReadme(t, fake.X, fake.Projects[p.Name], "remote change")
+	update(true)
+	checkUpdated(p)
+	checkContent(readme, "remote change")
+	checkContent(untracked, "")
+	if fake.X.Failures() == failures {
+		t.Errorf("expected the conflict to be reported as a failure")
+	}
+	if revs, err := scm.StashList(); err != nil || len(revs) != 1 {
+		t.Errorf("expected the stash to be kept, got %v, %v", revs, err)
+	}
+
+	// Autostash can be enabled in the local config.
+	p = localProjects[2]
+	if err := project.WriteLocalConfig(fake.X, p, project.LocalConfig{AutoStash: true}); err != nil {
+		t.Fatal(err)
+	}
+	readme = writeUncommitedFile(t, p.Path, "README", "local change")
+	// Staged changes are staged again.
+	scm = gitutil.New(fake.X, gitutil.RootDirOpt(p.Path))
+	if err := scm.Add("README"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fake.X, fake.Projects[p.Name], "file", "remote change")
+	update(false)
+	checkUpdated(p)
+	checkContent(readme, "local change")
+	if staged, err := scm.StagedFiles(); err != nil || !reflect.DeepEqual(staged, []string{"README"}) {
+		t.Errorf("got staged files %v, %v, want [README]", staged, err)
+	}
+}
+
 // TestHookLoadSimple tests that manifest is loaded correctly
 // with correct project path in hook
 func TestHookLoadSimple(t *testing.T) {
@@ -919,6 +1012,87 @@ func TestUpdateUniverseWithRevision(t *testing.T) {
 	}
 }
 
+// setupUniverseWithTrack creates tags v1.0.0, v1.1.0 and v2.0.0 followed by
+// an untagged commit in the remote of project 1, makes project 1 track
+// "tag:v1.*" and updates the universe. It returns the revision of v1.1.0.
+func setupUniverseWithTrack(t *testing.T) ([]project.Project, *jiritest.FakeJiriRoot, string) {
+	localProjects, fake := setupUniverse(t)
+
+	remote := fake.Projects[localProjects[1].Name]
+	g := gitutil.New(fake.X, gitutil.RootDirOpt(remote))
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v2.0.0"} {
+		writeReadme(t, fake.X, remote, "readme "+tag)
+		if err := g.CreateLightweightTag(tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeReadme(t, fake.X, remote, "untagged")
+	want, err := g.CurrentRevisionForRef("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := fake.ReadRemoteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects := []project.Project{}
+	for _, p := range m.Projects {
+		if p.Name == localProjects[1].Name {
+			p.Track = "tag:v1.*"
+		}
+		projects = append(projects, p)
+	}
+	m.Projects = projects
+	if err := fake.WriteRemoteManifest(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.UpdateUniverse(false); err != nil {
+		t.Fatal(err)
+	}
+	return localProjects, fake, want
+}
+
+// TestUpdateUniverseWithTrack checks that projects tracking tags are updated
+// to the newest matching tag.
+func TestUpdateUniverseWithTrack(t *testing.T) {
+	t.Parallel()
+
+	localProjects, _, _ := setupUniverseWithTrack(t)
+	checkReadme(t, localProjects[1], "readme v1.1.0")
+	checkReadme(t, localProjects[2], "initial readme")
+}
+
+// TestResolveWithTrack checks that resolving locks projects tracking tags to
+// the newest matching tag and records the tag in the lock.
+func TestResolveWithTrack(t *testing.T) {
+	t.Parallel()
+
+	localProjects, fake, want := setupUniverseWithTrack(t)
+
+	fake.X.LockfileVersion = project.LatestLockFileVersion
+	lockPath := filepath.Join(t.TempDir(), "jiri.lock")
+	config := fakeResolveConfig{lockFilePath: lockPath, projectLock: true}
+	if err := project.GenerateJiriLockFile(fake.X, []string{fake.X.JiriManifestFile()}, config); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectLocks, _, err := project.UnmarshalLockEntries(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, ok := projectLocks[project.ProjectLockKey(localProjects[1].Key())]
+	if !ok {
+		t.Fatalf("project %q was not locked: %v", localProjects[1].Name, projectLocks)
+	}
+	if lock.Revision != want || lock.Ref != "refs/tags/v1.1.0" {
+		t.Errorf("got lock at %q (%s), want %q (%s)", lock.Ref, lock.Revision, "refs/tags/v1.1.0", want)
+	}
+}
+
 // TestUpdateUniverseWithBadRevision checks that UpdateUniverse
 // will not leave bad state behind.
 //func TestUpdateUniverseWithBadRevision(t *testing.T) {
@@ -2282,7 +2456,7 @@ func TestProjectToFromFile(t *testing.T) {
 func TestMarshalAndUnmarshalLockEntries(t *testing.T) {
 	t.Parallel()
 
-	projectLock0 := project.ProjectLock{"https://dart.googlesource.com/web_socket_channel.git", "dart", "1.0.9"}
+	projectLock0 := project.ProjectLock{Remote: "https://dart.googlesource.com/web_socket_channel.git", Name: "dart", Revision: "1.0.9"}
 	pkgLock0 := project.PackageLock{
 		PackageName: "fuchsia/go/mac-amd64",
 		VersionTag:  "git_revision:b8bd7d94a2ae6c80ab8b6ed5900d3eeba8a777c3",
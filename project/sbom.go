@@ -0,0 +1,536 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/version"
+)
+
+const (
+	SBOMFormatSPDX      = "spdx-json"
+	SBOMFormatCycloneDX = "cyclonedx-json"
+
+	sbomNoAssertion = "NOASSERTION"
+)
+
+// SBOMComponent is a project or a package instance recorded in an SBOM.
+type SBOMComponent struct {
+	// Kind is "git" for projects and the source of the package for
+	// packages.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Version is the revision of a project or the instance id of a
+	// package.
+	Version string `json:"version"`
+	// VersionTag is the version of a package in the manifest.
+	VersionTag string `json:"version_tag,omitempty"`
+	// Path is the directory of the component relative to the jiri root,
+	// with forward slashes.
+	Path     string `json:"path"`
+	Supplier string `json:"supplier,omitempty"`
+	License  string `json:"license,omitempty"`
+	PURL     string `json:"purl"`
+	// Remote is the remote repository of a project.
+	Remote string `json:"remote,omitempty"`
+	// DownloadURL is where the component can be fetched from, in the
+	// format of SPDX download locations.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// SBOM is a software bill of materials describing the projects and packages
+// of a jiri checkout.
+type SBOM struct {
+	Name       string
+	Components []SBOMComponent
+}
+
+// NewCheckoutSBOM returns the SBOM of the projects checked out under the jiri
+// root and the packages installed for the current platform and the platforms
+// in jirix.FetchPlatforms. Licenses are read from the manifest.
+func NewCheckoutSBOM(jirix *jiri.X, name string) (*SBOM, error) {
+	localProjects, err := LocalProjects(jirix, FullScan)
+	if err != nil {
+		return nil, err
+	}
+	remoteProjects, _, pkgs, err := LoadManifestFile(jirix, jirix.JiriManifestFile(), localProjects, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := FilterOptionalProjectsPackages(jirix, jirix.FetchingAttrs, nil, pkgs); err != nil {
+		return nil, err
+	}
+	sbom := &SBOM{Name: name}
+	for k, p := range localProjects {
+		if r, ok := remoteProjects[k]; ok && r.License != "" {
+			p.License = r.License
+		}
+		if err := sbom.addProject(jirix, p); err != nil {
+			return nil, err
+		}
+	}
+	instances, err := installedInstances(jirix, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, ins := range instances {
+		sbom.addPackageInstance(ins)
+	}
+	sbom.sort()
+	return sbom, nil
+}
+
+// NewSnapshotSBOM returns the SBOM of the projects and the package instances
+// recorded in snapshot file. It does not depend on the jiri root nor on the
+// current platform.
+func NewSnapshotSBOM(jirix *jiri.X, name, file string) (*SBOM, error) {
+	m, err := ManifestFromFile(jirix, file)
+	if err != nil {
+		return nil, err
+	}
+	sbom := &SBOM{Name: name}
+	for _, p := range m.Projects {
+		if err := sbom.addProject(jirix, p); err != nil {
+			return nil, err
+		}
+	}
+	for _, pkg := range m.Packages {
+		if err := pkg.FillDefaults(); err != nil {
+			return nil, err
+		}
+		plats, err := pkg.GetPlatforms()
+		if err != nil {
+			return nil, err
+		}
+		targets, err := pkg.expandTargets(plats)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, t := range targets {
+			id := pkg.knownInstance(t.name)
+			if id == "" || seen[t.name] {
+				continue
+			}
+			seen[t.name] = true
+			subdir, err := pkg.expandedPathFor(t.plat)
+			if err != nil {
+				return nil, err
+			}
+			sbom.addPackageInstance(installedInstance{pkg, fetchTarget{t, subdir}, id})
+		}
+	}
+	sbom.sort()
+	return sbom, nil
+}
+
+func (s *SBOM) addProject(jirix *jiri.X, p Project) error {
+	if err := p.relativizePaths(jirix.Root); err != nil {
+		return err
+	}
+	remote := rewriteRemote(jirix, p.Remote)
+	s.Components = append(s.Components, SBOMComponent{
+		Kind:        "git",
+		Name:        p.Name,
+		Version:     p.Revision,
+		Path:        filepath.ToSlash(p.Path),
+		Supplier:    sbomSupplier(remote),
+		License:     p.License,
+		PURL:        vcsPURL(p.Name, remote, p.Revision),
+		Remote:      remote,
+		DownloadURL: "git+" + remote + "@" + p.Revision,
+	})
+	return nil
+}
+
+func (s *SBOM) addPackageInstance(ins installedInstance) {
+	c := SBOMComponent{
+		Kind:       ins.pkg.GetSource(),
+		Name:       ins.name,
+		Version:    ins.id,
+		VersionTag: ins.pkg.Version,
+		Path:       filepath.ToSlash(ins.subdir),
+		License:    ins.pkg.License,
+	}
+	qualifiers := map[string]string{}
+	switch c.Kind {
+	case PackageSourceCIPD:
+		c.DownloadURL = cipd.ServiceURL + "/p/" + ins.name + "/+/" + ins.id
+		c.Supplier = sbomSupplier(cipd.ServiceURL)
+	default:
+		c.DownloadURL = ins.url
+		c.Supplier = sbomSupplier(ins.url)
+		if strings.HasPrefix(ins.id, "sha256:") {
+			qualifiers["checksum"] = ins.id
+		}
+	}
+	qualifiers["download_url"] = c.DownloadURL
+	c.PURL = purl("generic", ins.name, ins.id, qualifiers)
+	s.Components = append(s.Components, c)
+}
+
+func (s *SBOM) sort() {
+	sort.Slice(s.Components, func(i, j int) bool {
+		a, b := s.Components[i], s.Components[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Version < b.Version
+	})
+}
+
+// sbomSupplier returns the supplier of the component at rawURL derived from
+// its hostname: the owner of repositories on code hosting sites, the host
+// name of googlesource.com hosts and the hostname otherwise.
+func sbomSupplier(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := u.Hostname()
+	if _, ok := purlTypes[host]; ok {
+		if owner, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/"); owner != "" {
+			return owner
+		}
+	}
+	if name, ok := strings.CutSuffix(host, ".googlesource.com"); ok {
+		return strings.TrimSuffix(name, "-review")
+	}
+	return host
+}
+
+// purlTypes maps code hosting sites to their package url types.
+var purlTypes = map[string]string{
+	"github.com":    "github",
+	"gitlab.com":    "gitlab",
+	"bitbucket.org": "bitbucket",
+}
+
+// vcsPURL returns the package url of revision of the repository at remote.
+// Repositories on code hosting sites with their own package url type use it,
+// other ones use a generic package url with a vcs_url qualifier.
+func vcsPURL(name, remote, revision string) string {
+	if u, err := url.Parse(remote); err == nil {
+		if typ, ok := purlTypes[u.Hostname()]; ok {
+			repo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+			if strings.Count(repo, "/") == 1 {
+				return purl(typ, strings.ToLower(repo), revision, nil)
+			}
+		}
+	}
+	return purl("generic", name, revision, map[string]string{"vcs_url": "git+" + remote + "@" + revision})
+}
+
+// purl returns the package url of the given type, name and version. The
+// segments of name are escaped separately and qualifiers are sorted.
+func purl(typ, name, version string, qualifiers map[string]string) string {
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(name, "/"), "/") {
+		segments = append(segments, url.PathEscape(s))
+	}
+	ret := "pkg:" + typ + "/" + strings.Join(segments, "/")
+	if version != "" {
+		ret += "@" + url.PathEscape(version)
+	}
+	var keys []string
+	for k := range qualifiers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		sep := "&"
+		if i == 0 {
+			sep = "?"
+		}
+		ret += sep + k + "=" + strings.ReplaceAll(url.PathEscape(qualifiers[k]), "%2F", "/")
+	}
+	return ret
+}
+
+// digest returns a hash of the components of the SBOM which is used to
+// derive its unique identifiers.
+func (s *SBOM) digest() ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Name       string
+		Components []SBOMComponent
+	}{s.Name, s.Components})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// Marshal returns the SBOM in format, which is either SBOMFormatSPDX or
+// SBOMFormatCycloneDX, recording created as its creation time. The output
+// only depends on the SBOM and created.
+func (s *SBOM) Marshal(format string, created time.Time) ([]byte, error) {
+	digest, err := s.digest()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := created.UTC().Format(time.RFC3339)
+	var doc any
+	switch format {
+	case SBOMFormatSPDX:
+		doc = s.spdx(digest, timestamp)
+	case SBOMFormatCycloneDX:
+		doc = s.cycloneDX(digest, timestamp)
+	default:
+		return nil, fmt.Errorf("unsupported sbom format %q, expecting %q or %q", format, SBOMFormatSPDX, SBOMFormatCycloneDX)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize sbom: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	Supplier         string            `json:"supplier"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+var spdxIDInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// uniqueIDs returns a function which returns the ids passed to it, with a
+// numeric suffix added to the ones it has already returned.
+func uniqueIDs() func(string) string {
+	seen := make(map[string]int)
+	return func(id string) string {
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			return fmt.Sprintf("%s-%d", id, n)
+		}
+		return id
+	}
+}
+
+// toolName returns the name of jiri including its version, if known.
+func toolName() string {
+	if version.GitCommit != "" {
+		return "jiri-" + version.GitCommit
+	}
+	return "jiri"
+}
+
+func (s *SBOM) spdx(digest []byte, timestamp string) spdxDocument {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + url.PathEscape(s.Name) + "-" + hex.EncodeToString(digest),
+		CreationInfo: spdxCreationInfo{
+			Created:  timestamp,
+			Creators: []string{"Tool: " + toolName()},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	orNoAssertion := func(s string) string {
+		if s == "" {
+			return sbomNoAssertion
+		}
+		return s
+	}
+	unique := uniqueIDs()
+	for _, c := range s.Components {
+		id := unique("SPDXRef-" + strings.Trim(spdxIDInvalidChars.ReplaceAllString(c.Kind+"-"+c.Name, "-"), "-"))
+		supplier := sbomNoAssertion
+		if c.Supplier != "" {
+			supplier = "Organization: " + c.Supplier
+		}
+		comment := "path: " + c.Path
+		if c.VersionTag != "" {
+			comment += ", version: " + c.VersionTag
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             c.Name,
+			SPDXID:           id,
+			VersionInfo:      c.Version,
+			Supplier:         supplier,
+			DownloadLocation: orNoAssertion(c.DownloadURL),
+			LicenseConcluded: sbomNoAssertion,
+			LicenseDeclared:  orNoAssertion(c.License),
+			CopyrightText:    sbomNoAssertion,
+			Comment:          comment,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.PURL,
+			}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      doc.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: id,
+		})
+	}
+	return doc
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXSupplier struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDXComponent struct {
+	Type               string                       `json:"type"`
+	BOMRef             string                       `json:"bom-ref,omitempty"`
+	Supplier           *cycloneDXSupplier           `json:"supplier,omitempty"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	Licenses           []cycloneDXLicense           `json:"licenses,omitempty"`
+	PURL               string                       `json:"purl,omitempty"`
+	ExternalReferences []cycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+func (s *SBOM) cycloneDX(digest []byte, timestamp string) cycloneDXDocument {
+	// Derive a version 5 style UUID from the digest.
+	u := append([]byte(nil), digest[:16]...)
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	uuid := fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+	tool := cycloneDXComponent{Type: "application", Name: "jiri", Version: version.GitCommit}
+	root := cycloneDXComponent{Type: "application", BOMRef: s.Name, Name: s.Name}
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid,
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: timestamp,
+			Tools:     cycloneDXTools{Components: []cycloneDXComponent{tool}},
+			Component: root,
+		},
+		Components: []cycloneDXComponent{},
+	}
+	dependency := cycloneDXDependency{Ref: root.BOMRef, DependsOn: []string{}}
+	unique := uniqueIDs()
+	for _, c := range s.Components {
+		component := cycloneDXComponent{
+			Type:       "library",
+			BOMRef:     unique(c.PURL),
+			Name:       path.Base(c.Name),
+			Version:    c.Version,
+			PURL:       c.PURL,
+			Properties: []cycloneDXProperty{{Name: "jiri:name", Value: c.Name}, {Name: "jiri:path", Value: c.Path}},
+		}
+		if c.Supplier != "" {
+			component.Supplier = &cycloneDXSupplier{Name: c.Supplier}
+		}
+		if c.License != "" {
+			component.Licenses = []cycloneDXLicense{{Expression: c.License}}
+		}
+		if c.Remote != "" {
+			component.ExternalReferences = []cycloneDXExternalReference{{Type: "vcs", URL: c.Remote}}
+		} else if c.DownloadURL != "" {
+			component.ExternalReferences = []cycloneDXExternalReference{{Type: "distribution", URL: c.DownloadURL}}
+		}
+		if c.VersionTag != "" {
+			component.Properties = append(component.Properties, cycloneDXProperty{Name: "jiri:version", Value: c.VersionTag})
+		}
+		doc.Components = append(doc.Components, component)
+		dependency.DependsOn = append(dependency.DependsOn, component.BOMRef)
+	}
+	doc.Dependencies = []cycloneDXDependency{dependency}
+	return doc
+}
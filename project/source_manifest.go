@@ -14,6 +14,7 @@ import (
 	"sync"
 
 	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
 	"go.fuchsia.dev/jiri/gerrit"
 	"go.fuchsia.dev/jiri/gitutil"
 )
@@ -56,8 +57,27 @@ type SourceManifest_GitCheckout struct {
 	FetchRef string `json:"fetch_ref,omitempty"`
 }
 
+type SourceManifest_CIPDPackage struct {
+	// The package pattern that was given to the CIPD client (if known). Ex.
+	//   fuchsia/third_party/clang/${platform}
+	PackagePattern string `json:"package_pattern,omitempty"`
+
+	// The version that was given to the CIPD client (if known). Ex.
+	//   git_revision:4d2b7b1ba0ddb79be0ae8c5d6e0c5e7cbbd2d0e1
+	Version string `json:"version,omitempty"`
+
+	// The fully resolved instance ID of the deployed package.
+	InstanceId string `json:"instance_id,omitempty"`
+}
+
 type SourceManifest_Directory struct {
 	GitCheckout *SourceManifest_GitCheckout `json:"git_checkout,omitempty"`
+
+	// The canonicalized URL of the CIPD server which provided the packages.
+	CipdServerHost string `json:"cipd_server_host,omitempty"`
+
+	// A map from CIPD package name -> CIPDPackage.
+	CipdPackage map[string]*SourceManifest_CIPDPackage `json:"cipd_package,omitempty"`
 }
 
 type SourceManifest struct {
@@ -181,6 +201,85 @@ func NewSourceManifest(jirix *jiri.X, projects Projects) (*SourceManifest, error
 	return sm, errFromChannel(errs)
 }
 
+// installedInstance is an instance of a package installed for a platform.
+type installedInstance struct {
+	pkg Package
+	fetchTarget
+	id string
+}
+
+// installedInstances returns the instances of pkgs which are installed for
+// the current platform and the platforms in jirix.FetchPlatforms. Instance
+// ids are taken from the locks enforced on the packages, packages without
+// them are resolved.
+func installedInstances(jirix *jiri.X, pkgs Packages) ([]installedInstance, error) {
+	plats, err := fetchPlatforms(jirix)
+	if err != nil {
+		return nil, err
+	}
+	var ret []installedInstance
+	unlocked := make(Packages)
+	for _, pkg := range pkgs {
+		targets, err := pkg.fetchTargets(plats)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			id := pkg.knownInstance(t.name)
+			if id == "" {
+				unlocked[pkg.Key()] = pkg
+			}
+			ret = append(ret, installedInstance{pkg, t, id})
+		}
+	}
+	if len(unlocked) == 0 {
+		return ret, nil
+	}
+	locks, err := resolvePackageLocks(jirix, unlocked)
+	if err != nil {
+		return nil, err
+	}
+	for i, ins := range ret {
+		if ins.id != "" {
+			continue
+		}
+		for _, lock := range locks {
+			if lock.PackageName == ins.name && lock.VersionTag == ins.pkg.Version {
+				ret[i].id = lock.InstanceID
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// AddPackages adds the cipd packages in pkgs to the source manifest, under
+// the directories they are installed to.
+func (sm *SourceManifest) AddPackages(jirix *jiri.X, pkgs Packages) error {
+	instances, err := installedInstances(jirix, pkgs.filterSource(PackageSourceCIPD))
+	if err != nil {
+		return err
+	}
+	for _, ins := range instances {
+		dirPath := filepath.ToSlash(ins.subdir)
+		dir, ok := sm.Directories[dirPath]
+		if !ok {
+			dir = &SourceManifest_Directory{}
+			sm.Directories[dirPath] = dir
+		}
+		if dir.CipdPackage == nil {
+			dir.CipdPackage = make(map[string]*SourceManifest_CIPDPackage)
+		}
+		dir.CipdServerHost = cipd.ServiceURL
+		dir.CipdPackage[ins.name] = &SourceManifest_CIPDPackage{
+			PackagePattern: ins.pkg.Name,
+			Version:        ins.pkg.Version,
+			InstanceId:     ins.id,
+		}
+	}
+	return nil
+}
+
 func (sm *SourceManifest) ToFile(jirix *jiri.X, filename string) error {
 	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
 		return fmtError(err)
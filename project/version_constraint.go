@@ -0,0 +1,564 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cipd"
+	"go.fuchsia.dev/jiri/gitutil"
+)
+
+const (
+	tagPrefix    = "tag:"
+	semverPrefix = "semver:"
+
+	// semverTagKey is the key of the cipd tags that "semver:" package
+	// versions are matched against.
+	semverTagKey = "version"
+
+	// maxConstraintInstances is the number of the most recent instances of a
+	// package whose tags are considered when resolving a version constraint.
+	maxConstraintInstances = 50
+
+	// instanceTagsCacheFile is the file, relative to the root metadata
+	// directory, caching the tags of the cipd instances described when
+	// resolving version constraints.
+	instanceTagsCacheFile = "cipd_instance_tags.json"
+)
+
+// semver is a semantic version. Missing minor and patch numbers are zero.
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+// parseSemver parses versions such as "1.2.3", "v1.2" or "1.2.3-rc.1". Build
+// metadata is ignored. It also returns the number of version numbers that
+// were present.
+func parseSemver(s string) (semver, int, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	var v semver
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.pre = s[i+1:]
+		s = s[:i]
+		if v.pre == "" {
+			return semver{}, 0, false
+		}
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, 0, false
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, 0, false
+		}
+		*nums[i] = n
+	}
+	return v, len(parts), true
+}
+
+func (v semver) compare(other semver) int {
+	for _, d := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.pre == other.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case other.pre == "":
+		return -1
+	}
+	return comparePrerelease(v.pre, other.pre)
+}
+
+// comparePrerelease compares prerelease versions identifier by identifier.
+// Numeric identifiers are compared as numbers and rank below alphanumeric
+// ones, and a longer list of identifiers ranks above its prefix.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] != bs[i]:
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// semverBound is a comparison of a version with v.
+type semverBound struct {
+	op string
+	v  semver
+}
+
+func (b semverBound) match(v semver) bool {
+	c := v.compare(b.v)
+	switch b.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return c == 0
+}
+
+// semverConstraint is a set of bounds that all have to match.
+type semverConstraint struct {
+	bounds []semverBound
+	// pre is set if prerelease versions may match.
+	pre bool
+}
+
+// parseSemverConstraint parses constraints made of terms separated by
+// spaces or commas, all of which have to match. Terms are "^1.2", "~1.2",
+// "1.2.x", "1.2.*", "*", comparisons such as ">=1.2.3" or "<2", and
+// versions, where "1.2" matches any "1.2.x".
+func parseSemverConstraint(s string) (semverConstraint, error) {
+	var c semverConstraint
+	terms := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(terms) == 0 {
+		return c, fmt.Errorf("empty version constraint")
+	}
+	for _, term := range terms {
+		if strings.Contains(term, "-") {
+			c.pre = true
+		}
+		op := ""
+		for _, o := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(term, o) {
+				op = o
+				term = term[len(o):]
+				break
+			}
+		}
+		// Turn wildcards into missing version numbers.
+		var parts []string
+		for _, part := range strings.Split(strings.TrimPrefix(term, "v"), ".") {
+			if part == "x" || part == "X" || part == "*" {
+				break
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 {
+			if op != "" {
+				return c, fmt.Errorf("invalid version constraint %q", s)
+			}
+			// "*" matches any version.
+			continue
+		}
+		v, n, ok := parseSemver(strings.Join(parts, "."))
+		if !ok {
+			return c, fmt.Errorf("invalid version constraint %q", s)
+		}
+		// next returns the smallest version above the versions sharing the
+		// first i version numbers of v.
+		next := func(i int) semver {
+			switch i {
+			case 1:
+				return semver{major: v.major + 1}
+			case 2:
+				return semver{major: v.major, minor: v.minor + 1}
+			}
+			return semver{major: v.major, minor: v.minor, patch: v.patch + 1}
+		}
+		switch op {
+		case ">=", "<=", ">", "<":
+			c.bounds = append(c.bounds, semverBound{op, v})
+		case "^":
+			i := 1
+			if v.major == 0 {
+				i = 2
+				if v.minor == 0 && n == 3 {
+					i = 3
+				}
+			}
+			if n < i {
+				i = n
+			}
+			c.bounds = append(c.bounds, semverBound{">=", v}, semverBound{"<", next(i)})
+		case "~":
+			i := 2
+			if n < i {
+				i = n
+			}
+			c.bounds = append(c.bounds, semverBound{">=", v}, semverBound{"<", next(i)})
+		default:
+			if n == 3 {
+				c.bounds = append(c.bounds, semverBound{"=", v})
+			} else {
+				c.bounds = append(c.bounds, semverBound{">=", v}, semverBound{"<", next(n)})
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c semverConstraint) match(v semver) bool {
+	if v.pre != "" && !c.pre {
+		return false
+	}
+	for _, b := range c.bounds {
+		if !b.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// trackConstraint selects a git tag, as set by the "track" attribute of a
+// project.
+type trackConstraint struct {
+	pattern string
+	semver  *semverConstraint
+}
+
+func parseTrack(s string) (trackConstraint, error) {
+	switch {
+	case strings.HasPrefix(s, tagPrefix):
+		pattern := strings.TrimPrefix(s, tagPrefix)
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return trackConstraint{}, fmt.Errorf("invalid tag pattern in track %q", s)
+		}
+		return trackConstraint{pattern: pattern}, nil
+	case strings.HasPrefix(s, semverPrefix):
+		c, err := parseSemverConstraint(strings.TrimPrefix(s, semverPrefix))
+		if err != nil {
+			return trackConstraint{}, err
+		}
+		return trackConstraint{semver: &c}, nil
+	}
+	return trackConstraint{}, fmt.Errorf("invalid track %q, expecting \"tag:<pattern>\" or \"semver:<constraint>\"", s)
+}
+
+// selectTag returns the tag among tags that matches the constraint and has
+// the highest version. Releases rank above prereleases, and tags which are not
+// semantic versions rank below the ones that are, in reverse lexicographic
+// order.
+func (c trackConstraint) selectTag(tags []string) (string, bool) {
+	var matching []string
+	for _, tag := range tags {
+		if c.semver != nil {
+			if v, _, ok := parseSemver(tag); !ok || !c.semver.match(v) {
+				continue
+			}
+		} else if ok, _ := path.Match(c.pattern, tag); !ok {
+			continue
+		}
+		matching = append(matching, tag)
+	}
+	if len(matching) == 0 {
+		return "", false
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		vi, _, oki := parseSemver(matching[i])
+		vj, _, okj := parseSemver(matching[j])
+		switch {
+		case oki && okj:
+			if (vi.pre == "") != (vj.pre == "") {
+				return vi.pre == ""
+			}
+			if c := vi.compare(vj); c != 0 {
+				return c > 0
+			}
+		case oki != okj:
+			return oki
+		}
+		return matching[i] > matching[j]
+	})
+	return matching[0], true
+}
+
+// isVersionConstraint returns true if the version of a package is a
+// constraint rather than a cipd version.
+func isVersionConstraint(version string) bool {
+	return strings.HasPrefix(version, semverPrefix)
+}
+
+// selectVersionTag returns the "version:" tag among tags with the highest
+// semantic version matching constraint.
+func selectVersionTag(tags []string, constraint string) (string, bool) {
+	c, err := parseSemverConstraint(strings.TrimPrefix(constraint, semverPrefix))
+	if err != nil {
+		return "", false
+	}
+	var values []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, semverTagKey+":") {
+			values = append(values, strings.TrimPrefix(tag, semverTagKey+":"))
+		}
+	}
+	value, ok := trackConstraint{semver: &c}.selectTag(values)
+	if !ok {
+		return "", false
+	}
+	return semverTagKey + ":" + value, true
+}
+
+// resolveTrack returns the revision and the tag the track constraint of
+// Project p selects.
+func resolveTrack(jirix *jiri.X, p Project) (string, string, error) {
+	c, err := parseTrack(p.Track)
+	if err != nil {
+		return "", "", err
+	}
+	refs, err := gitutil.New(jirix).LsRemoteTags(p.Remote)
+	if err != nil {
+		return "", "", err
+	}
+	tags := make([]string, 0, len(refs))
+	for tag := range refs {
+		tags = append(tags, tag)
+	}
+	tag, ok := c.selectTag(tags)
+	if !ok {
+		return "", "", fmt.Errorf("no tag of project %q matches %q", p.Name, p.Track)
+	}
+	return refs[tag], "refs/tags/" + tag, nil
+}
+
+// instanceTagsCache caches the tags of cipd instances, keyed by instance id.
+// Describing an instance is expensive, so that resolving the version
+// constraints on each update only describes the instances registered since
+// the previous resolution.
+type instanceTagsCache struct {
+	tags map[string][]string
+	used map[string]bool
+}
+
+// loadInstanceTagsCache reads the tags cached in the root metadata directory.
+// A missing or invalid cache is treated as empty.
+func loadInstanceTagsCache(jirix *jiri.X) *instanceTagsCache {
+	c := &instanceTagsCache{tags: make(map[string][]string), used: make(map[string]bool)}
+	if data, err := os.ReadFile(filepath.Join(jirix.RootMetaDir(), instanceTagsCacheFile)); err == nil {
+		if err := json.Unmarshal(data, &c.tags); err != nil {
+			c.tags = make(map[string][]string)
+		}
+	}
+	return c
+}
+
+// instanceTags returns the tags of instance id of package pkg.
+func (c *instanceTagsCache) instanceTags(jirix *jiri.X, pkg, id string) ([]string, error) {
+	c.used[id] = true
+	if tags, ok := c.tags[id]; ok {
+		return tags, nil
+	}
+	desc, err := cipd.Describe(jirix, pkg, id)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, t := range desc.Tags {
+		tags = append(tags, t.Tag)
+	}
+	c.tags[id] = tags
+	return tags, nil
+}
+
+// save writes the tags of the instances used since the cache was loaded.
+func (c *instanceTagsCache) save(jirix *jiri.X) error {
+	tags := make(map[string][]string)
+	for id := range c.used {
+		tags[id] = c.tags[id]
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	return SafeWriteFile(jirix, filepath.Join(jirix.RootMetaDir(), instanceTagsCacheFile), data)
+}
+
+// resolveVersionConstraint returns the version tag the version constraint of
+// Package pkg selects, using the tags of the instances for the current
+// platform, or the first platform pkg supports.
+func resolveVersionConstraint(jirix *jiri.X, pkg Package, cache *instanceTagsCache) (string, error) {
+	targets, err := pkg.lockTargets()
+	if err != nil {
+		return "", err
+	}
+	if len(targets) == 0 {
+		return "", fmt.Errorf("package %q does not support any platform", pkg.Name)
+	}
+	name := targets[0].name
+	for _, t := range targets {
+		if t.platform == cipd.CipdPlatform.String() {
+			name = t.name
+		}
+	}
+	ids, err := cipd.ListInstances(jirix, name, maxConstraintInstances)
+	if err != nil {
+		return "", err
+	}
+	var tags []string
+	for _, id := range ids {
+		instanceTags, err := cache.instanceTags(jirix, name, id)
+		if err != nil {
+			return "", err
+		}
+		tags = append(tags, instanceTags...)
+	}
+	tag, ok := selectVersionTag(tags, pkg.Version)
+	if !ok {
+		return "", fmt.Errorf("none of the %d newest instances of package %q has a tag matching %q", maxConstraintInstances, name, pkg.Version)
+	}
+	return tag, nil
+}
+
+// resolveTrackedProjects sets the revision of the projects with a track
+// constraint whose revision is not set, or is the one recorded in locks, to
+// the revision the constraint selects. It returns the tags that were
+// selected, keyed by project.
+func resolveTrackedProjects(jirix *jiri.X, projects Projects, locks ProjectLocks) (map[ProjectKey]string, error) {
+	refs := make(map[ProjectKey]string)
+	for k, v := range projects {
+		if v.Track == "" {
+			continue
+		}
+		if v.Revision != "" && v.Revision != "HEAD" {
+			if lock, ok := locks[ProjectLockKey(k)]; !ok || lock.Revision != v.Revision {
+				continue
+			}
+		}
+		rev, ref, err := resolveTrack(jirix, v)
+		if err != nil {
+			return nil, err
+		}
+		jirix.Logger.Infof("project %q tracks %q, which resolved to %q (%s)\n", v.Name, v.Track, ref, rev)
+		v.Revision = rev
+		projects[k] = v
+		refs[k] = ref
+	}
+	return refs, nil
+}
+
+// resolveConstrainedPackages returns the version tags the version
+// constraints of pkgs select, keyed by package.
+func resolveConstrainedPackages(jirix *jiri.X, pkgs Packages) (map[PackageKey]string, error) {
+	tags := make(map[PackageKey]string)
+	cache := loadInstanceTagsCache(jirix)
+	for k, v := range pkgs {
+		if !isVersionConstraint(v.Version) {
+			continue
+		}
+		tag, err := resolveVersionConstraint(jirix, v, cache)
+		if err != nil {
+			return nil, err
+		}
+		jirix.Logger.Infof("package %q uses version constraint %q, which resolved to %q\n", v.Name, v.Version, tag)
+		tags[k] = tag
+	}
+	if len(cache.used) != 0 {
+		if err := cache.save(jirix); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// resolveUnlockedConstraints resolves the track and version constraints of
+// the projects and packages that are not locked, so that they can be
+// updated without a lockfile.
+func resolveUnlockedConstraints(jirix *jiri.X, projects Projects, pkgs Packages) error {
+	if _, err := resolveTrackedProjects(jirix, projects, nil); err != nil {
+		return err
+	}
+	unlocked := make(Packages)
+	for k, v := range pkgs {
+		if len(v.Instances) == 0 {
+			unlocked[k] = v
+		}
+	}
+	tags, err := resolveConstrainedPackages(jirix, unlocked)
+	if err != nil {
+		return err
+	}
+	for k, tag := range tags {
+		v := pkgs[k]
+		v.Version = tag
+		pkgs[k] = v
+	}
+	return nil
+}
+
+// lockConstraints rewrites the locks of the packages in pkgs whose versions
+// are constraints, which were resolved using the version tags in tags, so
+// that they are keyed by the constraint and record the tag as their ref.
+func lockConstraints(pkgs Packages, tags map[PackageKey]string, pkgLocks PackageLocks) (PackageLocks, error) {
+	var resolved []PackageLock
+	for k, tag := range tags {
+		v := pkgs[k]
+		targets, err := v.lockTargets()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			lock, ok := pkgLocks.find(t.name, tag, v.Path, t.platform)
+			if !ok {
+				continue
+			}
+			lock.VersionTag = v.Version
+			lock.Ref = tag
+			resolved = append(resolved, lock)
+		}
+	}
+	ret := make(PackageLocks)
+	for k, v := range pkgLocks {
+		ret[k] = v
+	}
+	for _, lock := range resolved {
+		delete(ret, PackageLockKey{lock.PackageName, lock.Ref, lock.LocalPath, lock.Platform})
+		delete(ret, PackageLockKey{lock.PackageName, lock.Ref, "", ""})
+	}
+	for _, lock := range resolved {
+		ret[lock.Key()] = lock
+	}
+	return ret, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	"go.fuchsia.dev/jiri/jiritest/xtest"
+)
+
+func TestSemverConstraint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"^2.3", []string{"2.3.0", "v2.9.1", "2.3"}, []string{"2.2.9", "3.0.0", "2.4.0-rc.1"}},
+		{"^0.3", []string{"0.3.0", "0.3.9"}, []string{"0.4.0", "0.2.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2", []string{"1.2.0", "1.2.7"}, []string{"1.3.0", "1.1.9"}},
+		{"1.2.x", []string{"1.2.0", "1.2.99"}, []string{"1.3.0"}},
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}, []string{"1.2.4"}},
+		{">=1.2, <2", []string{"1.2.0", "1.99.0"}, []string{"1.1.0", "2.0.0"}},
+		{">=2.0.0-0", []string{"2.0.0-rc.1", "2.1.0"}, []string{"1.9.0"}},
+		{">1.0.0-rc.9", []string{"1.0.0-rc.10", "1.0.0-rc.9.1", "1.0.0-rc.a", "1.0.0"}, []string{"1.0.0-rc.9", "1.0.0-rc.2", "1.0.0-rc"}},
+		{"*", []string{"0.0.1", "10.0.0"}, []string{"1.0.0-beta"}},
+	}
+	for _, test := range tests {
+		c, err := parseSemverConstraint(test.constraint)
+		if err != nil {
+			t.Errorf("parsing %q failed: %v", test.constraint, err)
+			continue
+		}
+		for _, s := range test.match {
+			if v, _, ok := parseSemver(s); !ok || !c.match(v) {
+				t.Errorf("expected %q to match %q", s, test.constraint)
+			}
+		}
+		for _, s := range test.noMatch {
+			if v, _, ok := parseSemver(s); ok && c.match(v) {
+				t.Errorf("expected %q not to match %q", s, test.constraint)
+			}
+		}
+	}
+	for _, s := range []string{"", "^", "^a.b", ">=1.2.3.4"} {
+		if _, err := parseSemverConstraint(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestSelectTag(t *testing.T) {
+	t.Parallel()
+
+	tags := []string{"v1.0.0", "v1.10.0", "v1.9.0", "v2.0.0", "v1.11.0-rc.1", "v3.0.0-rc.9", "v3.0.0-rc.10", "release-a", "release-b"}
+	tests := []struct {
+		track string
+		want  string
+	}{
+		{"tag:v1.*", "v1.10.0"},
+		{"tag:v2*", "v2.0.0"},
+		{"tag:release-*", "release-b"},
+		{"semver:~1.9", "v1.9.0"},
+		{"semver:>=1", "v2.0.0"},
+		{"tag:v3.0.0-rc.*", "v3.0.0-rc.10"},
+		{"semver:>=3.0.0-0", "v3.0.0-rc.10"},
+	}
+	for _, test := range tests {
+		c, err := parseTrack(test.track)
+		if err != nil {
+			t.Fatalf("parsing %q failed: %v", test.track, err)
+		}
+		if got, _ := c.selectTag(tags); got != test.want {
+			t.Errorf("track %q selected %q, want %q", test.track, got, test.want)
+		}
+	}
+	if c, _ := parseTrack("tag:v4.*"); true {
+		if got, ok := c.selectTag(tags); ok {
+			t.Errorf("expected no tag to match, got %q", got)
+		}
+	}
+	if _, err := parseTrack("branch:main"); err == nil {
+		t.Errorf("expected invalid track to be rejected")
+	}
+
+	versionTags := []string{"version:2.3.1", "version:2.4.0", "version:3.0.0", "git_revision:abc"}
+	if got, _ := selectVersionTag(versionTags, "semver:^2.3"); got != "version:2.4.0" {
+		t.Errorf("selected %q, want %q", got, "version:2.4.0")
+	}
+}
+
+func TestLockConstraints(t *testing.T) {
+	t.Parallel()
+
+	pkg := Package{Name: "fuchsia/tool", Version: "semver:^2.3", Path: "prebuilt/tool"}
+	pkgs := Packages{pkg.Key(): pkg}
+	resolved := PackageLock{PackageName: "fuchsia/tool", VersionTag: "version:2.4.0", InstanceID: "id"}
+	locks, err := lockConstraints(pkgs, map[PackageKey]string{pkg.Key(): "version:2.4.0"}, PackageLocks{resolved.Key(): resolved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PackageLock{PackageName: "fuchsia/tool", VersionTag: "semver:^2.3", InstanceID: "id", Ref: "version:2.4.0"}
+	if len(locks) != 1 || locks[want.Key()] != want {
+		t.Errorf("unexpected locks %+v", locks)
+	}
+}
+
+func TestInstanceTagsCache(t *testing.T) {
+	t.Parallel()
+	jirix := xtest.NewX(t)
+
+	cache := loadInstanceTagsCache(jirix)
+	cache.tags["id-1"] = []string{"version:1.0.0"}
+	cache.tags["id-2"] = []string{"version:2.0.0"}
+	// Cached instances are not described again.
+	tags, err := cache.instanceTags(jirix, "test/tool", "id-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"version:2.0.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %v, want %v", tags, want)
+	}
+	if err := cache.save(jirix); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the instances which were used are kept.
+	cache = loadInstanceTagsCache(jirix)
+	if want := map[string][]string{"id-2": {"version:2.0.0"}}; !reflect.DeepEqual(cache.tags, want) {
+		t.Errorf("got cached tags %v, want %v", cache.tags, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reviewhost
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gerrit"
+)
+
+// gerritHost is a Gerrit review host. Pushing for review only needs the git
+// remote of the project, so g is nil for projects without a Gerrit host.
+type gerritHost struct {
+	jirix *jiri.X
+	host  string
+	g     *gerrit.Gerrit
+}
+
+func newGerritHost(jirix *jiri.X, host string) (*gerritHost, error) {
+	h := &gerritHost{jirix: jirix, host: host}
+	if host != "" {
+		u, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Gerrit host %q: %s", host, err)
+		}
+		h.g = gerrit.New(jirix, u)
+	}
+	return h, nil
+}
+
+func (h *gerritHost) gerrit() (*gerrit.Gerrit, error) {
+	if h.g == nil {
+		return nil, fmt.Errorf("no Gerrit host; add a 'gerrithost' attribute to the project")
+	}
+	return h.g, nil
+}
+
+func (h *gerritHost) change(c gerrit.Change) Change {
+	return Change{
+		Number:  c.Number,
+		Project: c.Project,
+		Branch:  c.Branch,
+		Ref:     c.Reference(),
+		Topic:   c.Topic,
+		Status:  c.Status,
+		Subject: c.Subject,
+		URL:     h.g.GetChangeURL(c.Number),
+	}
+}
+
+func (h *gerritHost) changes(cls gerrit.CLList) []Change {
+	var ret []Change
+	for _, cl := range cls {
+		ret = append(ret, h.change(cl))
+	}
+	return ret
+}
+
+// Push pushes to refs/for/<branch> of the remote. Gerrit does not report
+// the changes created by the push.
+func (h *gerritHost) Push(dir string, opts PushOptions) (*Change, error) {
+	return nil, gerrit.Push(h.jirix, dir, opts.CLOpts)
+}
+
+func (h *gerritHost) GetChange(number int) (*Change, error) {
+	g, err := h.gerrit()
+	if err != nil {
+		return nil, err
+	}
+	cl, err := g.GetChange(number)
+	if err != nil {
+		return nil, err
+	}
+	c := h.change(*cl)
+	return &c, nil
+}
+
+func (h *gerritHost) Query(query string) ([]Change, error) {
+	g, err := h.gerrit()
+	if err != nil {
+		return nil, err
+	}
+	cls, err := g.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return h.changes(cls), nil
+}
+
+func (h *gerritHost) TopicChanges(topic string) ([]Change, error) {
+	g, err := h.gerrit()
+	if err != nil {
+		return nil, err
+	}
+	cls, err := g.ListOpenChangesByTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+	return h.changes(cls), nil
+}
+
+// Link sets the topic of the changes of the host.
+func (h *gerritHost) Link(topic string, changes []Change) error {
+	if h.g == nil {
+		return nil
+	}
+	for _, c := range changes {
+		if c.Topic == topic || !strings.HasPrefix(c.URL, h.host+"/") {
+			continue
+		}
+		if err := h.g.SetTopic(strconv.Itoa(c.Number), gerrit.CLOpts{Topic: topic}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
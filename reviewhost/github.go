@@ -0,0 +1,374 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reviewhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/collect"
+	"go.fuchsia.dev/jiri/gitutil"
+)
+
+// linksMarker starts the section of the description of a pull request
+// written by Link. Everything after it is replaced when the links change.
+const linksMarker = "<!-- jiri links -->"
+
+var pullRefRE = regexp.MustCompile(`^refs/pull/(\d+)/head$`)
+
+// GitHub is a GitHub repository whose changes are reviewed as pull requests,
+// through the REST API of GitHub. The GITHUB_TOKEN environment variable is
+// used to authenticate.
+type GitHub struct {
+	jirix *jiri.X
+	api   *url.URL
+	owner string
+	repo  string
+	token string
+}
+
+// NewGitHub returns the GitHub repository of the given remote. apiURL is the
+// URL of the GitHub API, derived from the remote if empty.
+func NewGitHub(jirix *jiri.X, remote, apiURL string) (*GitHub, error) {
+	host, path := splitRemote(remote)
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return nil, fmt.Errorf("cannot find the GitHub repository of remote %q", remote)
+	}
+	if apiURL == "" {
+		switch host {
+		case "":
+			return nil, fmt.Errorf("cannot find the GitHub API of remote %q", remote)
+		case "github.com":
+			apiURL = "https://api.github.com"
+		default:
+			apiURL = "https://" + host + "/api/v3"
+		}
+	}
+	api, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %s", apiURL, err)
+	}
+	return &GitHub{
+		jirix: jirix,
+		api:   api,
+		owner: parts[len(parts)-2],
+		repo:  parts[len(parts)-1],
+		token: jirix.Env()["GITHUB_TOKEN"],
+	}, nil
+}
+
+// splitRemote returns the host and the path of a git remote, which is a URL
+// or an scp-like address such as "git@github.com:owner/repo.git".
+func splitRemote(remote string) (string, string) {
+	if !strings.Contains(remote, "://") {
+		if i := strings.Index(remote, ":"); i > 0 && !strings.Contains(remote[:i], "/") {
+			host := remote[:i]
+			if j := strings.LastIndex(host, "@"); j >= 0 {
+				host = host[j+1:]
+			}
+			return host, remote[i+1:]
+		}
+		return "", remote
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return "", remote
+	}
+	return u.Hostname(), u.Path
+}
+
+// ParsePullRef returns the number of the pull request of a
+// "refs/pull/<number>/head" ref.
+func ParsePullRef(ref string) (int, error) {
+	m := pullRefRE.FindStringSubmatch(ref)
+	if m == nil {
+		return -1, fmt.Errorf("%q is not a pull request ref", ref)
+	}
+	return strconv.Atoi(m[1])
+}
+
+// pull is a pull request, as returned by the GitHub API.
+type pull struct {
+	Number   int    `json:"number"`
+	State    string `json:"state"`
+	MergedAt string `json:"merged_at"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	HTMLURL  string `json:"html_url"`
+	Base     struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	// PullRequest is set by the search API.
+	PullRequest *struct {
+		MergedAt string `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+func (h *GitHub) fullName() string {
+	return h.owner + "/" + h.repo
+}
+
+func (h *GitHub) change(p pull, topic string) Change {
+	status := StatusNew
+	mergedAt := p.MergedAt
+	if p.PullRequest != nil {
+		mergedAt = p.PullRequest.MergedAt
+	}
+	if p.State == "closed" {
+		if mergedAt != "" {
+			status = StatusMerged
+		} else {
+			status = StatusAbandoned
+		}
+	}
+	if topic == "" {
+		topic = linkedTopic(p.Body)
+	}
+	return Change{
+		Number:  p.Number,
+		Project: h.fullName(),
+		Branch:  p.Base.Ref,
+		Ref:     fmt.Sprintf("refs/pull/%d/head", p.Number),
+		Topic:   topic,
+		Status:  status,
+		Subject: p.Title,
+		URL:     p.HTMLURL,
+	}
+}
+
+// request sends a request to the GitHub API, with in encoded as JSON, and
+// decodes the response in out.
+func (h *GitHub) request(method, path string, query url.Values, in, out any) (e error) {
+	u := *h.api
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("Marshal(%#v) failed: %v", in, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return fmt.Errorf("NewRequest(%q, %q) failed: %v", method, u.String(), err)
+	}
+	req.Header.Add("Accept", "application/vnd.github+json")
+	if in != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	if h.token != "" {
+		req.Header.Add("Authorization", "Bearer "+h.token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Do(%v) failed: %v", req, err)
+	}
+	defer collect.Error(func() error { return res.Body.Close() }, &e)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s %s failed: %v %s", method, u.Path, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("Decode() failed: %v", err)
+	}
+	return nil
+}
+
+// Push pushes opts.RefToUpload to the branch "jiri/<opts.Branch>" of the
+// remote and opens a pull request from it into opts.RemoteBranch, unless one
+// is already open. The push only replaces the remote branch if it is still at
+// the revision jiri last pushed to it. GitHub reviewers are user names, the
+// domain of the reviewer addresses is dropped.
+func (h *GitHub) Push(dir string, opts PushOptions) (*Change, error) {
+	if opts.Branch == "" {
+		return nil, fmt.Errorf("pull requests can only be opened from a branch")
+	}
+	if opts.Branch == opts.RemoteBranch {
+		return nil, fmt.Errorf("cannot open a pull request from branch %q into itself, upload from another local branch", opts.Branch)
+	}
+	if opts.GitOptions != "" {
+		h.jirix.Logger.Warningf("Git options %q are not supported for GitHub and are ignored\n", opts.GitOptions)
+	}
+	ref := opts.RefToUpload
+	if ref == "" {
+		ref = "HEAD"
+	}
+	remote := opts.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	head := PullBranchPrefix + opts.Branch
+	scm := gitutil.New(h.jirix, gitutil.RootDirOpt(dir))
+	// The remote-tracking branch, which git updates when pushing, records
+	// the revision last pushed.
+	tracking := "refs/remotes/" + remote + "/" + head
+	expect := ""
+	if exists, err := scm.BranchExists(tracking); err != nil {
+		return nil, err
+	} else if exists {
+		if expect, err = scm.CurrentRevisionForRef(tracking); err != nil {
+			return nil, err
+		}
+	}
+	lease := gitutil.ForceWithLeaseOpt("refs/heads/" + head + ":" + expect)
+	if err := scm.Push(remote, ref+":refs/heads/"+head, lease, gitutil.VerifyOpt(opts.Verify)); err != nil {
+		return nil, err
+	}
+
+	var open []pull
+	query := url.Values{"state": {"open"}, "head": {h.owner + ":" + head}}
+	if err := h.request("GET", "/repos/"+h.fullName()+"/pulls", query, nil, &open); err != nil {
+		return nil, err
+	}
+	var p pull
+	if len(open) != 0 {
+		p = open[0]
+	} else {
+		msg, err := scm.CommitMsg(ref)
+		if err != nil {
+			return nil, err
+		}
+		title, body, _ := strings.Cut(msg, "\n")
+		body = strings.TrimSpace(body)
+		if opts.Topic != "" {
+			body = setLinks(body, opts.Topic, nil)
+		}
+		in := map[string]string{"title": title, "body": body, "head": head, "base": opts.RemoteBranch}
+		if err := h.request("POST", "/repos/"+h.fullName()+"/pulls", nil, in, &p); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Reviewers) != 0 {
+		var reviewers []string
+		for _, r := range opts.Reviewers {
+			reviewers = append(reviewers, strings.SplitN(r, "@", 2)[0])
+		}
+		in := map[string][]string{"reviewers": reviewers}
+		if err := h.request("POST", fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", h.fullName(), p.Number), nil, in, nil); err != nil {
+			return nil, err
+		}
+	}
+	c := h.change(p, opts.Topic)
+	return &c, nil
+}
+
+func (h *GitHub) GetChange(number int) (*Change, error) {
+	var p pull
+	if err := h.request("GET", fmt.Sprintf("/repos/%s/pulls/%d", h.fullName(), number), nil, nil, &p); err != nil {
+		return nil, err
+	}
+	c := h.change(p, "")
+	return &c, nil
+}
+
+// Query returns the pull requests of the repository matching a GitHub search
+// query, e.g. "is:open author:octocat".
+func (h *GitHub) Query(query string) ([]Change, error) {
+	var result struct {
+		Items []pull `json:"items"`
+	}
+	q := url.Values{"q": {strings.TrimSpace("repo:" + h.fullName() + " is:pr " + query)}}
+	if err := h.request("GET", "/search/issues", q, nil, &result); err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, p := range result.Items {
+		changes = append(changes, h.change(p, ""))
+	}
+	return changes, nil
+}
+
+// TopicChanges returns the open pull requests of the repository linked
+// under topic.
+func (h *GitHub) TopicChanges(topic string) ([]Change, error) {
+	changes, err := h.Query(fmt.Sprintf("is:open in:body %q", "Topic: "+topic))
+	if err != nil {
+		return nil, err
+	}
+	var ret []Change
+	for _, c := range changes {
+		// The search is not exact, check the topic of the pull requests.
+		if c.Topic == topic {
+			ret = append(ret, c)
+		}
+	}
+	return ret, nil
+}
+
+// Link lists the other changes in the description of each pull request of
+// the repository among changes.
+func (h *GitHub) Link(topic string, changes []Change) error {
+	for _, c := range changes {
+		if c.Project != h.fullName() || !strings.HasPrefix(c.Ref, "refs/pull/") {
+			continue
+		}
+		var others []string
+		for _, other := range changes {
+			if other.URL != c.URL {
+				others = append(others, other.URL)
+			}
+		}
+		var p pull
+		path := fmt.Sprintf("/repos/%s/pulls/%d", h.fullName(), c.Number)
+		if err := h.request("GET", path, nil, nil, &p); err != nil {
+			return err
+		}
+		body := setLinks(p.Body, topic, others)
+		if body == p.Body {
+			continue
+		}
+		if err := h.request("PATCH", path, nil, map[string]string{"body": body}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLinks replaces the links section of the description of a pull request.
+func setLinks(body, topic string, links []string) string {
+	if i := strings.Index(body, linksMarker); i >= 0 {
+		body = strings.TrimRight(body[:i], "\n")
+	}
+	section := linksMarker + "\nTopic: " + topic + "\n"
+	if len(links) != 0 {
+		section += "\nLinked pull requests:\n"
+		for _, link := range links {
+			section += "- " + link + "\n"
+		}
+	}
+	if body == "" {
+		return section
+	}
+	return body + "\n\n" + section
+}
+
+// linkedTopic returns the topic of the links section of the description of
+// a pull request.
+func linkedTopic(body string) string {
+	i := strings.Index(body, linksMarker)
+	if i < 0 {
+		return ""
+	}
+	for _, line := range strings.Split(body[i:], "\n") {
+		if topic, ok := strings.CutPrefix(line, "Topic: "); ok {
+			return strings.TrimSpace(topic)
+		}
+	}
+	return ""
+}
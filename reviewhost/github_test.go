@@ -0,0 +1,264 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reviewhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/cmdline"
+	"go.fuchsia.dev/jiri/color"
+	"go.fuchsia.dev/jiri/envvar"
+	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/gitutil"
+	"go.fuchsia.dev/jiri/log"
+	"go.fuchsia.dev/jiri/project"
+	"go.fuchsia.dev/jiri/tool"
+)
+
+// newX returns a jiri.X for a temporary root. Unlike xtest.NewX, it does not
+// install cipd, which the review hosts do not use.
+func newX(t *testing.T) *jiri.X {
+	env := cmdline.EnvFromOS()
+	env.Stdout = io.Discard
+	env.Stderr = io.Discard
+	color := color.NewColor(color.ColorNever)
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, jiri.RootMetaDir), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return &jiri.X{
+		Context:  tool.NewContextFromEnv(env),
+		Root:     root,
+		Cwd:      root,
+		Jobs:     jiri.DefaultJobs,
+		Color:    color,
+		Logger:   log.NewLogger(log.InfoLevel, color, false, 0, time.Second*100, env.Stdout, env.Stderr),
+		Attempts: 1,
+	}
+}
+
+// fakeGitHub is a fake of the pull request API of GitHub for the repository
+// "owner/repo".
+type fakeGitHub struct {
+	mu        sync.Mutex
+	pulls     []map[string]any
+	reviewers map[int][]string
+	token     string
+}
+
+func (f *fakeGitHub) pull(n int) map[string]any {
+	if n < 1 || n > len(f.pulls) {
+		return nil
+	}
+	return f.pulls[n-1]
+}
+
+func (f *fakeGitHub) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = r.Header.Get("Authorization")
+	var in map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&in)
+	}
+	var out any
+	path := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/pulls")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case r.URL.Path == "/search/issues":
+		var items []map[string]any
+		for _, p := range f.pulls {
+			if p["state"] == "open" && strings.Contains(p["body"].(string), "Topic: ") {
+				items = append(items, p)
+			}
+		}
+		out = map[string]any{"items": items}
+	case path == r.URL.Path:
+		http.NotFound(rw, r)
+		return
+	case path == "" && r.Method == http.MethodGet:
+		open := []map[string]any{}
+		for _, p := range f.pulls {
+			if p["state"] == "open" && "owner:"+p["head"].(string) == r.URL.Query().Get("head") {
+				open = append(open, p)
+			}
+		}
+		out = open
+	case path == "" && r.Method == http.MethodPost:
+		n := len(f.pulls) + 1
+		p := map[string]any{
+			"number":   n,
+			"state":    "open",
+			"title":    in["title"],
+			"body":     in["body"],
+			"head":     in["head"],
+			"html_url": fmt.Sprintf("https://github.com/owner/repo/pull/%d", n),
+			"base":     map[string]any{"ref": in["base"]},
+		}
+		f.pulls = append(f.pulls, p)
+		out = p
+	default:
+		n, _ := strconv.Atoi(parts[0])
+		p := f.pull(n)
+		if p == nil {
+			http.NotFound(rw, r)
+			return
+		}
+		switch {
+		case len(parts) == 2 && parts[1] == "requested_reviewers":
+			for _, r := range in["reviewers"].([]any) {
+				f.reviewers[n] = append(f.reviewers[n], r.(string))
+			}
+		case r.Method == http.MethodPatch:
+			p["body"] = in["body"]
+		}
+		out = p
+	}
+	json.NewEncoder(rw).Encode(out)
+}
+
+func TestGitHub(t *testing.T) {
+	fake := &fakeGitHub{reviewers: map[int][]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	jirix := newX(t)
+	env := envvar.CopyMap(jirix.Env())
+	env["GITHUB_TOKEN"] = "secret"
+	jirix.Context = tool.NewContext(tool.ContextOpts{Env: env, Stdout: io.Discard, Stderr: io.Discard})
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "owner", "repo.git")
+	local := filepath.Join(dir, "local")
+	git := gitutil.New(jirix, gitutil.UserNameOpt("John Doe"), gitutil.UserEmailOpt("john.doe@example.com"))
+	if err := git.Init(remote, gitutil.BareOpt(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Clone(remote, local); err != nil {
+		t.Fatal(err)
+	}
+	git = gitutil.New(jirix, gitutil.RootDirOpt(local), gitutil.UserNameOpt("John Doe"), gitutil.UserEmailOpt("john.doe@example.com"))
+	if err := os.WriteFile(filepath.Join(local, "file"), []byte("change"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.CommitFile("file", "Add file\n\nWith a description."); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := New(jirix, project.Project{Name: "repo", Remote: "file://" + remote, ReviewHost: "github:" + server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := PushOptions{
+		CLOpts: gerrit.CLOpts{RemoteBranch: "main", Reviewers: []string{"jane@example.com"}, Topic: "feature"},
+		Branch: "feature",
+	}
+	c, err := h.Push(local, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Number != 1 || c.Ref != "refs/pull/1/head" || c.Subject != "Add file" || c.Status != StatusNew || c.Topic != "feature" {
+		t.Errorf("unexpected pull request %+v", c)
+	}
+	if fake.token != "Bearer secret" {
+		t.Errorf("got authorization %q, want the GitHub token", fake.token)
+	}
+	if got := fake.reviewers[1]; len(got) != 1 || got[0] != "jane" {
+		t.Errorf("got reviewers %v, want [jane]", got)
+	}
+	if out, err := gitutil.New(jirix, gitutil.RootDirOpt(remote)).CurrentRevisionForRef("refs/heads/jiri/feature"); err != nil || out == "" {
+		t.Errorf("expected branch jiri/feature to be pushed, got %q, %v", out, err)
+	}
+
+	// Pushing again updates the open pull request.
+	if c, err := h.Push(local, PushOptions{CLOpts: gerrit.CLOpts{RemoteBranch: "main"}, Branch: "feature"}); err != nil || c.Number != 1 {
+		t.Errorf("expected pull request 1 to be updated, got %+v, %v", c, err)
+	}
+	if len(fake.pulls) != 1 {
+		t.Errorf("expected a single pull request, got %d", len(fake.pulls))
+	}
+
+	// A pull request cannot be opened from the base branch into itself.
+	if _, err := h.Push(local, PushOptions{CLOpts: gerrit.CLOpts{RemoteBranch: "main"}, Branch: "main"}); err == nil {
+		t.Errorf("expected pushing branch main for review into main to fail")
+	}
+
+	other := Change{URL: "https://github.com/other/repo/pull/7", Project: "other/repo", Ref: "refs/pull/7/head"}
+	if err := h.Link("feature", []Change{*c, other}); err != nil {
+		t.Fatal(err)
+	}
+	c, err = h.GetChange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fake.pulls[0]["body"].(string)
+	if !strings.HasPrefix(body, "With a description.") || !strings.Contains(body, "- "+other.URL) || c.Topic != "feature" {
+		t.Errorf("unexpected description after linking:\n%s", body)
+	}
+	// Linking again replaces the links.
+	if err := h.Link("feature", []Change{*c}); err != nil {
+		t.Fatal(err)
+	}
+	if body := fake.pulls[0]["body"].(string); strings.Contains(body, other.URL) || strings.Count(body, linksMarker) != 1 {
+		t.Errorf("unexpected description after relinking:\n%s", body)
+	}
+
+	changes, err := h.TopicChanges("feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Number != 1 {
+		t.Errorf("got topic changes %+v, want pull request 1", changes)
+	}
+	if changes, err := h.TopicChanges("feat"); err != nil || len(changes) != 0 {
+		t.Errorf("expected no change for a topic prefix, got %+v, %v", changes, err)
+	}
+
+	fake.pulls[0]["state"] = "closed"
+	fake.pulls[0]["merged_at"] = "2026-01-01T00:00:00Z"
+	if c, err := h.GetChange(1); err != nil || c.Status != StatusMerged {
+		t.Errorf("expected pull request 1 to be merged, got %+v, %v", c, err)
+	}
+	if _, err := h.GetChange(2); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a missing pull request to fail, got %v", err)
+	}
+}
+
+func TestNewGitHub(t *testing.T) {
+	tests := []struct {
+		remote, api, wantAPI, wantRepo string
+	}{
+		{"https://github.com/owner/repo.git", "", "https://api.github.com", "owner/repo"},
+		{"git@github.com:owner/repo", "", "https://api.github.com", "owner/repo"},
+		{"https://git.example.com/org/owner/repo", "", "https://git.example.com/api/v3", "owner/repo"},
+		{"/path/to/owner/repo", "http://localhost:8080", "http://localhost:8080", "owner/repo"},
+	}
+	jirix := newX(t)
+	for _, test := range tests {
+		h, err := NewGitHub(jirix, test.remote, test.api)
+		if err != nil {
+			t.Errorf("NewGitHub(%q) failed: %v", test.remote, err)
+			continue
+		}
+		if h.api.String() != test.wantAPI || h.fullName() != test.wantRepo {
+			t.Errorf("NewGitHub(%q): got %s %s, want %s %s", test.remote, h.api, h.fullName(), test.wantAPI, test.wantRepo)
+		}
+	}
+	for _, remote := range []string{"/path/to/owner/repo", "https://github.com/repo"} {
+		if _, err := NewGitHub(jirix, remote, ""); err == nil {
+			t.Errorf("NewGitHub(%q) expected to fail", remote)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The Fuchsia Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reviewhost provides a common interface to the code review hosts
+// of the projects: Gerrit, and GitHub pull requests.
+package reviewhost
+
+import (
+	"fmt"
+	"strings"
+
+	"go.fuchsia.dev/jiri"
+	"go.fuchsia.dev/jiri/gerrit"
+	"go.fuchsia.dev/jiri/project"
+)
+
+// The statuses of a change, named after the Gerrit ones.
+const (
+	StatusNew       = "NEW"
+	StatusMerged    = "MERGED"
+	StatusAbandoned = "ABANDONED"
+)
+
+// Change is a change under review: a Gerrit change or a pull request.
+type Change struct {
+	Number int
+	// Project is the Gerrit project or the "<owner>/<repo>" GitHub
+	// repository of the change.
+	Project string
+	// Branch is the branch the change is to be merged into.
+	Branch string
+	// Ref is the ref to fetch the latest version of the change from.
+	Ref     string
+	Topic   string
+	Status  string
+	Subject string
+	URL     string
+}
+
+// PushOptions records the options of a push for review.
+type PushOptions struct {
+	gerrit.CLOpts
+	// Branch is the local branch being pushed. Pull requests are opened from
+	// the remote branch PullBranchPrefix+Branch.
+	Branch string
+}
+
+// PullBranchPrefix namespaces the remote branches pull requests are opened
+// from, so that they never collide with the branches of the repository.
+const PullBranchPrefix = "jiri/"
+
+// Host is a code review host.
+type Host interface {
+	// Push pushes opts.RefToUpload from the repository in dir for review,
+	// and returns the change it was pushed to if the host reports it.
+	Push(dir string, opts PushOptions) (*Change, error)
+	// GetChange returns the change with the given number.
+	GetChange(number int) (*Change, error)
+	// Query returns the changes matching a query in the syntax of the host.
+	Query(query string) ([]Change, error)
+	// TopicChanges returns the open changes of a topic.
+	TopicChanges(topic string) ([]Change, error)
+	// Link groups the changes of the host among changes under topic, so that
+	// they are reviewed together. Gerrit sets their topic, and GitHub lists
+	// the other changes in the description of each pull request.
+	Link(topic string, changes []Change) error
+}
+
+// New returns the review host of a project, selected by its "reviewhost"
+// attribute.
+func New(jirix *jiri.X, p project.Project) (Host, error) {
+	kind, apiURL, _ := strings.Cut(p.ReviewHost, ":")
+	switch kind {
+	case "", "gerrit":
+		return newGerritHost(jirix, p.GerritHost)
+	case "github":
+		return NewGitHub(jirix, p.Remote, apiURL)
+	default:
+		return nil, fmt.Errorf("project %s: unknown review host %q", p.Name, p.ReviewHost)
+	}
+}
+
+// IsGitHub returns whether the changes of the project are GitHub pull
+// requests.
+func IsGitHub(p project.Project) bool {
+	return p.ReviewHost == "github" || strings.HasPrefix(p.ReviewHost, "github:")
+}
@@ -64,8 +64,10 @@ type Config struct {
 	SsoCookiePath     string   `xml:"SsoCookiePath,omitempty"`
 	LockfileEnabled   string   `xml:"lockfile>enabled,omitempty"`
 	LockfileName      string   `xml:"lockfile>name,omitempty"`
+	LockfileVersion   int      `xml:"lockfile>version,omitempty"`
 	PrebuiltJSON      string   `xml:"prebuilt>JSON,omitempty"`
 	FetchingAttrs     string   `xml:"fetchingAttrs,omitempty"`
+	FetchPlatforms    string   `xml:"fetchPlatforms,omitempty"`
 	AnalyticsOptIn    string   `xml:"analytics>optin,omitempty"`
 	AnalyticsUserId   string   `xml:"analytics>userId,omitempty"`
 	Partial           bool     `xml:"partial,omitempty"`
@@ -130,6 +132,7 @@ type X struct {
 	config              *Config
 	Cache               string
 	CipdParanoidMode    bool
+	CipdCheckIntegrity  bool
 	CipdMaxThreads      int
 	Dissociate          bool
 	Shared              bool
@@ -138,12 +141,14 @@ type X struct {
 	RewriteSsoToHttps   bool
 	LockfileEnabled     bool
 	LockfileName        string
+	LockfileVersion     int
 	OffloadPackfiles    bool
 	SsoCookiePath       string
 	Partial             bool
 	PartialSkip         []string
 	PrebuiltJSON        string
 	FetchingAttrs       string
+	FetchPlatforms      string
 	UsingSnapshot       bool
 	UsingImportOverride bool
 	OverrideOptional    bool
@@ -338,9 +343,14 @@ func NewX(env *cmdline.Env, flags TopLevelFlags) (*X, error) {
 		x.LockfileName = x.config.LockfileName
 		x.PrebuiltJSON = x.config.PrebuiltJSON
 		x.FetchingAttrs = x.config.FetchingAttrs
+		x.FetchPlatforms = x.config.FetchPlatforms
 		if x.LockfileName == "" {
 			x.LockfileName = "jiri.lock"
 		}
+		x.LockfileVersion = x.config.LockfileVersion
+		if x.LockfileVersion == 0 {
+			x.LockfileVersion = 1
+		}
 		if x.PrebuiltJSON == "" {
 			x.PrebuiltJSON = "prebuilt.json"
 		}